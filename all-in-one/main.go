@@ -0,0 +1,223 @@
+// Command all-in-one runs every service in a single process for local
+// development: the backend, the frontend, the scraper, the web client, the S3
+// importer and, when SNS_QUEUE_URL is set, the SNS bridge. Messages go through
+// the in-memory bus, Redis is replaced by an in-process server and the bot
+// talks to the terminal instead of Telegram. Only PostgreSQL is external.
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	backend "github.com/FlavioMalvestitiJunior/bf-offers/backend/app"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	frontend "github.com/FlavioMalvestitiJunior/bf-offers/frontend/app"
+	s3importer "github.com/FlavioMalvestitiJunior/bf-offers/s3-importer/app"
+	webclient "github.com/FlavioMalvestitiJunior/bf-offers/webclient/app"
+	"github.com/alicebob/miniredis/v2"
+	snsbridge "github.com/flaviomalvestitijunior/bf-offers/sns-bridge/app"
+	"github.com/go-redis/redis/v8"
+	_ "github.com/lib/pq"
+	scraper "github.com/yourusername/bf-offers/scraper/app"
+)
+
+func main() {
+	userID := flag.Int64("user", 1, "Telegram id of the user typing in the terminal")
+	quiet := flag.Bool("quiet", false, "Hide the logs of the services, showing only the bot messages")
+	staticDir := flag.String("static", "../webclient/static", "Directory of the web client pages")
+	flag.Parse()
+
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	// The services default to the docker-compose hosts, run them against localhost
+	setDefaultEnv("POSTGRES_HOST", "localhost")
+	setDefaultEnv("BACKEND_URL", "http://localhost:8080")
+
+	backendConfig := backend.LoadConfig()
+	frontendConfig := frontend.LoadConfig()
+	scraperConfig := scraper.LoadConfig()
+	importerConfig := s3importer.LoadConfig()
+	bridgeConfig := snsbridge.LoadConfig()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		log.Println("Shutdown signal received, stopping...")
+		cancel()
+	}()
+
+	// PostgreSQL is shared by every service
+	db, err := initDB(backendConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// In-process Redis, shared by the backend, the scraper and the web client
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		log.Fatalf("Failed to start in-process Redis: %v", err)
+	}
+	defer redisServer.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer redisClient.Close()
+
+	// In-memory message bus in place of Kafka
+	messageBus := bus.NewMemory()
+	defer messageBus.Close()
+
+	backendService, err := backend.Start(ctx, backendConfig, db, redisClient, messageBus)
+	if err != nil {
+		log.Fatalf("Failed to start backend: %v", err)
+	}
+	go serve("backend", backendConfig.Port, backendService.Handler())
+
+	telegram := newTerminalTelegram(os.Stdout)
+	frontendService, err := frontend.Start(ctx, frontendConfig, telegram, messageBus)
+	if err != nil {
+		log.Fatalf("Failed to start frontend: %v", err)
+	}
+
+	if _, err := scraper.Start(ctx, scraperConfig, redisClient, messageBus); err != nil {
+		log.Fatalf("Failed to start scraper: %v", err)
+	}
+
+	go serve("webclient", getEnv("PORT", "8082"), webclient.NewHandler(db, redisClient, getEnv("BACKEND_URL", ""), *staticDir))
+
+	// The S3 importer is a job, run it periodically like the scheduled task does
+	go runImporter(ctx, importerConfig, db, messageBus, getEnvDuration("S3_IMPORT_INTERVAL", 10*time.Minute))
+
+	// The SNS bridge needs an SQS queue
+	if bridgeConfig.SNSQueueURL != "" {
+		bridge, err := snsbridge.NewBridge(bridgeConfig, db, messageBus)
+		if err != nil {
+			log.Fatalf("Failed to create SNS bridge: %v", err)
+		}
+		go bridge.Run(ctx)
+	} else {
+		log.Println("SNS_QUEUE_URL not set, SNS bridge disabled")
+	}
+
+	fmt.Printf("Todos os serviços estão rodando. Digite comandos do bot (ex.: /help) como o usuário %d.\n", *userID)
+
+	// Each line typed in the terminal is a message sent to the bot
+	lines := make(chan string)
+	go readLines(os.Stdin, lines)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("All-in-one stopped gracefully")
+			return
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			if update, ok := telegram.Update(*userID, line); ok {
+				go frontendService.HandleUpdate(update)
+			}
+		}
+	}
+}
+
+// runImporter runs the S3 import job every interval until ctx is cancelled
+func runImporter(ctx context.Context, config s3importer.Config, db *sql.DB, publisher bus.Publisher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s3importer.Run(ctx, config, db, publisher); err != nil {
+			log.Printf("Import job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readLines sends the non-empty lines read from r to lines
+func readLines(r io.Reader, lines chan<- string) {
+	defer close(lines)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines <- line
+		}
+	}
+}
+
+// serve serves handler on port
+func serve(name, port string, handler http.Handler) {
+	log.Printf("%s listening on :%s", name, port)
+	if err := http.ListenAndServe(":"+port, handler); err != nil {
+		log.Printf("%s server error: %v", name, err)
+	}
+}
+
+// initDB initializes the database connection
+func initDB(config backend.Config) (*sql.DB, error) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		config.PostgresHost, config.PostgresPort, config.PostgresUser, config.PostgresPass, config.PostgresDB)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s:%s: %w", config.PostgresHost, config.PostgresPort, err)
+	}
+
+	log.Println("Database connection established")
+	return db, nil
+}
+
+// setDefaultEnv sets an environment variable that is not set
+func setDefaultEnv(key, value string) {
+	if os.Getenv(key) == "" {
+		os.Setenv(key, value)
+	}
+}
+
+// getEnv gets an environment variable with a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "10m") with a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration for %s: %s, using %v", key, value, defaultValue)
+	}
+	return defaultValue
+}
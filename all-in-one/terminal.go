@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// terminalTelegram replaces the Telegram Bot API: the messages sent by the bot
+// are printed to the terminal and the lines typed in it become updates
+type terminalTelegram struct {
+	mu            sync.Mutex
+	out           io.Writer
+	lastMessageID int
+	lastUpdateID  int
+}
+
+func newTerminalTelegram(out io.Writer) *terminalTelegram {
+	return &terminalTelegram{out: out}
+}
+
+// Send prints a message, a photo or an edited message
+func (t *terminalTelegram) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch m := c.(type) {
+	case tgbotapi.MessageConfig:
+		t.lastMessageID++
+		fmt.Fprintf(t.out, "\n🤖 [%d → %d]\n%s\n", t.lastMessageID, m.ChatID, m.Text)
+		return t.message(m.ChatID), nil
+	case tgbotapi.PhotoConfig:
+		t.lastMessageID++
+		fmt.Fprintf(t.out, "\n🤖 [%d → %d] 🖼 %s\n%s\n", t.lastMessageID, m.ChatID, describeFile(m.File), m.Caption)
+		return t.message(m.ChatID), nil
+	case tgbotapi.EditMessageTextConfig:
+		fmt.Fprintf(t.out, "\n🤖 [%d editada]\n%s\n", m.MessageID, m.Text)
+		return tgbotapi.Message{MessageID: m.MessageID, Chat: &tgbotapi.Chat{ID: m.ChatID}}, nil
+	default:
+		return tgbotapi.Message{}, fmt.Errorf("unsupported Telegram request %T", c)
+	}
+}
+
+// Request prints deleted messages and accepts any other request
+func (t *terminalTelegram) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	if m, ok := c.(tgbotapi.DeleteMessageConfig); ok {
+		t.mu.Lock()
+		fmt.Fprintf(t.out, "\n🤖 [%d apagada]\n", m.MessageID)
+		t.mu.Unlock()
+	}
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+// Update returns the update of a line typed by the user. A line starting with
+// "/" is a command, as in the Telegram clients.
+func (t *terminalTelegram) Update(userID int64, line string) (tgbotapi.Update, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return tgbotapi.Update{}, false
+	}
+
+	t.mu.Lock()
+	t.lastUpdateID++
+	updateID := t.lastUpdateID
+	t.mu.Unlock()
+
+	message := &tgbotapi.Message{
+		MessageID: updateID,
+		From:      &tgbotapi.User{ID: userID, FirstName: "Dev", UserName: "dev"},
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Text:      line,
+	}
+	if strings.HasPrefix(line, "/") {
+		command := strings.Fields(line)[0]
+		// Entity offsets and lengths are in UTF-16 code units
+		message.Entities = []tgbotapi.MessageEntity{{
+			Type:   "bot_command",
+			Offset: 0,
+			Length: len(utf16.Encode([]rune(command))),
+		}}
+	}
+
+	return tgbotapi.Update{UpdateID: updateID, Message: message}, true
+}
+
+// message returns the message sent to a chat with the last message id
+func (t *terminalTelegram) message(chatID int64) tgbotapi.Message {
+	return tgbotapi.Message{MessageID: t.lastMessageID, Chat: &tgbotapi.Chat{ID: chatID}}
+}
+
+// describeFile returns the URL or the name of a photo
+func describeFile(file tgbotapi.RequestFileData) string {
+	switch f := file.(type) {
+	case tgbotapi.FileURL:
+		return string(f)
+	case tgbotapi.FileBytes:
+		return f.Name
+	default:
+		return "foto"
+	}
+}
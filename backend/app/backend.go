@@ -0,0 +1,368 @@
+// Package app runs the backend: it consumes commands and offers from the
+// message bus, matches offers against the wishlists and publishes the
+// notifications and command responses. main runs it against Kafka; the
+// all-in-one command runs it in process.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/backtest"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/consumer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/dedupe"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/fakediscount"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/handler"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/history"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/metrics"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/normalizer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/outbox"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/producer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/migrations"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/health"
+	"github.com/go-redis/redis/v8"
+)
+
+// Backend is a started backend
+type Backend struct {
+	// Checker holds the readiness checks of the database, the cache and the
+	// consumer groups. Callers add the checks of their message bus.
+	Checker *health.Checker
+
+	explainHandler  http.Handler
+	backtestHandler http.Handler
+}
+
+// Start applies the pending database migrations (unless MigrateOnStart is
+// off) and starts the consumers of commands, offers and wishlist events, the
+// outbox relay and the background jobs. They stop when ctx is cancelled.
+func Start(ctx context.Context, config Config, db *sql.DB, redisClient *redis.Client, messageBus bus.Bus) (*Backend, error) {
+	// Bring the schema up to date before anything reads it
+	if config.MigrateOnStart {
+		migrator, err := migrations.NewMigrator(db)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := migrator.Up(ctx); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	// Readiness checks of the dependencies, served on /health/ready
+	checker := health.NewChecker(3 * time.Second)
+	checker.Add("postgres", db.PingContext)
+	checker.Add("redis", func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	})
+
+	// Initialize repository
+	repo := repository.NewWishlistRepository(db, redisClient)
+	offerRepo := repository.NewOfferRepository(db)
+
+	// Initialize producer for notifications
+	notificationProducer := producer.NewNotificationProducer(messageBus, config.KafkaNotificationTopic)
+
+	// Initialize notification deduplicator
+	deduplicator := dedupe.NewNotificationDeduplicator(
+		repository.NewNotificationRepository(db),
+		config.NotificationDedupWindow,
+	)
+
+	// Initialize offer normalizer
+	offerNormalizer := normalizer.NewOfferNormalizer()
+
+	// Initialize offer matcher
+	offerMatcher := matcher.NewOfferMatcher(config.MatchThreshold)
+
+	// Build the wishlist index used to pick match candidates for each offer
+	wishlistIndex := matcher.NewWishlistIndex()
+	if err := refreshWishlistIndex(repo, wishlistIndex); err != nil {
+		return nil, fmt.Errorf("failed to build wishlist index: %w", err)
+	}
+	go startIndexRefresher(ctx, repo, wishlistIndex, 5*time.Minute)
+
+	// Initialize backtester used to show how often new wishlist items would have been notified
+	backtester := backtest.NewBacktester(offerRepo, offerMatcher, config.NotificationDedupWindow)
+
+	// Initialize price history of offers and wishlist terms
+	priceHistory := history.NewPriceHistory(repository.NewPriceHistoryRepository(db), offerMatcher, config.HistoryLocation)
+
+	// Initialize detector of inflated original prices
+	fakeDiscountDetector := fakediscount.NewDetector(priceHistory, repository.NewUserRepository(db))
+
+	// Initialize command handler
+	cmdHandler := handler.NewCommandHandler(db, redisClient, messageBus, config.KafkaNotificationTopic, backtester, config.BacktestWindow, priceHistory)
+
+	// Publish the wishlist events written to the outbox with each wishlist change
+	relay := outbox.NewRelay(
+		repository.NewOutboxRepository(db),
+		messageBus,
+		config.KafkaWishlistEventsTopic,
+		config.OutboxPollInterval,
+		7*24*time.Hour,
+	)
+	go relay.Run(ctx)
+
+	// Forget processed commands once Kafka can no longer redeliver them
+	go startCommandLogPruner(ctx, repository.NewCommandLogRepository(db), config.CommandLogRetention, time.Hour)
+
+	// Messages that keep failing are moved to "<topic>.dlq" (see cmd/dlq-replay)
+	deadLetters := bus.NewDeadLetterWriter(messageBus)
+
+	// Start command consumer
+	commandsGroup, err := consumer.StartConsumerGroup(
+		ctx,
+		messageBus,
+		config.KafkaCommandTopic,
+		"backend-command-consumer",
+		config.ConsumerRetry,
+		deadLetters,
+		cmdHandler.HandleCommand,
+	)
+	if err != nil {
+		log.Printf("Failed to start command consumer: %v", err)
+	}
+	addConsumerCheck(checker, "kafka_consumer_commands", commandsGroup, err)
+
+	// Start offers consumer
+	offersGroup, err := consumer.StartConsumerGroup(
+		ctx,
+		messageBus,
+		config.KafkaOffersTopic,
+		"backend-offers-consumer",
+		config.ConsumerRetry,
+		deadLetters,
+		func(ctx context.Context, data []byte) error {
+			var offer models.Offer
+			if err := json.Unmarshal(data, &offer); err != nil {
+				log.Printf("Failed to unmarshal offer: %v", err)
+				return nil // Don't retry malformed messages
+			}
+			// In the zone of the price history, so the same-day check of the
+			// offer repository agrees with the history days
+			offer.ReceivedAt = time.Now().In(config.HistoryLocation)
+			metrics.OffersReceived.WithLabelValues(offer.Source).Inc()
+
+			changes, err := offerNormalizer.Normalize(&offer)
+			for _, change := range changes {
+				log.Printf("Normalized offer '%s': %s", offer.ProductName, change)
+			}
+			if err != nil {
+				log.Printf("Rejected offer from %s: %v", offer.Source, err)
+				return nil // Don't retry invalid offers
+			}
+
+			return handleOffer(ctx, &offer, offerRepo, wishlistIndex, offerMatcher, priceHistory, fakeDiscountDetector, deduplicator, notificationProducer, config.ConsumerRetry)
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start offers consumer: %w", err)
+	}
+	addConsumerCheck(checker, "kafka_consumer_offers", offersGroup, nil)
+
+	// Start wishlist events consumer to keep the index up to date. Every backend
+	// instance keeps its own index, so each one needs its own consumer group.
+	// Events that keep failing are dropped: the periodic refresh catches up.
+	hostname, _ := os.Hostname()
+	wishlistEventsGroup, err := consumer.StartConsumerGroupFromNewest(
+		ctx,
+		messageBus,
+		config.KafkaWishlistEventsTopic,
+		"backend-wishlist-index-"+hostname,
+		config.ConsumerRetry,
+		nil,
+		func(_ context.Context, data []byte) error {
+			return handleWishlistEvent(data, repo, wishlistIndex)
+		},
+	)
+	if err != nil {
+		log.Printf("Failed to start wishlist events consumer: %v", err)
+	}
+	addConsumerCheck(checker, "kafka_consumer_wishlist_events", wishlistEventsGroup, err)
+
+	return &Backend{
+		Checker:         checker,
+		explainHandler:  handler.NewExplainHandler(repo, offerRepo, offerMatcher, offerNormalizer),
+		backtestHandler: handler.NewBacktestHandler(repo, offerRepo, offerMatcher, config.NotificationDedupWindow, config.BacktestWindow),
+	}, nil
+}
+
+// Handler serves /health/live for liveness and /health/ready for readiness
+// probes, the Prometheus metrics, the match explanation endpoint used by the
+// webclient and the backtest endpoint
+func (b *Backend) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", b.Checker.LiveHandler)
+	mux.HandleFunc("/health/ready", b.Checker.ReadyHandler)
+	mux.HandleFunc("/health", b.Checker.LiveHandler) // Kept for older probes
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/explain", b.explainHandler)
+	mux.Handle("/backtest", b.backtestHandler)
+	return mux
+}
+
+// handleOffer processes an incoming offer. It can run again for the same
+// offer: a repeated receipt is not added to the price history and users
+// already notified are skipped. Only the notification sends can fail; they are
+// retried here as set by retry, and a send that keeps failing stops the handler
+// with a permanent error so the message goes to the dead-letter topic.
+func handleOffer(ctx context.Context, offer *models.Offer, offerRepo *repository.OfferRepository, index *matcher.WishlistIndex,
+	matcher *matcher.OfferMatcher, priceHistory *history.PriceHistory, detector *fakediscount.Detector, deduplicator *dedupe.NotificationDeduplicator, producer *producer.NotificationProducer,
+	retry bus.RetryPolicy) error {
+
+	log.Printf("Processing offer: %s - R$ %.2f", offer.ProductName, offer.Price)
+
+	// Save offer to database, recording a price event when it is new or changed
+	event, repeated, err := offerRepo.UpsertOffer(offer)
+	if err != nil {
+		log.Printf("Failed to save offer: %v", err)
+	} else if event != nil && event.PreviousPrice != nil {
+		log.Printf("Price changed for offer %s: R$ %.2f -> R$ %.2f", offer.Key, *event.PreviousPrice, event.Price)
+	}
+
+	// Only wishlists sharing words with the offer can match
+	matchStart := time.Now()
+	candidates := index.Candidates(offer)
+
+	// Track the price of the offer and of the wishlist terms it matches. An
+	// unchanged offer received again on the same day adds nothing to the day.
+	if !repeated {
+		if err := priceHistory.Record(offer, candidates); err != nil {
+			log.Printf("Failed to record price history: %v", err)
+		}
+	}
+
+	// Match offer against candidate wishlists
+	notifications := matcher.MatchOffer(offer, candidates)
+
+	// Flag inflated original prices, dropping them for users who opted out
+	notifications = detector.Filter(offer, notifications, candidates)
+
+	// Drop alerts the users already received for this offer
+	notifications = deduplicator.Filter(offer, notifications)
+	metrics.MatchDuration.Observe(time.Since(matchStart).Seconds())
+
+	// Send notifications via Kafka, recording each one before the next so the
+	// deduplicator skips the users already notified if the offer is handled again
+	for i := range notifications {
+		notification := &notifications[i]
+		_, err := retry.Run(ctx, func() error {
+			return producer.SendNotification(ctx, notification)
+		})
+		if err != nil {
+			metrics.Notifications.WithLabelValues("failed").Add(float64(len(notifications) - i))
+			return bus.Permanent(fmt.Errorf("failed to send notification for wishlist %d: %w", notification.WishlistID, err))
+		}
+		metrics.Notifications.WithLabelValues("sent").Inc()
+		deduplicator.Record(offer, notifications[i:i+1])
+	}
+	if len(notifications) > 0 {
+		log.Printf("Sent %d notifications for offer: %s", len(notifications), offer.ProductName)
+	}
+
+	return nil
+}
+
+// handleWishlistEvent applies a wishlist event to the in-memory index
+func handleWishlistEvent(data []byte, repo *repository.WishlistRepository, index *matcher.WishlistIndex) error {
+	var event models.WishlistEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Printf("Failed to unmarshal wishlist event: %v", err)
+		return nil // Don't retry malformed messages
+	}
+
+	if event.WishlistID == 0 {
+		return nil
+	}
+
+	switch event.Type {
+	case models.WishlistEventAdded, models.WishlistEventUpdated:
+		wishlist, err := repo.GetWishlistByID(event.WishlistID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil // Already deleted
+			}
+			return err
+		}
+		index.Add(*wishlist)
+	case models.WishlistEventDeleted:
+		index.Remove(event.WishlistID)
+	}
+
+	return nil
+}
+
+// refreshWishlistIndex reloads the whole wishlist index from the repository
+func refreshWishlistIndex(repo *repository.WishlistRepository, index *matcher.WishlistIndex) error {
+	wishlists, err := repo.GetAllWishlists()
+	if err != nil {
+		return fmt.Errorf("failed to get wishlists: %w", err)
+	}
+
+	index.Load(wishlists)
+	log.Printf("Wishlist index loaded with %d items", index.Len())
+	return nil
+}
+
+// startIndexRefresher periodically rebuilds the wishlist index so changes made
+// outside the command flow (e.g. users deleted from the dashboard) are picked up
+func startIndexRefresher(ctx context.Context, repo *repository.WishlistRepository, index *matcher.WishlistIndex, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := refreshWishlistIndex(repo, index); err != nil {
+				log.Printf("Failed to refresh wishlist index: %v", err)
+			}
+		}
+	}
+}
+
+// startCommandLogPruner periodically deletes processed commands older than retention
+func startCommandLogPruner(ctx context.Context, commandLog *repository.CommandLogRepository, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := commandLog.DeleteProcessedBefore(time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Failed to prune processed commands: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Pruned %d processed commands", deleted)
+			}
+		}
+	}
+}
+
+// addConsumerCheck adds the readiness check of a consumer group, down for good
+// when the group failed to start
+func addConsumerCheck(checker *health.Checker, name string, status *bus.Status, err error) {
+	if err != nil {
+		checker.Add(name, func(context.Context) error {
+			return fmt.Errorf("failed to start: %w", err)
+		})
+		return
+	}
+	checker.Add(name, status.Check)
+}
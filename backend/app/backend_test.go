@@ -0,0 +1,288 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/dedupe"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/fakediscount"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/history"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/producer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+)
+
+var testRetry = bus.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+// flakyPublisher fails the publishes to the users in failures, as many times
+// as set for each user, and publishes the others to the in-memory bus
+type flakyPublisher struct {
+	*bus.Memory
+
+	mu        sync.Mutex
+	failures  map[string]int
+	published []*bus.Message
+	attempts  map[string]int
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, msgs ...*bus.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, msg := range msgs {
+		user := string(msg.Key)
+		p.attempts[user]++
+		if p.failures[user] != 0 {
+			p.failures[user]--
+			return errors.New("broker unavailable")
+		}
+		p.published = append(p.published, msg)
+	}
+	return p.Memory.Publish(ctx, msgs...)
+}
+
+type offerTest struct {
+	mock      sqlmock.Sqlmock
+	publisher *flakyPublisher
+	handle    func(offer *models.Offer) error
+}
+
+// newOfferTest wires handleOffer to a mocked database and two wishlists of
+// different users matching "Geladeira Consul" below R$ 3000. Queries the test
+// does not expect fail and are only logged by handleOffer, so handle fails the
+// test on logged failures.
+func newOfferTest(t *testing.T, failures map[string]int) *offerTest {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	targetPrice := 3000.0
+	index := matcher.NewWishlistIndex()
+	index.Load([]models.Wishlist{
+		{ID: 1, TelegramID: 10, ProductName: "geladeira", TargetPrice: &targetPrice},
+		{ID: 2, TelegramID: 20, ProductName: "geladeira consul", TargetPrice: &targetPrice},
+	})
+
+	offerMatcher := matcher.NewOfferMatcher(0.5)
+	priceHistory := history.NewPriceHistory(repository.NewPriceHistoryRepository(db), offerMatcher, time.UTC)
+	publisher := &flakyPublisher{Memory: bus.NewMemory(), failures: failures, attempts: make(map[string]int)}
+	deduplicator := dedupe.NewNotificationDeduplicator(repository.NewNotificationRepository(db), 24*time.Hour)
+
+	return &offerTest{
+		mock:      mock,
+		publisher: publisher,
+		handle: func(offer *models.Offer) error {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			err := handleOffer(context.Background(), offer, repository.NewOfferRepository(db), index, offerMatcher, priceHistory,
+				fakediscount.NewDetector(priceHistory, repository.NewUserRepository(db)), deduplicator,
+				producer.NewNotificationProducer(publisher, "bot-responses"), testRetry)
+			if strings.Contains(logs.String(), "Failed") {
+				t.Errorf("handleOffer() logged failures:\n%s", logs.String())
+			}
+			return err
+		},
+	}
+}
+
+// expectStored expects the offer to be saved with a new price event and its
+// price recorded for the offer and both wishlist terms
+func (o *offerTest) expectStored() {
+	o.mock.ExpectBegin()
+	o.mock.ExpectQuery("INSERT INTO offers").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	o.mock.ExpectQuery("INSERT INTO offer_price_events").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	o.mock.ExpectCommit()
+	for i := 0; i < 3; i++ {
+		o.mock.ExpectExec("INSERT INTO price_history").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+var lastNotificationColumns = []string{
+	"id", "telegram_id", "wishlist_id", "offer_id", "offer_key", "product_name", "source", "price",
+	"discount_percentage", "cashback_percentage", "sent_at",
+}
+
+func (o *offerTest) expectNotNotified(wishlistID int) {
+	o.mock.ExpectQuery("FROM notifications").
+		WithArgs(wishlistID, "promobit:1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(lastNotificationColumns))
+}
+
+func (o *offerTest) expectRecorded(telegramID int64, wishlistID int) {
+	o.mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(telegramID, wishlistID, 7, "promobit:1", sqlmock.AnyArg(), "promobit", 2500.0, 0, 0, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+func newTestOffer() *models.Offer {
+	return &models.Offer{Key: "promobit:1", ProductName: "Geladeira Consul 300L", Price: 2500, Source: "promobit", ReceivedAt: time.Now()}
+}
+
+func TestHandleOfferRetriesOnlyTheFailedSend(t *testing.T) {
+	o := newOfferTest(t, map[string]int{"20": 2})
+	o.expectStored()
+	o.expectNotNotified(2)
+	o.expectNotNotified(1)
+	o.expectRecorded(20, 2)
+	o.expectRecorded(10, 1)
+
+	if err := o.handle(newTestOffer()); err != nil {
+		t.Fatalf("handleOffer() error = %v", err)
+	}
+	if err := o.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.publisher.attempts["20"] != 3 || o.publisher.attempts["10"] != 1 {
+		t.Errorf("publish attempts = %v, want 3 for user 20 and 1 for user 10", o.publisher.attempts)
+	}
+	if len(o.publisher.published) != 2 {
+		t.Errorf("published %d notifications, want 2", len(o.publisher.published))
+	}
+}
+
+func TestHandleOfferReplaySkipsNotifiedUsers(t *testing.T) {
+	// The newest wishlist is sent first; its user gets the alert before the
+	// sends to the other user run out of attempts
+	o := newOfferTest(t, map[string]int{"10": testRetry.MaxAttempts})
+	o.expectStored()
+	o.expectNotNotified(2)
+	o.expectNotNotified(1)
+	o.expectRecorded(20, 2)
+
+	err := o.handle(newTestOffer())
+	if !bus.IsPermanent(err) || !strings.Contains(err.Error(), "wishlist 1") {
+		t.Fatalf("handleOffer() error = %v, want a permanent error for wishlist 1", err)
+	}
+	if err := o.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replayed from the dead-letter topic the same day: the offer is unchanged,
+	// so neither a price event nor the price history is recorded, and user 20
+	// already got the alert
+	o.mock.ExpectBegin()
+	o.mock.ExpectQuery("INSERT INTO offers").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	o.mock.ExpectQuery("SELECT id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "price", "original_price", "cashback_percentage", "received_at"}).
+			AddRow(7, 2500.0, 0.0, 0, time.Now()))
+	o.mock.ExpectExec("UPDATE offers").WillReturnResult(sqlmock.NewResult(0, 1))
+	o.mock.ExpectCommit()
+	o.mock.ExpectQuery("FROM notifications").
+		WithArgs(2, "promobit:1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(lastNotificationColumns).
+			AddRow(1, 20, 2, 7, "promobit:1", "Geladeira Consul 300L", "promobit", 2500.0, 0, 0, time.Now()))
+	o.expectNotNotified(1)
+	o.expectRecorded(10, 1)
+
+	if err := o.handle(newTestOffer()); err != nil {
+		t.Fatalf("handleOffer() replay error = %v", err)
+	}
+	if err := o.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	var users []string
+	for _, msg := range o.publisher.published {
+		users = append(users, string(msg.Key))
+	}
+	if strings.Join(users, ",") != "20,10" {
+		t.Errorf("notified users %v, want 20 then 10, once each", users)
+	}
+}
+
+// notifications reads the notifications published to the bot-responses topic
+func (o *offerTest) notifications(t *testing.T) map[string]models.OfferNotification {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifications := make(map[string]models.OfferNotification)
+	_, err := o.publisher.Subscribe(ctx, bus.Subscription{Topic: "bot-responses", Group: "frontend"}, func(ctx context.Context, msg *bus.Message) error {
+		var envelope models.Envelope
+		var notification models.OfferNotification
+		if err := json.Unmarshal(msg.Value, &envelope); err != nil || envelope.Type != models.MessageTypeOfferNotification {
+			t.Errorf("unexpected message %s", msg.Value)
+		}
+		if err := json.Unmarshal(envelope.Payload, &notification); err != nil {
+			t.Errorf("invalid notification %s: %v", envelope.Payload, err)
+		}
+		notifications[string(msg.Key)] = notification
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !o.publisher.Drained() {
+		if time.Now().After(deadline) {
+			t.Fatal("notifications not consumed in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return notifications
+}
+
+func TestHandleOfferPublishesNotificationPerUser(t *testing.T) {
+	o := newOfferTest(t, nil)
+	o.expectStored()
+	o.expectNotNotified(2)
+	o.expectNotNotified(1)
+	o.expectRecorded(20, 2)
+	o.expectRecorded(10, 1)
+
+	if err := o.handle(newTestOffer()); err != nil {
+		t.Fatalf("handleOffer() error = %v", err)
+	}
+	if err := o.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	notifications := o.notifications(t)
+	if len(notifications) != 2 {
+		t.Fatalf("got notifications for %d users, want 2", len(notifications))
+	}
+	for user, wishlistID := range map[string]int{"10": 1, "20": 2} {
+		n := notifications[user]
+		if n.WishlistID != wishlistID || n.ProductName != "Geladeira Consul 300L" || n.Price != 2500 || n.MatchType != models.MatchTypePrice {
+			t.Errorf("notification for user %s = %+v, want wishlist %d at R$ 2500", user, n, wishlistID)
+		}
+	}
+}
+
+func TestHandleOfferWithoutCandidatesOnlyStoresIt(t *testing.T) {
+	o := newOfferTest(t, nil)
+	o.mock.ExpectBegin()
+	o.mock.ExpectQuery("INSERT INTO offers").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	o.mock.ExpectQuery("INSERT INTO offer_price_events").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	o.mock.ExpectCommit()
+	o.mock.ExpectExec("INSERT INTO price_history").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	offer := &models.Offer{Key: "promobit:2", ProductName: "Air Fryer Mondial", Price: 300, Source: "promobit", ReceivedAt: time.Now()}
+	if err := o.handle(offer); err != nil {
+		t.Fatalf("handleOffer() error = %v", err)
+	}
+	if err := o.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if notifications := o.notifications(t); len(notifications) != 0 {
+		t.Errorf("got notifications %+v, want none", notifications)
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Zone data for HISTORY_TIMEZONE in images without it
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/similarity"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+)
+
+// Config holds application configuration
+type Config struct {
+	KafkaBrokers             string
+	KafkaNotificationTopic   string
+	KafkaCommandTopic        string
+	KafkaOffersTopic         string
+	KafkaWishlistEventsTopic string
+	RedisHost                string
+	RedisPort                string
+	RedisPassword            string
+	RedisDB                  int
+	PostgresHost             string
+	PostgresPort             string
+	PostgresUser             string
+	PostgresPass             string
+	PostgresDB               string
+	Port                     string
+	NotificationDedupWindow  time.Duration
+	MatchThreshold           float64
+	BacktestWindow           time.Duration
+	ConsumerRetry            bus.RetryPolicy
+	CommandLogRetention      time.Duration  // How long processed command ids are kept to skip redeliveries
+	OutboxPollInterval       time.Duration  // How often pending wishlist events are published
+	MigrateOnStart           bool           // Apply the pending database migrations when starting
+	HistoryLocation          *time.Location // Time zone of the days of the price history
+}
+
+// LoadConfig loads configuration from environment variables
+func LoadConfig() Config {
+	return Config{
+		KafkaBrokers:             getEnv("KAFKA_BROKERS", "kafka:9092"),
+		KafkaNotificationTopic:   getEnv("KAFKA_NOTIFICATION_TOPIC", "bot-responses"),
+		KafkaCommandTopic:        getEnv("KAFKA_COMMAND_TOPIC", "bot-commands"),
+		KafkaOffersTopic:         getEnv("KAFKA_OFFERS_TOPIC", "offers"),
+		KafkaWishlistEventsTopic: getEnv("KAFKA_WISHLIST_EVENTS_TOPIC", "wishlist-events"),
+		RedisHost:                getEnv("REDIS_HOST", "redis"),
+		RedisPort:                getEnv("REDIS_PORT", "6379"),
+		RedisPassword:            getEnv("REDIS_PASSWORD", ""),
+		RedisDB:                  0,
+		PostgresHost:             getEnv("POSTGRES_HOST", "postgres"),
+		PostgresPort:             getEnv("POSTGRES_PORT", "5432"),
+		PostgresUser:             getEnv("POSTGRES_USER", "postgres"),
+		PostgresPass:             getEnv("POSTGRES_PASSWORD", "postgres"),
+		PostgresDB:               getEnv("POSTGRES_DB", "postgres"),
+		Port:                     getEnv("BACKEND_PORT", "8080"),
+		NotificationDedupWindow:  getEnvDuration("NOTIFICATION_DEDUP_WINDOW", 24*time.Hour),
+		MatchThreshold:           getEnvFloat("MATCH_THRESHOLD", similarity.DefaultThreshold),
+		BacktestWindow:           getEnvDuration("BACKTEST_WINDOW", 30*24*time.Hour),
+		ConsumerRetry: bus.RetryPolicy{
+			MaxAttempts:    getEnvInt("CONSUMER_MAX_ATTEMPTS", bus.DefaultRetryPolicy.MaxAttempts),
+			InitialBackoff: getEnvDuration("CONSUMER_RETRY_BACKOFF", bus.DefaultRetryPolicy.InitialBackoff),
+			MaxBackoff:     getEnvDuration("CONSUMER_MAX_BACKOFF", bus.DefaultRetryPolicy.MaxBackoff),
+		},
+		CommandLogRetention: getEnvDuration("COMMAND_LOG_RETENTION", 7*24*time.Hour),
+		OutboxPollInterval:  getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		MigrateOnStart:      getEnvBool("MIGRATE_ON_START", true),
+		HistoryLocation:     getEnvLocation("HISTORY_TIMEZONE", "America/Sao_Paulo"),
+	}
+}
+
+// getEnv gets an environment variable with a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "24h") with a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration for %s: %s, using %v", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvInt gets an integer environment variable with a default value
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+		log.Printf("Invalid number for %s: %s, using %v", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvBool gets a boolean environment variable (e.g. "true" or "0") with a default value
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		log.Printf("Invalid boolean for %s: %s, using %v", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvLocation gets a time zone environment variable (e.g. "America/Sao_Paulo")
+// with a default value
+func getEnvLocation(key, defaultValue string) *time.Location {
+	if value := os.Getenv(key); value != "" {
+		if location, err := time.LoadLocation(value); err == nil {
+			return location
+		}
+		log.Printf("Invalid time zone for %s: %s, using %s", key, value, defaultValue)
+	}
+	location, err := time.LoadLocation(defaultValue)
+	if err != nil {
+		log.Printf("Failed to load time zone %s, using UTC: %v", defaultValue, err)
+		return time.UTC
+	}
+	return location
+}
+
+// getEnvFloat gets a float environment variable with a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+		log.Printf("Invalid number for %s: %s, using %v", key, value, defaultValue)
+	}
+	return defaultValue
+}
@@ -0,0 +1,194 @@
+// Command dlq-replay moves messages from a dead-letter topic back to the topic
+// they failed on, once the cause of the failure is fixed:
+//
+//	dlq-replay -dlq offers.dlq
+//	dlq-replay -dlq bot-commands.dlq -dry-run
+//
+// Replayed messages are committed in the "dlq-replay" consumer group, so each
+// message is moved once. The tool stops when no message arrives for -idle.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/IBM/sarama"
+)
+
+func main() {
+	brokers := flag.String("brokers", getEnv("KAFKA_BROKERS", "kafka:9092"), "Kafka brokers, comma separated")
+	dlqTopic := flag.String("dlq", "", "Dead-letter topic to replay (e.g. offers.dlq)")
+	target := flag.String("topic", "", "Topic to publish to (default: the original topic in the message headers)")
+	groupID := flag.String("group", "dlq-replay", "Consumer group that tracks replayed messages")
+	idle := flag.Duration("idle", 10*time.Second, "Stop after this long without messages")
+	dryRun := flag.Bool("dry-run", false, "Print the messages without moving them")
+	flag.Parse()
+
+	if *dlqTopic == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	writerConfig := sarama.NewConfig()
+	writerConfig.Producer.Return.Successes = true
+	writerConfig.Producer.RequiredAcks = sarama.WaitForAll
+	writerConfig.Producer.Retry.Max = 5
+
+	writer, err := sarama.NewSyncProducer(strings.Split(*brokers, ","), writerConfig)
+	if err != nil {
+		log.Fatalf("Failed to create Kafka writer: %v", err)
+	}
+	defer writer.Close()
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	group, err := sarama.NewConsumerGroup(strings.Split(*brokers, ","), *groupID, config)
+	if err != nil {
+		log.Fatalf("Failed to create consumer group: %v", err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replayer := &replayer{
+		cancel:   cancel,
+		writer:   writer,
+		target:   *target,
+		dryRun:   *dryRun,
+		activity: make(chan struct{}, 1),
+	}
+
+	go func() {
+		for ctx.Err() == nil {
+			if err := group.Consume(ctx, []string{*dlqTopic}, replayer); err != nil {
+				log.Printf("Error from consumer: %v", err)
+				time.Sleep(time.Second)
+			}
+		}
+	}()
+
+	// Stop once the topic is drained or a message cannot be replayed
+wait:
+	for {
+		select {
+		case <-replayer.activity:
+		case <-ctx.Done():
+			break wait
+		case <-time.After(*idle):
+			break wait
+		}
+	}
+	cancel()
+
+	log.Printf("Replayed %d message(s) from %s (failed: %d)", replayer.replayed.Load(), *dlqTopic, replayer.failed.Load())
+	if replayer.failed.Load() > 0 {
+		os.Exit(1)
+	}
+}
+
+// replayer publishes dead-lettered messages to their original topic
+type replayer struct {
+	cancel   context.CancelFunc
+	writer   sarama.SyncProducer
+	target   string
+	dryRun   bool
+	activity chan struct{}
+	replayed atomic.Int64
+	failed   atomic.Int64
+}
+
+func (r *replayer) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (r *replayer) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (r *replayer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for message := range claim.Messages() {
+		select {
+		case r.activity <- struct{}{}:
+		default:
+		}
+
+		if err := r.replay(message); err != nil {
+			// Leave the message in the dead-letter topic for the next run
+			r.failed.Add(1)
+			log.Printf("Failed to replay offset %d: %v", message.Offset, err)
+			r.cancel()
+			return nil
+		}
+		r.replayed.Add(1)
+
+		if !r.dryRun {
+			session.MarkMessage(message, "")
+		}
+	}
+	return nil
+}
+
+// replay publishes the message to its original topic without the dead-letter headers
+func (r *replayer) replay(message *sarama.ConsumerMessage) error {
+	topic := r.target
+	var headers []sarama.RecordHeader
+	for _, h := range message.Headers {
+		if h == nil {
+			continue
+		}
+		if string(h.Key) == bus.HeaderOriginalTopic && topic == "" {
+			topic = string(h.Value)
+		}
+		if !bus.IsDeadLetterHeader(string(h.Key)) {
+			headers = append(headers, *h)
+		}
+	}
+
+	if topic == "" {
+		return fmt.Errorf("no %s header, use -topic", bus.HeaderOriginalTopic)
+	}
+
+	if r.dryRun {
+		log.Printf("Would replay offset %d to %s (error: %s): %s",
+			message.Offset, topic, headerValue(message, bus.HeaderError), string(message.Value))
+		return nil
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic:   topic,
+		Value:   sarama.ByteEncoder(message.Value),
+		Headers: headers,
+	}
+	if message.Key != nil {
+		msg.Key = sarama.ByteEncoder(message.Key)
+	}
+
+	if _, _, err := r.writer.SendMessage(msg); err != nil {
+		return fmt.Errorf("failed to send message to %s: %w", topic, err)
+	}
+
+	log.Printf("Replayed offset %d to %s", message.Offset, topic)
+	return nil
+}
+
+func headerValue(message *sarama.ConsumerMessage, key string) string {
+	for _, h := range message.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// getEnv gets an environment variable with a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
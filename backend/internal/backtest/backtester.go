@@ -0,0 +1,168 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/dedupe"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+// Hit is an offer that would have been notified for a wishlist
+type Hit struct {
+	OfferID            int       `json:"offer_id"`
+	ProductName        string    `json:"product_name"`
+	Source             string    `json:"source,omitempty"`
+	Price              float64   `json:"price"`
+	OriginalPrice      float64   `json:"original_price,omitempty"`
+	DiscountPercentage int       `json:"discount_percentage,omitempty"`
+	CashbackPercentage int       `json:"cashback_percentage,omitempty"`
+	URL                string    `json:"url,omitempty"`
+	MatchType          string    `json:"match_type"`
+	ReceivedAt         time.Time `json:"received_at"`
+}
+
+// WishlistReport summarizes the would-be notifications of one wishlist
+type WishlistReport struct {
+	WishlistID   int     `json:"wishlist_id"`
+	ProductName  string  `json:"product_name"`
+	Hits         []Hit   `json:"hits"`
+	HitCount     int     `json:"hit_count"`
+	DaysWithHits int     `json:"days_with_hits"`
+	LowestPrice  float64 `json:"lowest_price,omitempty"`
+}
+
+// Report is the result of a backtest run. Truncated is set when the period
+// had more price events of candidate offers than a run replays.
+type Report struct {
+	From          time.Time        `json:"from"`
+	To            time.Time        `json:"to"`
+	Threshold     float64          `json:"threshold"`
+	OffersScanned int              `json:"offers_scanned"`
+	Truncated     bool             `json:"truncated,omitempty"`
+	Wishlists     []WishlistReport `json:"wishlists"`
+}
+
+// MaxEvents is the largest number of price events replayed in one run
+const MaxEvents = 50000
+
+// Backtester replays stored offers through the offer matcher to show what
+// would have been notified for a set of wishlists
+type Backtester struct {
+	repo        *repository.OfferRepository
+	matcher     *matcher.OfferMatcher
+	dedupWindow time.Duration
+}
+
+func NewBacktester(repo *repository.OfferRepository, offerMatcher *matcher.OfferMatcher, dedupWindow time.Duration) *Backtester {
+	return &Backtester{
+		repo:        repo,
+		matcher:     offerMatcher,
+		dedupWindow: dedupWindow,
+	}
+}
+
+// Run matches every offer price recorded between from and to against the
+// wishlists. Only the events of offers the wishlist index picks as candidates
+// for some wishlist are loaded, oldest first and up to MaxEvents. Repeated alerts are suppressed like the notification deduplicator
+// does: the same offer is only notified again after the window or when it got
+// better on the metric that made the match.
+func (b *Backtester) Run(ctx context.Context, wishlists []models.Wishlist, from, to time.Time) (*Report, error) {
+	names, err := b.repo.GetOfferNamesBetween(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load offer names: %w", err)
+	}
+
+	index := matcher.NewWishlistIndex()
+	index.Load(wishlists)
+	var candidateIDs []int
+	for i := range names {
+		if len(index.Candidates(&names[i])) > 0 {
+			candidateIDs = append(candidateIDs, names[i].ID)
+		}
+	}
+
+	var offers []models.Offer
+	if len(candidateIDs) > 0 {
+		offers, err = b.repo.GetOffersBetween(ctx, candidateIDs, from, to, MaxEvents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load offers: %w", err)
+		}
+	}
+
+	report := &Report{
+		From:          from,
+		To:            to,
+		Threshold:     b.matcher.Threshold(),
+		OffersScanned: len(names),
+		Truncated:     len(offers) == MaxEvents,
+		Wishlists:     make([]WishlistReport, len(wishlists)),
+	}
+
+	type sentKey struct {
+		wishlistID int
+		offerKey   string
+	}
+	lastSent := make(map[sentKey]models.Notification)
+
+	for i := range wishlists {
+		wishlist := &wishlists[i]
+		wishlistReport := &report.Wishlists[i]
+		wishlistReport.WishlistID = wishlist.ID
+		wishlistReport.ProductName = wishlist.ProductName
+		wishlistReport.Hits = []Hit{}
+		days := make(map[string]struct{})
+
+		for j := range offers {
+			offer := &offers[j]
+
+			explanation := b.matcher.Explain(offer, wishlist)
+			if !explanation.Matched {
+				continue
+			}
+
+			key := sentKey{wishlist.ID, offer.Key}
+			if offer.Key == "" {
+				// Offers stored before offer keys existed
+				key.offerKey = offer.Source + "|" + offer.ProductName
+			}
+			if last, ok := lastSent[key]; ok && offer.ReceivedAt.Sub(last.SentAt) < b.dedupWindow && !dedupe.Improved(explanation.MatchType, offer, &last) {
+				continue
+			}
+
+			hit := Hit{
+				OfferID:            offer.ID,
+				ProductName:        offer.ProductName,
+				Source:             offer.Source,
+				Price:              offer.Price,
+				OriginalPrice:      offer.OriginalPrice,
+				DiscountPercentage: offer.DiscountPercentage,
+				CashbackPercentage: offer.CashbackPercentage,
+				URL:                offer.URL,
+				MatchType:          explanation.MatchType,
+				ReceivedAt:         offer.ReceivedAt,
+			}
+			price := offer.Price
+			lastSent[key] = models.Notification{
+				Price:              &price,
+				DiscountPercentage: offer.DiscountPercentage,
+				CashbackPercentage: offer.CashbackPercentage,
+				SentAt:             offer.ReceivedAt,
+			}
+
+			wishlistReport.Hits = append(wishlistReport.Hits, hit)
+			days[offer.ReceivedAt.Format("2006-01-02")] = struct{}{}
+			if offer.Price > 0 && (wishlistReport.LowestPrice == 0 || offer.Price < wishlistReport.LowestPrice) {
+				wishlistReport.LowestPrice = offer.Price
+			}
+		}
+
+		wishlistReport.HitCount = len(wishlistReport.Hits)
+		wishlistReport.DaysWithHits = len(days)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,135 @@
+package backtest
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+var eventColumns = []string{
+	"id", "offer_key", "external_id", "seller", "product_name", "price", "original_price",
+	"discount_percentage", "cashback_percentage", "url", "image_url", "source", "recorded_at",
+}
+
+func TestRunReplaysOnlyCandidateOffers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	to := time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -30)
+	day := func(d, h int) time.Time { return from.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour) }
+
+	mock.ExpectQuery("SELECT o.id, o.product_name").
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name"}).
+			AddRow(1, "Geladeira Brastemp Frost Free").
+			AddRow(2, "Air Fryer Mondial").
+			AddRow(3, "Geladeiras Consul"))
+
+	event := func(id int, name string, price float64, at time.Time) []driver.Value {
+		return []driver.Value{id, "promobit:" + name, "", "", name, price, 0.0, 0, 0, "", "", "promobit", at}
+	}
+	mock.ExpectQuery("FROM offer_price_events").
+		WithArgs("{1,3}", from, to, MaxEvents).
+		WillReturnRows(sqlmock.NewRows(eventColumns).
+			AddRow(event(1, "Geladeira Brastemp Frost Free", 3500, day(1, 0))...).
+			AddRow(event(1, "Geladeira Brastemp Frost Free", 2900, day(2, 0))...).
+			AddRow(event(1, "Geladeira Brastemp Frost Free", 2900, day(2, 6))...).
+			AddRow(event(1, "Geladeira Brastemp Frost Free", 2800, day(2, 12))...).
+			AddRow(event(3, "Geladeiras Consul", 2950, day(5, 0))...))
+
+	targetPrice := 3000.0
+	wishlists := []models.Wishlist{{ID: 7, ProductName: "geladeira", TargetPrice: &targetPrice}}
+
+	backtester := NewBacktester(repository.NewOfferRepository(db), matcher.NewOfferMatcher(0.5), 24*time.Hour)
+	report, err := backtester.Run(context.Background(), wishlists, from, to)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.OffersScanned != 3 || report.Truncated {
+		t.Errorf("OffersScanned = %d, Truncated = %t, want 3, false", report.OffersScanned, report.Truncated)
+	}
+
+	result := report.Wishlists[0]
+	// 3500 is above the target and the second 2900 is a repeated alert
+	var prices []float64
+	for _, hit := range result.Hits {
+		prices = append(prices, hit.Price)
+	}
+	if result.HitCount != 3 || prices[0] != 2900 || prices[1] != 2800 || prices[2] != 2950 {
+		t.Errorf("hits at %v, want 2900, 2800 and 2950", prices)
+	}
+	if result.DaysWithHits != 2 || result.LowestPrice != 2800 {
+		t.Errorf("DaysWithHits = %d, LowestPrice = %.2f, want 2, 2800", result.DaysWithHits, result.LowestPrice)
+	}
+}
+
+func TestRunWithoutCandidatesSkipsEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT o.id, o.product_name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name"}).AddRow(2, "Air Fryer Mondial"))
+
+	targetPrice := 3000.0
+	wishlists := []models.Wishlist{{ID: 7, ProductName: "geladeira", TargetPrice: &targetPrice}}
+
+	to := time.Now()
+	backtester := NewBacktester(repository.NewOfferRepository(db), matcher.NewOfferMatcher(0.5), 24*time.Hour)
+	report, err := backtester.Run(context.Background(), wishlists, to.Add(-time.Hour), to)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.OffersScanned != 1 || report.Wishlists[0].HitCount != 0 || report.Wishlists[0].Hits == nil {
+		t.Errorf("report = %+v, want one offer scanned and no hits", report)
+	}
+}
+
+func TestRunReportsTruncatedPeriods(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	to := time.Now()
+	mock.ExpectQuery("SELECT o.id, o.product_name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name"}).AddRow(1, "Geladeira Consul"))
+	rows := sqlmock.NewRows(eventColumns)
+	for i := 0; i < MaxEvents; i++ {
+		rows.AddRow(1, "promobit:1", "", "", "Geladeira Consul", 5000.0, 0.0, 0, 0, "", "", "promobit", to.Add(-time.Minute))
+	}
+	mock.ExpectQuery("FROM offer_price_events").WillReturnRows(rows)
+
+	targetPrice := 3000.0
+	wishlists := []models.Wishlist{{ID: 7, ProductName: "geladeira", TargetPrice: &targetPrice}}
+
+	backtester := NewBacktester(repository.NewOfferRepository(db), matcher.NewOfferMatcher(0.5), 24*time.Hour)
+	report, err := backtester.Run(context.Background(), wishlists, to.Add(-time.Hour), to)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !report.Truncated {
+		t.Error("Truncated = false, want true when MaxEvents events are loaded")
+	}
+}
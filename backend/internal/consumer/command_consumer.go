@@ -1,106 +1,114 @@
-package consumer
-
-import (
-	"context"
-	"encoding/json"
-	"log"
-	"time"
-
-	"github.com/IBM/sarama"
-)
-
-// Command represents a command from the frontend
-type Command struct {
-	Type               string    `json:"type"`
-	TelegramID         int64     `json:"telegram_id"`
-	ChatID             int64     `json:"chat_id,omitempty"`
-	Username           string    `json:"username,omitempty"`
-	FirstName          string    `json:"first_name,omitempty"`
-	LastName           string    `json:"last_name,omitempty"`
-	ProductName        string    `json:"product_name,omitempty"`
-	TargetPrice        *float64  `json:"target_price,omitempty"`
-	DiscountPercentage *int      `json:"discount_percentage,omitempty"`
-	WishlistID         int       `json:"wishlist_id,omitempty"`
-	Timestamp          time.Time `json:"timestamp"`
-}
-
-// ParseCommand parses a command from JSON
-func ParseCommand(data []byte) (*Command, error) {
-	var cmd Command
-	if err := json.Unmarshal(data, &cmd); err != nil {
-		return nil, err
-	}
-	return &cmd, nil
-}
-
-type CommandConsumer struct {
-	ready   chan bool
-	handler func([]byte) error
-}
-
-func NewCommandConsumer(handler func([]byte) error) *CommandConsumer {
-	return &CommandConsumer{
-		ready:   make(chan bool),
-		handler: handler,
-	}
-}
-
-// Setup is run at the beginning of a new session, before ConsumeClaim
-func (consumer *CommandConsumer) Setup(sarama.ConsumerGroupSession) error {
-	// Mark the consumer as ready
-	close(consumer.ready)
-	return nil
-}
-
-// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
-func (consumer *CommandConsumer) Cleanup(sarama.ConsumerGroupSession) error {
-	return nil
-}
-
-// ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages()
-func (consumer *CommandConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for message := range claim.Messages() {
-		// Process the message
-		if err := consumer.handler(message.Value); err != nil {
-			log.Printf("Error handling command: %v", err)
-		}
-		session.MarkMessage(message, "")
-	}
-	return nil
-}
-
-// StartConsumerGroup starts the consumer group
-func StartConsumerGroup(ctx context.Context, brokers []string, topic, groupID string, handler func([]byte) error) error {
-	config := sarama.NewConfig()
-	config.Version = sarama.V2_8_0_0 // Specify a version
-	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetOldest
-
-	client, err := sarama.NewConsumerGroup(brokers, groupID, config)
-	if err != nil {
-		return err
-	}
-
-	consumer := NewCommandConsumer(handler)
-
-	go func() {
-		for {
-			// `Consume` should be called inside an infinite loop, when a
-			// server-side rebalance happens, the consumer session will need to be
-			// recreated to get the new claims
-			if err := client.Consume(ctx, []string{topic}, consumer); err != nil {
-				log.Printf("Error from consumer: %v", err)
-				time.Sleep(time.Second * 5) // Wait before retrying
-			}
-			// check if context was cancelled, signaling that the consumer should stop
-			if ctx.Err() != nil {
-				return
-			}
-			consumer.ready = make(chan bool)
-		}
-	}()
-
-	<-consumer.ready // Wait till the consumer has been set up
-	log.Println("Sarama consumer up and running!...")
-	return nil
-}
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+)
+
+// tracer starts the spans of this package
+var tracer = tracing.NewTracer("github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/consumer")
+
+// Command represents a command from the frontend
+type Command struct {
+	Type               string    `json:"type"`
+	CommandID          string    `json:"command_id,omitempty"`     // Client-generated, used to skip redelivered commands
+	CorrelationID      string    `json:"correlation_id,omitempty"` // Echoed in the response envelope
+	TelegramID         int64     `json:"telegram_id"`
+	ChatID             int64     `json:"chat_id,omitempty"`
+	Username           string    `json:"username,omitempty"`
+	FirstName          string    `json:"first_name,omitempty"`
+	LastName           string    `json:"last_name,omitempty"`
+	ProductName        string    `json:"product_name,omitempty"`
+	TargetPrice        *float64  `json:"target_price,omitempty"`
+	DiscountPercentage *int      `json:"discount_percentage,omitempty"`
+	MinPrice           *float64  `json:"min_price,omitempty"`
+	ExcludedTerms      []string  `json:"excluded_terms,omitempty"`
+	MinCashback        *int      `json:"min_cashback,omitempty"`
+	MaxEffectivePrice  *float64  `json:"max_effective_price,omitempty"`
+	WishlistID         int       `json:"wishlist_id,omitempty"`
+	HideSuspicious     *bool     `json:"hide_suspicious,omitempty"`
+	Timestamp          time.Time `json:"timestamp"`
+}
+
+// ParseCommand parses a command from JSON
+func ParseCommand(data []byte) (*Command, error) {
+	var cmd Command
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// CommandConsumer runs a message handler with retries, moving messages that
+// keep failing to their dead-letter topic
+type CommandConsumer struct {
+	handler     func(context.Context, []byte) error
+	groupID     string
+	retry       bus.RetryPolicy
+	deadLetters *bus.DeadLetterWriter // Nil drops messages that keep failing
+}
+
+func NewCommandConsumer(handler func(context.Context, []byte) error, groupID string, retry bus.RetryPolicy, deadLetters *bus.DeadLetterWriter) *CommandConsumer {
+	return &CommandConsumer{
+		handler:     handler,
+		groupID:     groupID,
+		retry:       retry,
+		deadLetters: deadLetters,
+	}
+}
+
+// Handle is the bus handler of the consumer. It returns nil once the message
+// was handled or sent to the dead-letter topic, so only then is it marked.
+// The handler runs in a span continuing the trace of the message producer.
+func (consumer *CommandConsumer) Handle(ctx context.Context, message *bus.Message) error {
+	spanCtx, span := tracer.StartConsumerSpan(ctx, message, consumer.groupID)
+	attempts, err := consumer.retry.Run(ctx, func() error {
+		return consumer.handler(spanCtx, message.Value)
+	})
+	tracing.End(span, err)
+
+	if err == nil {
+		return nil
+	}
+
+	// Rebalance or shutdown while retrying, the message is consumed again
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	log.Printf("Error handling message from %s [%d] at offset %d after %d attempt(s): %v",
+		message.Topic, message.Partition, message.Offset, attempts, err)
+
+	if consumer.deadLetters == nil {
+		log.Printf("Dropping message from %s at offset %d", message.Topic, message.Offset)
+		return nil
+	}
+
+	if dlqErr := consumer.deadLetters.Send(ctx, message, consumer.groupID, err, attempts); dlqErr != nil {
+		// Leave the message unmarked so it is consumed again
+		return fmt.Errorf("failed to dead-letter message: %w", dlqErr)
+	}
+	log.Printf("Sent message from %s at offset %d to %s", message.Topic, message.Offset, bus.DeadLetterTopic(message.Topic))
+	return nil
+}
+
+// StartConsumerGroup subscribes the handler to the topic. Handler errors are
+// retried as set by retry, then the message is sent to its dead-letter topic.
+// The returned status reports whether the member has joined the group.
+func StartConsumerGroup(ctx context.Context, subscriber bus.Subscriber, topic, groupID string, retry bus.RetryPolicy, deadLetters *bus.DeadLetterWriter, handler func(context.Context, []byte) error) (*bus.Status, error) {
+	return subscriber.Subscribe(ctx, bus.Subscription{Topic: topic, Group: groupID},
+		NewCommandConsumer(handler, groupID, retry, deadLetters).Handle)
+}
+
+// StartConsumerGroupFromNewest is StartConsumerGroup for groups that skip the
+// messages published before they first joined
+func StartConsumerGroupFromNewest(ctx context.Context, subscriber bus.Subscriber, topic, groupID string, retry bus.RetryPolicy, deadLetters *bus.DeadLetterWriter, handler func(context.Context, []byte) error) (*bus.Status, error) {
+	return subscriber.Subscribe(ctx, bus.Subscription{Topic: topic, Group: groupID, FromNewest: true},
+		NewCommandConsumer(handler, groupID, retry, deadLetters).Handle)
+}
@@ -0,0 +1,98 @@
+package dedupe
+
+import (
+	"log"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+// NotificationDeduplicator suppresses notifications already sent for the same
+// wishlist and offer (by offer key) within a time window, unless the offer got
+// better since then (see Improved).
+type NotificationDeduplicator struct {
+	repo   *repository.NotificationRepository
+	window time.Duration
+}
+
+func NewNotificationDeduplicator(repo *repository.NotificationRepository, window time.Duration) *NotificationDeduplicator {
+	return &NotificationDeduplicator{
+		repo:   repo,
+		window: window,
+	}
+}
+
+// Filter returns the notifications that should be sent for the offer
+func (d *NotificationDeduplicator) Filter(offer *models.Offer, notifications []models.OfferNotification) []models.OfferNotification {
+	var result []models.OfferNotification
+	since := time.Now().Add(-d.window)
+
+	for _, n := range notifications {
+		last, err := d.repo.GetLastNotification(n.WishlistID, offer.Key, since)
+		if err != nil {
+			// Prefer a duplicate alert over a missed one
+			log.Printf("Failed to check notification history: %v", err)
+			result = append(result, n)
+			continue
+		}
+
+		if last != nil && !Improved(n.MatchType, offer, last) {
+			log.Printf("Skipping duplicate notification for wishlist %d: '%s' already sent at R$ %.2f with %d%% cashback",
+				n.WishlistID, offer.ProductName, last.EffectivePrice(), last.CashbackPercentage)
+			continue
+		}
+
+		result = append(result, n)
+	}
+
+	return result
+}
+
+// Improved reports whether the offer is better than the last one notified for
+// a wishlist, on the metric that made the match: the discount for discount
+// alerts, the price after cashback for cashback and effective price alerts
+// (a higher cashback at the same price counts) and the price otherwise
+func Improved(matchType string, offer *models.Offer, last *models.Notification) bool {
+	if last.Price == nil {
+		return true
+	}
+
+	switch matchType {
+	case models.MatchTypeDiscount:
+		return offer.DiscountPercentage > last.DiscountPercentage
+	case models.MatchTypeCashback, models.MatchTypeEffectivePrice:
+		return offer.EffectivePrice() < last.EffectivePrice()
+	default:
+		return offer.Price < *last.Price
+	}
+}
+
+// Record stores the sent notifications so later offers can be compared against them
+func (d *NotificationDeduplicator) Record(offer *models.Offer, notifications []models.OfferNotification) {
+	now := time.Now()
+
+	for _, n := range notifications {
+		wishlistID := n.WishlistID
+		price := offer.Price
+		notification := &models.Notification{
+			TelegramID:         n.TelegramID,
+			WishlistID:         &wishlistID,
+			OfferKey:           offer.Key,
+			ProductName:        offer.ProductName,
+			Source:             offer.Source,
+			Price:              &price,
+			DiscountPercentage: offer.DiscountPercentage,
+			CashbackPercentage: offer.CashbackPercentage,
+			SentAt:             now,
+		}
+		if offer.ID != 0 {
+			offerID := offer.ID
+			notification.OfferID = &offerID
+		}
+
+		if err := d.repo.SaveNotification(notification); err != nil {
+			log.Printf("Failed to record notification for wishlist %d: %v", n.WishlistID, err)
+		}
+	}
+}
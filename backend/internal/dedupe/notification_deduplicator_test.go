@@ -0,0 +1,129 @@
+package dedupe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+func TestImproved(t *testing.T) {
+	price := 1000.0
+	last := &models.Notification{Price: &price, DiscountPercentage: 20, CashbackPercentage: 5}
+
+	tests := []struct {
+		name      string
+		matchType string
+		offer     models.Offer
+		want      bool
+	}{
+		{"price: same offer", models.MatchTypePrice, models.Offer{Price: 1000, CashbackPercentage: 5}, false},
+		{"price: lower price", models.MatchTypePrice, models.Offer{Price: 999, CashbackPercentage: 5}, true},
+		{"price: higher cashback only", models.MatchTypePrice, models.Offer{Price: 1000, CashbackPercentage: 10}, false},
+		{"discount: same discount", models.MatchTypeDiscount, models.Offer{Price: 990, DiscountPercentage: 20}, false},
+		{"discount: higher discount", models.MatchTypeDiscount, models.Offer{Price: 1000, DiscountPercentage: 25}, true},
+		{"cashback: same offer", models.MatchTypeCashback, models.Offer{Price: 1000, CashbackPercentage: 5}, false},
+		{"cashback: higher cashback at the same price", models.MatchTypeCashback, models.Offer{Price: 1000, CashbackPercentage: 10}, true},
+		{"cashback: lower price at the same cashback", models.MatchTypeCashback, models.Offer{Price: 950, CashbackPercentage: 5}, true},
+		{"cashback: higher cashback but higher price after it", models.MatchTypeCashback, models.Offer{Price: 1100, CashbackPercentage: 10}, false},
+		{"effective price: higher cashback at the same price", models.MatchTypeEffectivePrice, models.Offer{Price: 1000, CashbackPercentage: 8}, true},
+		{"effective price: lower cashback", models.MatchTypeEffectivePrice, models.Offer{Price: 1000, CashbackPercentage: 2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Improved(tt.matchType, &tt.offer, last); got != tt.want {
+				t.Errorf("Improved() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImprovedWithoutRecordedPrice(t *testing.T) {
+	if !Improved(models.MatchTypePrice, &models.Offer{Price: 1000}, &models.Notification{}) {
+		t.Error("Improved() = false for a notification without price, want true")
+	}
+}
+
+var lastNotificationColumns = []string{
+	"id", "telegram_id", "wishlist_id", "offer_id", "offer_key", "product_name", "source", "price",
+	"discount_percentage", "cashback_percentage", "sent_at",
+}
+
+func TestFilterComparesOnTheMatchMetric(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	offer := &models.Offer{Key: "promobit:1", ProductName: "Air Fryer", Price: 500, CashbackPercentage: 10}
+	notifications := []models.OfferNotification{
+		{WishlistID: 1, TelegramID: 10, MatchType: models.MatchTypePrice},
+		{WishlistID: 2, TelegramID: 20, MatchType: models.MatchTypeCashback},
+		{WishlistID: 3, TelegramID: 30, MatchType: models.MatchTypeEffectivePrice},
+	}
+
+	// Every wishlist was notified at the same price with 5% cashback
+	for _, n := range notifications {
+		mock.ExpectQuery("FROM notifications").
+			WithArgs(n.WishlistID, offer.Key, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(lastNotificationColumns).
+				AddRow(1, n.TelegramID, n.WishlistID, nil, offer.Key, offer.ProductName, "promobit", 500.0, 0, 5, time.Now()))
+	}
+
+	deduplicator := NewNotificationDeduplicator(repository.NewNotificationRepository(db), 24*time.Hour)
+	got := deduplicator.Filter(offer, notifications)
+
+	if len(got) != 2 || got[0].WishlistID != 2 || got[1].WishlistID != 3 {
+		t.Fatalf("Filter() kept %v, want the cashback and effective price alerts", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFilterKeepsNewAndFailedLookups(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	offer := &models.Offer{Key: "promobit:1", ProductName: "Air Fryer", Price: 500}
+	notifications := []models.OfferNotification{
+		{WishlistID: 1, MatchType: models.MatchTypePrice},
+		{WishlistID: 2, MatchType: models.MatchTypePrice},
+	}
+
+	mock.ExpectQuery("FROM notifications").WillReturnRows(sqlmock.NewRows(lastNotificationColumns))
+	mock.ExpectQuery("FROM notifications").WillReturnError(sqlmock.ErrCancelled)
+
+	deduplicator := NewNotificationDeduplicator(repository.NewNotificationRepository(db), 24*time.Hour)
+	if got := deduplicator.Filter(offer, notifications); len(got) != 2 {
+		t.Fatalf("Filter() kept %d notifications, want 2", len(got))
+	}
+}
+
+func TestRecordStoresTheMatchMetrics(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	offer := &models.Offer{ID: 7, Key: "promobit:1", ProductName: "Air Fryer", Source: "promobit", Price: 500, DiscountPercentage: 30, CashbackPercentage: 10}
+
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(int64(10), 1, 7, offer.Key, offer.ProductName, offer.Source, 500.0, 30, 10, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	deduplicator := NewNotificationDeduplicator(repository.NewNotificationRepository(db), 24*time.Hour)
+	deduplicator.Record(offer, []models.OfferNotification{{WishlistID: 1, TelegramID: 10}})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,102 @@
+package fakediscount
+
+import (
+	"log"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/history"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+// Detector flags notifications whose offer claims an original price above the
+// recorded price history of the product, and drops them for users who opted
+// out of such offers
+type Detector struct {
+	history *history.PriceHistory
+	users   *repository.UserRepository
+}
+
+func NewDetector(priceHistory *history.PriceHistory, users *repository.UserRepository) *Detector {
+	return &Detector{
+		history: priceHistory,
+		users:   users,
+	}
+}
+
+// Filter flags the notifications of an offer with a suspicious reference price
+// and returns the ones that should be sent. Wishlists are the match candidates
+// of the offer.
+func (d *Detector) Filter(offer *models.Offer, notifications []models.OfferNotification, wishlists []models.Wishlist) []models.OfferNotification {
+	// No discount claimed
+	if len(notifications) == 0 || offer.OriginalPrice <= offer.Price {
+		return notifications
+	}
+
+	// Prefer the history of the offer itself; the wishlist term history covers
+	// offers seen for the first time
+	offerCheck, err := d.history.CheckReference(offer, history.OfferKey(offer))
+	if err != nil {
+		log.Printf("Failed to check reference price: %v", err)
+		return notifications
+	}
+
+	byID := make(map[int]*models.Wishlist, len(wishlists))
+	for i := range wishlists {
+		byID[wishlists[i].ID] = &wishlists[i]
+	}
+	termChecks := make(map[string]*history.ReferenceCheck)
+	hides := make(map[int64]bool)
+
+	var result []models.OfferNotification
+	for _, n := range notifications {
+		check := offerCheck
+		if wishlist, ok := byID[n.WishlistID]; ok && !check.Conclusive() {
+			check = d.termCheck(offer, wishlist, termChecks)
+		}
+
+		if !check.Suspicious {
+			result = append(result, n)
+			continue
+		}
+
+		n.SuspiciousReference = true
+		n.HighestPrice = check.HighestPrice
+
+		hide, ok := hides[n.TelegramID]
+		if !ok {
+			hide, err = d.users.HidesSuspiciousOffers(n.TelegramID)
+			if err != nil {
+				// Prefer a flagged alert over a missed one
+				log.Printf("Failed to get user preferences: %v", err)
+			}
+			hides[n.TelegramID] = hide
+		}
+
+		if hide {
+			log.Printf("Skipping notification for wishlist %d: '%s' claims R$ %.2f, highest recorded price is R$ %.2f",
+				n.WishlistID, offer.ProductName, offer.OriginalPrice, check.HighestPrice)
+			continue
+		}
+
+		result = append(result, n)
+	}
+
+	return result
+}
+
+// termCheck checks the offer against the history of a wishlist term, once per term
+func (d *Detector) termCheck(offer *models.Offer, wishlist *models.Wishlist, checks map[string]*history.ReferenceCheck) *history.ReferenceCheck {
+	key := history.WishlistKey(wishlist)
+	if check, ok := checks[key]; ok {
+		return check
+	}
+
+	check, err := d.history.CheckReference(offer, key)
+	if err != nil {
+		log.Printf("Failed to check reference price: %v", err)
+		check = &history.ReferenceCheck{ProductKey: key}
+	}
+
+	checks[key] = check
+	return check
+}
@@ -0,0 +1,159 @@
+package fakediscount
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/history"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+// highestPrice is the highest price of every day of the mocked histories, so
+// original prices above R$ 2750 are more than 10% above it
+const highestPrice = 2500.0
+
+var receivedAt = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+var wishlists = []models.Wishlist{
+	{ID: 1, TelegramID: 10, ProductName: "geladeira"},
+	{ID: 2, TelegramID: 20, ProductName: "geladeira consul"},
+}
+
+// expectHistory expects the daily prices of a product key, with the given
+// number of days before the offer
+func expectHistory(mock sqlmock.Sqlmock, key string, days int) {
+	rows := sqlmock.NewRows([]string{"product_key", "day", "min_price", "max_price", "samples"})
+	for i := days; i > 0; i-- {
+		day := time.Date(2026, 10, 16-i, 0, 0, 0, 0, time.UTC)
+		rows.AddRow(key, day, highestPrice-100, highestPrice, 1)
+	}
+	mock.ExpectQuery("FROM price_history").WithArgs(key, sqlmock.AnyArg()).WillReturnRows(rows)
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		originalPrice float64
+		offerDays     int            // Days of history of the offer
+		termDays      map[int]int    // Days of history of the wishlist terms, checked when the offer history is inconclusive
+		hides         map[int64]bool // Preferences of the users of flagged notifications
+		want          []string
+	}{
+		{
+			name:          "no discount claimed",
+			originalPrice: 2000,
+			want:          []string{"1", "2"},
+		},
+		{
+			name:          "at the threshold",
+			originalPrice: 2750,
+			offerDays:     3,
+			want:          []string{"1", "2"},
+		},
+		{
+			name:          "just above the threshold",
+			originalPrice: 2751,
+			offerDays:     3,
+			hides:         map[int64]bool{10: false, 20: false},
+			want:          []string{"1 flagged", "2 flagged"},
+		},
+		{
+			name:          "fewer than 3 prior days is inconclusive",
+			originalPrice: 5000,
+			offerDays:     2,
+			termDays:      map[int]int{1: 2, 2: 0},
+			want:          []string{"1", "2"},
+		},
+		{
+			name:          "offer history preferred over the terms",
+			originalPrice: 2700,
+			offerDays:     90,
+			want:          []string{"1", "2"},
+		},
+		{
+			name:          "falls back to the term history",
+			originalPrice: 5000,
+			offerDays:     1,
+			termDays:      map[int]int{1: 3, 2: 2},
+			hides:         map[int64]bool{10: false},
+			want:          []string{"1 flagged", "2"},
+		},
+		{
+			name:          "opt-out hides flagged offers",
+			originalPrice: 5000,
+			offerDays:     3,
+			hides:         map[int64]bool{10: true, 20: false},
+			want:          []string{"2 flagged"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			offerMatcher := matcher.NewOfferMatcher(0.5)
+			priceHistory := history.NewPriceHistory(repository.NewPriceHistoryRepository(db), offerMatcher, time.UTC)
+			detector := NewDetector(priceHistory, repository.NewUserRepository(db))
+
+			offer := &models.Offer{Key: "promobit:1", ProductName: "Geladeira Consul 300L", Price: 2000, OriginalPrice: tt.originalPrice, ReceivedAt: receivedAt}
+			var notifications []models.OfferNotification
+			for _, w := range wishlists {
+				notifications = append(notifications, models.OfferNotification{TelegramID: w.TelegramID, WishlistID: w.ID, Price: offer.Price})
+			}
+
+			// Queries run in order: the offer history, then per notification the
+			// term history when needed and the preference of flagged users
+			if tt.originalPrice > offer.Price {
+				expectHistory(mock, history.OfferKey(offer), tt.offerDays)
+				for _, w := range wishlists {
+					if days, ok := tt.termDays[w.ID]; ok {
+						expectHistory(mock, history.WishlistKey(&w), days)
+					}
+					if hide, ok := tt.hides[w.TelegramID]; ok {
+						mock.ExpectQuery("FROM users").WithArgs(w.TelegramID).
+							WillReturnRows(sqlmock.NewRows([]string{"hide"}).AddRow(hide))
+					}
+				}
+			}
+
+			// Unexpected queries fail and are only logged by Filter
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			filtered := detector.Filter(offer, notifications, wishlists)
+			log.SetOutput(os.Stderr)
+			if strings.Contains(logs.String(), "Failed") {
+				t.Errorf("Filter() logged failures:\n%s", logs.String())
+			}
+
+			var got []string
+			for _, n := range filtered {
+				result := fmt.Sprint(n.WishlistID)
+				if n.SuspiciousReference {
+					result += " flagged"
+					if n.HighestPrice != highestPrice {
+						t.Errorf("notification for wishlist %d highest price = %.2f, want %.2f", n.WishlistID, n.HighestPrice, highestPrice)
+					}
+				}
+				got = append(got, result)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/backtest"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+// BacktestRequest selects the wishlists and the period to backtest. The period
+// defaults to the configured backtest window ending now; Threshold defaults to
+// the matcher threshold and can be set to try other values.
+type BacktestRequest struct {
+	WishlistIDs []int      `json:"wishlist_ids"`
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	Threshold   float64    `json:"threshold,omitempty"`
+}
+
+// BacktestHandler runs wishlists against stored offers
+type BacktestHandler struct {
+	repo        *repository.WishlistRepository
+	offerRepo   *repository.OfferRepository
+	matcher     *matcher.OfferMatcher
+	dedupWindow time.Duration
+	window      time.Duration
+}
+
+func NewBacktestHandler(repo *repository.WishlistRepository, offerRepo *repository.OfferRepository, offerMatcher *matcher.OfferMatcher, dedupWindow, window time.Duration) *BacktestHandler {
+	return &BacktestHandler{
+		repo:        repo,
+		offerRepo:   offerRepo,
+		matcher:     offerMatcher,
+		dedupWindow: dedupWindow,
+		window:      window,
+	}
+}
+
+// ServeHTTP handles POST /backtest
+func (h *BacktestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BacktestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.WishlistIDs) == 0 {
+		http.Error(w, "wishlist_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now()
+	if req.To != nil {
+		to = *req.To
+	}
+	from := to.Add(-h.window)
+	if req.From != nil {
+		from = *req.From
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	wishlists := make([]models.Wishlist, 0, len(req.WishlistIDs))
+	for _, id := range req.WishlistIDs {
+		wishlist, err := h.repo.GetWishlistByID(id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("Wishlist %d not found", id), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to run backtest: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		wishlists = append(wishlists, *wishlist)
+	}
+
+	offerMatcher := h.matcher
+	if req.Threshold != 0 {
+		if req.Threshold < 0 || req.Threshold > 1 {
+			http.Error(w, "threshold must be between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		offerMatcher = matcher.NewOfferMatcher(req.Threshold)
+	}
+
+	report, err := backtest.NewBacktester(h.offerRepo, offerMatcher, h.dedupWindow).Run(r.Context(), wishlists, from, to)
+	if err != nil {
+		log.Printf("Failed to run backtest: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
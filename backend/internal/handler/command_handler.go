@@ -1,255 +1,792 @@
-package handler
-
-import (
-	"database/sql"
-	"encoding/json"
-	"fmt"
-	"log"
-	"time"
-
-	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/consumer"
-	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
-	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
-	"github.com/IBM/sarama"
-	"github.com/go-redis/redis/v8"
-)
-
-type CommandHandler struct {
-	repo                *repository.WishlistRepository
-	responseWriter      sarama.SyncProducer
-	responseTopic       string
-	wishlistEventsTopic string
-}
-
-func NewCommandHandler(db *sql.DB, redisClient *redis.Client, responseWriter sarama.SyncProducer, responseTopic, wishlistEventsTopic string) *CommandHandler {
-	return &CommandHandler{
-		repo:                repository.NewWishlistRepository(db, redisClient),
-		responseWriter:      responseWriter,
-		responseTopic:       responseTopic,
-		wishlistEventsTopic: wishlistEventsTopic,
-	}
-}
-
-// HandleCommand processes a command from the frontend
-func (h *CommandHandler) HandleCommand(data []byte) error {
-	cmd, err := consumer.ParseCommand(data)
-	if err != nil {
-		return fmt.Errorf("failed to parse command: %w", err)
-	}
-
-	log.Printf("Handling command: %s for user %d", cmd.Type, cmd.TelegramID)
-
-	switch cmd.Type {
-	case "register_user":
-		return h.handleRegisterUser(cmd)
-	case "add_wishlist":
-		return h.handleAddWishlist(cmd)
-	case "list_wishlist":
-		return h.handleListWishlist(cmd)
-	case "delete_wishlist":
-		return h.handleDeleteWishlist(cmd)
-	default:
-		log.Printf("Unknown command type: %s", cmd.Type)
-	}
-
-	return nil
-}
-
-// handleRegisterUser registers or updates a user
-func (h *CommandHandler) handleRegisterUser(cmd *consumer.Command) error {
-	user := &models.User{
-		TelegramID: cmd.TelegramID,
-		Username:   cmd.Username,
-		FirstName:  cmd.FirstName,
-		LastName:   cmd.LastName,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-	}
-
-	query := `
-		INSERT INTO users (telegram_id, username, first_name, last_name, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (telegram_id) 
-		DO UPDATE SET username = $2, first_name = $3, last_name = $4, updated_at = $6
-	`
-
-	_, err := h.repo.GetDB().Exec(query,
-		user.TelegramID,
-		user.Username,
-		user.FirstName,
-		user.LastName,
-		user.CreatedAt,
-		user.UpdatedAt,
-	)
-
-	if err != nil {
-		log.Printf("Error registering user: %v", err)
-		return err
-	}
-
-	log.Printf("User registered: %d (%s)", user.TelegramID, user.Username)
-	return nil
-}
-
-// handleAddWishlist adds a wishlist item
-func (h *CommandHandler) handleAddWishlist(cmd *consumer.Command) error {
-	wishlist := &models.Wishlist{
-		TelegramID:         cmd.TelegramID,
-		ProductName:        cmd.ProductName,
-		TargetPrice:        cmd.TargetPrice,
-		DiscountPercentage: cmd.DiscountPercentage,
-		CreatedAt:          time.Now(),
-	}
-
-	query := `
-		INSERT INTO wishlists (telegram_id, product_name, target_price, discount_percentage, created_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
-	`
-
-	err := h.repo.GetDB().QueryRow(
-		query,
-		wishlist.TelegramID,
-		wishlist.ProductName,
-		wishlist.TargetPrice,
-		wishlist.DiscountPercentage,
-		wishlist.CreatedAt,
-	).Scan(&wishlist.ID)
-
-	if err != nil {
-		log.Printf("Error adding wishlist item: %v", err)
-		return err
-	}
-
-	h.repo.InvalidateUserCache(wishlist.TelegramID)
-	log.Printf("Wishlist item added: %d for user %d", wishlist.ID, wishlist.TelegramID)
-
-	// Publish event to Kafka
-	event := models.WishlistEvent{
-		Type:               "wishlist_item_added",
-		TelegramID:         wishlist.TelegramID,
-		ProductName:        wishlist.ProductName,
-		TargetPrice:        wishlist.TargetPrice,
-		DiscountPercentage: wishlist.DiscountPercentage,
-		Timestamp:          time.Now(),
-	}
-
-	if err := h.publishEvent(event); err != nil {
-		log.Printf("Failed to publish wishlist event: %v", err)
-		// Don't fail the request, just log the error
-	}
-
-	return nil
-}
-
-// handleListWishlist retrieves and sends wishlist items
-func (h *CommandHandler) handleListWishlist(cmd *consumer.Command) error {
-	wishlists, err := h.repo.GetWishlistsByTelegramID(cmd.TelegramID)
-	if err != nil {
-		log.Printf("Error getting wishlists: %v", err)
-		return err
-	}
-
-	// Convert to response format
-	items := make([]WishlistItem, len(wishlists))
-	for i, w := range wishlists {
-		items[i] = WishlistItem{
-			ID:                 w.ID,
-			ProductName:        w.ProductName,
-			TargetPrice:        w.TargetPrice,
-			DiscountPercentage: w.DiscountPercentage,
-		}
-	}
-
-	response := WishlistResponse{
-		ChatID: cmd.ChatID,
-		Items:  items,
-	}
-
-	return h.sendResponse(response)
-}
-
-// handleDeleteWishlist deletes a wishlist item
-func (h *CommandHandler) handleDeleteWishlist(cmd *consumer.Command) error {
-	query := `
-		DELETE FROM wishlists
-		WHERE id = $1 AND telegram_id = $2
-	`
-
-	result, err := h.repo.GetDB().Exec(query, cmd.WishlistID, cmd.TelegramID)
-	if err != nil {
-		log.Printf("Error deleting wishlist item: %v", err)
-		return err
-	}
-
-	rowsAffected, _ := result.RowsAffected()
-	success := rowsAffected > 0
-
-	if success {
-		h.repo.InvalidateUserCache(cmd.TelegramID)
-		log.Printf("Wishlist item deleted: %d for user %d", cmd.WishlistID, cmd.TelegramID)
-	}
-
-	response := DeleteResponse{
-		ChatID:  cmd.ChatID,
-		Success: success,
-	}
-
-	return h.sendResponse(response)
-}
-
-// sendResponse sends a response back to the frontend via Kafka
-func (h *CommandHandler) sendResponse(response interface{}) error {
-	data, err := json.Marshal(response)
-	if err != nil {
-		return fmt.Errorf("failed to marshal response: %w", err)
-	}
-
-	msg := &sarama.ProducerMessage{
-		Topic: h.responseTopic,
-		Value: sarama.ByteEncoder(data),
-	}
-
-	_, _, err = h.responseWriter.SendMessage(msg)
-	if err != nil {
-		return fmt.Errorf("failed to send response: %w", err)
-	}
-
-	return nil
-}
-
-// publishEvent publishes a wishlist event to Kafka
-func (h *CommandHandler) publishEvent(event models.WishlistEvent) error {
-	data, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
-	}
-
-	msg := &sarama.ProducerMessage{
-		Topic: h.wishlistEventsTopic,
-		Value: sarama.ByteEncoder(data),
-	}
-
-	_, _, err = h.responseWriter.SendMessage(msg)
-	return err
-}
-
-// WishlistItem represents a wishlist item in the response
-type WishlistItem struct {
-	ID                 int      `json:"id"`
-	ProductName        string   `json:"product_name"`
-	TargetPrice        *float64 `json:"target_price,omitempty"`
-	DiscountPercentage *int     `json:"discount_percentage,omitempty"`
-}
-
-// WishlistResponse represents the response to a list command
-type WishlistResponse struct {
-	ChatID int64          `json:"chat_id"`
-	Items  []WishlistItem `json:"items"`
-}
-
-// DeleteResponse represents the response to a delete command
-type DeleteResponse struct {
-	ChatID  int64 `json:"chat_id"`
-	Success bool  `json:"success"`
-}
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/backtest"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/consumer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/history"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/metrics"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+	"github.com/go-redis/redis/v8"
+	"github.com/lib/pq"
+)
+
+// tracer starts the spans of this package
+var tracer = tracing.NewTracer("github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/handler")
+
+// Backtest summaries run in the background after an add command, bounded in
+// time and in how many run at once
+const (
+	backtestTimeout        = 30 * time.Second
+	maxConcurrentBacktests = 4
+)
+
+type CommandHandler struct {
+	repo           *repository.WishlistRepository
+	users          *repository.UserRepository
+	commandLog     *repository.CommandLogRepository
+	outbox         *repository.OutboxRepository
+	responseWriter bus.Publisher
+	responseTopic  string
+	backtester     *backtest.Backtester
+	backtestWindow time.Duration
+	priceHistory   *history.PriceHistory
+	backtests      chan struct{}
+	running        sync.WaitGroup
+}
+
+func NewCommandHandler(db *sql.DB, redisClient *redis.Client, responseWriter bus.Publisher, responseTopic string, backtester *backtest.Backtester, backtestWindow time.Duration, priceHistory *history.PriceHistory) *CommandHandler {
+	return &CommandHandler{
+		repo:           repository.NewWishlistRepository(db, redisClient),
+		users:          repository.NewUserRepository(db),
+		commandLog:     repository.NewCommandLogRepository(db),
+		outbox:         repository.NewOutboxRepository(db),
+		responseWriter: responseWriter,
+		responseTopic:  responseTopic,
+		backtester:     backtester,
+		backtestWindow: backtestWindow,
+		priceHistory:   priceHistory,
+		backtests:      make(chan struct{}, maxConcurrentBacktests),
+	}
+}
+
+// Wait blocks until the backtest summaries running in the background are sent
+func (h *CommandHandler) Wait() {
+	h.running.Wait()
+}
+
+// HandleCommand processes a command from the frontend
+func (h *CommandHandler) HandleCommand(ctx context.Context, data []byte) error {
+	cmd, err := consumer.ParseCommand(data)
+	if err != nil {
+		return bus.Permanent(fmt.Errorf("failed to parse command: %w", err))
+	}
+
+	log.Printf("Handling command: %s for user %d", cmd.Type, cmd.TelegramID)
+
+	// Kafka redelivers commands after rebalances. Commands already applied are
+	// answered again with their original response.
+	if cmd.CommandID != "" {
+		processed, err := h.commandLog.GetProcessedCommand(cmd.CommandID)
+		if err == nil {
+			metrics.Commands.WithLabelValues(cmd.Type, "duplicate").Inc()
+			return h.replayResponse(ctx, cmd, processed)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to check command %s: %w", cmd.CommandID, err)
+		}
+	}
+
+	switch cmd.Type {
+	case "register_user":
+		err = h.handleRegisterUser(ctx, cmd)
+	case "add_wishlist":
+		err = h.handleAddWishlist(ctx, cmd)
+	case "list_wishlist":
+		err = h.handleListWishlist(ctx, cmd)
+	case "update_wishlist":
+		err = h.handleUpdateWishlist(ctx, cmd)
+	case "delete_wishlist":
+		err = h.handleDeleteWishlist(ctx, cmd)
+	case "price_history":
+		err = h.handlePriceHistory(ctx, cmd)
+	case "set_hide_suspicious":
+		err = h.handleSetHideSuspicious(ctx, cmd)
+	default:
+		log.Printf("Unknown command type: %s", cmd.Type)
+		metrics.Commands.WithLabelValues("unknown", "error").Inc()
+		return nil
+	}
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.Commands.WithLabelValues(cmd.Type, result).Inc()
+
+	return err
+}
+
+// handleRegisterUser registers or updates a user
+func (h *CommandHandler) handleRegisterUser(ctx context.Context, cmd *consumer.Command) error {
+	user := &models.User{
+		TelegramID: cmd.TelegramID,
+		Username:   cmd.Username,
+		FirstName:  cmd.FirstName,
+		LastName:   cmd.LastName,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	query := `
+		INSERT INTO users (telegram_id, username, first_name, last_name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (telegram_id) 
+		DO UPDATE SET username = $2, first_name = $3, last_name = $4, updated_at = $6
+	`
+
+	tx, err := h.repo.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(query,
+		user.TelegramID,
+		user.Username,
+		user.FirstName,
+		user.LastName,
+		user.CreatedAt,
+		user.UpdatedAt,
+	)
+
+	if err != nil {
+		log.Printf("Error registering user: %v", err)
+		if rejectedReason(err) != "" {
+			return bus.Permanent(err)
+		}
+		return err
+	}
+
+	applied, err := h.commitCommand(ctx, tx, cmd, "", nil)
+	if err != nil || !applied {
+		return err
+	}
+
+	log.Printf("User registered: %d (%s)", user.TelegramID, user.Username)
+	return nil
+}
+
+// handleAddWishlist adds a wishlist item
+func (h *CommandHandler) handleAddWishlist(ctx context.Context, cmd *consumer.Command) error {
+	wishlist := &models.Wishlist{
+		TelegramID:         cmd.TelegramID,
+		ProductName:        cmd.ProductName,
+		TargetPrice:        cmd.TargetPrice,
+		DiscountPercentage: cmd.DiscountPercentage,
+		MinPrice:           cmd.MinPrice,
+		ExcludedTerms:      cmd.ExcludedTerms,
+		MinCashback:        cmd.MinCashback,
+		MaxEffectivePrice:  cmd.MaxEffectivePrice,
+		CreatedAt:          time.Now(),
+	}
+
+	query := `
+		INSERT INTO wishlists (telegram_id, product_name, target_price, discount_percentage, min_price, excluded_terms,
+			min_cashback, max_effective_price, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	tx, err := h.repo.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		query,
+		wishlist.TelegramID,
+		wishlist.ProductName,
+		wishlist.TargetPrice,
+		wishlist.DiscountPercentage,
+		wishlist.MinPrice,
+		pq.Array(wishlist.ExcludedTerms),
+		wishlist.MinCashback,
+		wishlist.MaxEffectivePrice,
+		wishlist.CreatedAt,
+	).Scan(&wishlist.ID)
+
+	if err != nil {
+		log.Printf("Error adding wishlist item: %v", err)
+		if reason := rejectedReason(err); reason != "" {
+			tx.Rollback()
+			return h.rejectCommand(ctx, cmd, reason, err)
+		}
+		return err
+	}
+
+	// The outbox relay publishes the event once the transaction is committed
+	event := &models.WishlistEvent{
+		Type:               models.WishlistEventAdded,
+		WishlistID:         wishlist.ID,
+		TelegramID:         wishlist.TelegramID,
+		ProductName:        wishlist.ProductName,
+		TargetPrice:        wishlist.TargetPrice,
+		DiscountPercentage: wishlist.DiscountPercentage,
+		MinPrice:           wishlist.MinPrice,
+		ExcludedTerms:      wishlist.ExcludedTerms,
+		MinCashback:        wishlist.MinCashback,
+		MaxEffectivePrice:  wishlist.MaxEffectivePrice,
+		Timestamp:          time.Now(),
+	}
+	if err := h.outbox.AddEvent(ctx, tx, event); err != nil {
+		return err
+	}
+
+	// Confirm the item was saved to frontends waiting for the response
+	var responseType string
+	var response interface{}
+	if cmd.CorrelationID != "" {
+		responseType = models.MessageTypeWishlistAdded
+		response = WishlistAddedResponse{
+			ChatID: cmd.ChatID,
+			Item:   newWishlistItem(wishlist),
+		}
+	}
+
+	applied, err := h.commitCommand(ctx, tx, cmd, responseType, response)
+	if !applied {
+		return err
+	}
+
+	h.repo.InvalidateUserCache(wishlist.TelegramID)
+	log.Printf("Wishlist item added: %d for user %d", wishlist.ID, wishlist.TelegramID)
+
+	// Tell the user how often the target was hit in the past. The backtest
+	// scans stored offers, so it runs off the consumer.
+	if cmd.ChatID != 0 {
+		h.startBacktestSummary(ctx, cmd, wishlist)
+	}
+
+	// A failed response send is retried, replaying the response
+	return err
+}
+
+// startBacktestSummary sends the backtest summary of a new wishlist item in the
+// background. It is skipped when too many backtests are already running.
+func (h *CommandHandler) startBacktestSummary(ctx context.Context, cmd *consumer.Command, wishlist *models.Wishlist) {
+	select {
+	case h.backtests <- struct{}{}:
+	default:
+		log.Printf("Skipping backtest of wishlist %d: %d backtests already running", wishlist.ID, maxConcurrentBacktests)
+		return
+	}
+
+	// Keep the trace of the command but not its cancellation
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backtestTimeout)
+	h.running.Add(1)
+	go func() {
+		defer h.running.Done()
+		defer func() { <-h.backtests }()
+		defer cancel()
+		h.sendBacktestSummary(ctx, cmd, wishlist)
+	}()
+}
+
+// sendBacktestSummary runs a new wishlist item against recent offers and sends
+// how often it would have been notified
+func (h *CommandHandler) sendBacktestSummary(ctx context.Context, cmd *consumer.Command, wishlist *models.Wishlist) {
+	to := time.Now()
+	report, err := h.backtester.Run(ctx, []models.Wishlist{*wishlist}, to.Add(-h.backtestWindow), to)
+	if err != nil {
+		log.Printf("Failed to backtest wishlist %d: %v", wishlist.ID, err)
+		return
+	}
+
+	result := report.Wishlists[0]
+	response := BacktestResponse{
+		ChatID: cmd.ChatID,
+		Backtest: &BacktestSummary{
+			WishlistID:    wishlist.ID,
+			Days:          int(h.backtestWindow.Hours() / 24),
+			OffersScanned: report.OffersScanned,
+			HitCount:      result.HitCount,
+			DaysWithHits:  result.DaysWithHits,
+			LowestPrice:   result.LowestPrice,
+		},
+	}
+
+	if err := h.sendResponse(ctx, models.MessageTypeBacktestSummary, cmd, response); err != nil {
+		log.Printf("Failed to send backtest summary: %v", err)
+	}
+}
+
+// handleListWishlist retrieves and sends wishlist items
+func (h *CommandHandler) handleListWishlist(ctx context.Context, cmd *consumer.Command) error {
+	wishlists, err := h.repo.GetWishlistsByTelegramID(cmd.TelegramID)
+	if err != nil {
+		log.Printf("Error getting wishlists: %v", err)
+		return err
+	}
+
+	// Convert to response format
+	items := make([]WishlistItem, len(wishlists))
+	for i := range wishlists {
+		items[i] = newWishlistItem(&wishlists[i])
+	}
+
+	response := WishlistResponse{
+		ChatID: cmd.ChatID,
+		Items:  items,
+	}
+
+	return h.sendResponse(ctx, models.MessageTypeWishlistList, cmd, response)
+}
+
+// handleUpdateWishlist replaces the product and conditions of a wishlist item
+func (h *CommandHandler) handleUpdateWishlist(ctx context.Context, cmd *consumer.Command) error {
+	wishlist := &models.Wishlist{
+		ID:                 cmd.WishlistID,
+		TelegramID:         cmd.TelegramID,
+		ProductName:        cmd.ProductName,
+		TargetPrice:        cmd.TargetPrice,
+		DiscountPercentage: cmd.DiscountPercentage,
+		MinPrice:           cmd.MinPrice,
+		ExcludedTerms:      cmd.ExcludedTerms,
+		MinCashback:        cmd.MinCashback,
+		MaxEffectivePrice:  cmd.MaxEffectivePrice,
+	}
+
+	query := `
+		UPDATE wishlists
+		SET product_name = $3, target_price = $4, discount_percentage = $5, min_price = $6, excluded_terms = $7,
+		    min_cashback = $8, max_effective_price = $9
+		WHERE id = $1 AND telegram_id = $2
+		RETURNING created_at
+	`
+
+	tx, err := h.repo.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		query,
+		wishlist.ID,
+		wishlist.TelegramID,
+		wishlist.ProductName,
+		wishlist.TargetPrice,
+		wishlist.DiscountPercentage,
+		wishlist.MinPrice,
+		pq.Array(wishlist.ExcludedTerms),
+		wishlist.MinCashback,
+		wishlist.MaxEffectivePrice,
+	).Scan(&wishlist.CreatedAt)
+
+	success := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error updating wishlist item: %v", err)
+		if reason := rejectedReason(err); reason != "" {
+			tx.Rollback()
+			return h.rejectCommand(ctx, cmd, reason, err)
+		}
+		return err
+	}
+
+	// Missing or someone else's item: answered without an event
+	if success {
+		event := &models.WishlistEvent{
+			Type:               models.WishlistEventUpdated,
+			WishlistID:         wishlist.ID,
+			TelegramID:         wishlist.TelegramID,
+			ProductName:        wishlist.ProductName,
+			TargetPrice:        wishlist.TargetPrice,
+			DiscountPercentage: wishlist.DiscountPercentage,
+			MinPrice:           wishlist.MinPrice,
+			ExcludedTerms:      wishlist.ExcludedTerms,
+			MinCashback:        wishlist.MinCashback,
+			MaxEffectivePrice:  wishlist.MaxEffectivePrice,
+			Timestamp:          time.Now(),
+		}
+		if err := h.outbox.AddEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+
+	response := WishlistUpdatedResponse{
+		ChatID:  cmd.ChatID,
+		Success: success,
+	}
+	if success {
+		item := newWishlistItem(wishlist)
+		response.Item = &item
+	}
+
+	applied, err := h.commitCommand(ctx, tx, cmd, models.MessageTypeWishlistUpdated, response)
+	if !applied {
+		return err
+	}
+
+	if success {
+		h.repo.InvalidateUserCache(wishlist.TelegramID)
+		log.Printf("Wishlist item updated: %d for user %d", wishlist.ID, wishlist.TelegramID)
+	}
+
+	// A failed response send is retried, replaying the response
+	return err
+}
+
+// handleDeleteWishlist deletes a wishlist item
+func (h *CommandHandler) handleDeleteWishlist(ctx context.Context, cmd *consumer.Command) error {
+	query := `
+		DELETE FROM wishlists
+		WHERE id = $1 AND telegram_id = $2
+	`
+
+	tx, err := h.repo.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, cmd.WishlistID, cmd.TelegramID)
+	if err != nil {
+		log.Printf("Error deleting wishlist item: %v", err)
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	success := rowsAffected > 0
+
+	if success {
+		event := &models.WishlistEvent{
+			Type:       models.WishlistEventDeleted,
+			WishlistID: cmd.WishlistID,
+			TelegramID: cmd.TelegramID,
+			Timestamp:  time.Now(),
+		}
+		if err := h.outbox.AddEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+
+	response := DeleteResponse{
+		ChatID:  cmd.ChatID,
+		Success: success,
+	}
+
+	applied, err := h.commitCommand(ctx, tx, cmd, models.MessageTypeWishlistDeleted, response)
+	if !applied {
+		return err
+	}
+
+	if success {
+		h.repo.InvalidateUserCache(cmd.TelegramID)
+		log.Printf("Wishlist item deleted: %d for user %d", cmd.WishlistID, cmd.TelegramID)
+	}
+
+	// A failed response send is retried, replaying the response
+	return err
+}
+
+// handlePriceHistory sends the price history of a wishlist item with a chart
+func (h *CommandHandler) handlePriceHistory(ctx context.Context, cmd *consumer.Command) error {
+	response := HistoryResponse{
+		ChatID:  cmd.ChatID,
+		History: &PriceHistorySummary{WishlistID: cmd.WishlistID},
+	}
+
+	wishlist, err := h.repo.GetWishlistByID(cmd.WishlistID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && wishlist.TelegramID != cmd.TelegramID) {
+		return h.sendResponse(ctx, models.MessageTypePriceHistory, cmd, response)
+	}
+	if err != nil {
+		log.Printf("Error getting wishlist item: %v", err)
+		return err
+	}
+
+	summary, err := h.priceHistory.ForWishlist(wishlist, time.Now())
+	if err != nil {
+		log.Printf("Error getting price history: %v", err)
+		return err
+	}
+
+	response.History.Found = true
+	response.History.ProductName = wishlist.ProductName
+	response.History.Periods = summary.Periods
+	response.History.CurrentPrice = summary.CurrentPrice
+	if !summary.CurrentDay.IsZero() {
+		response.History.CurrentDay = &summary.CurrentDay
+	}
+
+	chart, err := history.RenderChart(wishlist.ProductName, summary.Prices)
+	if err != nil && !errors.Is(err, history.ErrNotEnoughData) {
+		log.Printf("Failed to render price chart for wishlist %d: %v", wishlist.ID, err)
+	}
+	response.History.Chart = chart
+
+	return h.sendResponse(ctx, models.MessageTypePriceHistory, cmd, response)
+}
+
+// handleSetHideSuspicious sets whether the user receives offers with a
+// suspicious reference price
+func (h *CommandHandler) handleSetHideSuspicious(ctx context.Context, cmd *consumer.Command) error {
+	if cmd.HideSuspicious == nil {
+		return nil
+	}
+
+	tx, err := h.repo.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := h.users.SetHideSuspiciousOffers(tx, cmd.TelegramID, *cmd.HideSuspicious); err != nil {
+		log.Printf("Error updating user preferences: %v", err)
+		return err
+	}
+
+	var responseType string
+	var response interface{}
+	if cmd.CorrelationID != "" {
+		responseType = models.MessageTypePreferences
+		response = PreferencesResponse{
+			ChatID:         cmd.ChatID,
+			HideSuspicious: *cmd.HideSuspicious,
+		}
+	}
+
+	applied, err := h.commitCommand(ctx, tx, cmd, responseType, response)
+	if !applied {
+		return err
+	}
+
+	log.Printf("User %d hide suspicious offers: %t", cmd.TelegramID, *cmd.HideSuspicious)
+	// A failed response send is retried, replaying the response
+	return err
+}
+
+// commitCommand records the command with its response in the transaction of
+// its changes, commits it and sends the response. It returns false when the
+// command was not applied: on errors, or when another consumer applied it
+// first, in which case the original response is sent instead. When only the
+// response send fails it returns true and the error, for the caller to finish
+// applying the command before returning it.
+func (h *CommandHandler) commitCommand(ctx context.Context, tx *sql.Tx, cmd *consumer.Command, responseType string, response interface{}) (bool, error) {
+	var data []byte
+	if responseType != "" {
+		var err error
+		data, err = models.NewEnvelope(responseType, cmd.CorrelationID, response)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal response: %w", err)
+		}
+	}
+
+	if cmd.CommandID != "" {
+		err := h.commandLog.RecordCommand(tx, &models.ProcessedCommand{
+			CommandID:   cmd.CommandID,
+			Type:        cmd.Type,
+			TelegramID:  cmd.TelegramID,
+			Response:    data,
+			ProcessedAt: time.Now(),
+		})
+		if errors.Is(err, repository.ErrCommandProcessed) {
+			tx.Rollback()
+			processed, err := h.commandLog.GetProcessedCommand(cmd.CommandID)
+			if err != nil {
+				return false, fmt.Errorf("failed to get command %s: %w", cmd.CommandID, err)
+			}
+			return false, h.replayResponse(ctx, cmd, processed)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit command: %w", err)
+	}
+
+	// A failed send is retried as a duplicate, which replays the response
+	if data != nil {
+		if err := h.writeResponse(ctx, cmd, data); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// rejectCommand answers a command the database rejected with an error
+// response. The error is returned as permanent, so the command goes to the
+// dead-letter topic instead of being retried.
+func (h *CommandHandler) rejectCommand(ctx context.Context, cmd *consumer.Command, reason string, err error) error {
+	response := CommandFailedResponse{
+		ChatID:  cmd.ChatID,
+		Command: cmd.Type,
+		Reason:  reason,
+	}
+	if err := h.sendResponse(ctx, models.MessageTypeCommandFailed, cmd, response); err != nil {
+		return err
+	}
+
+	return bus.Permanent(fmt.Errorf("command %s rejected: %w", cmd.Type, err))
+}
+
+// rejectedReason returns why the database rejected a command for good: a
+// violated constraint or an invalid value, which retrying cannot fix. It
+// returns an empty reason for any other error.
+func rejectedReason(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+
+	switch {
+	case pqErr.Code == "23503":
+		// The only foreign key of wishlists is their user
+		return FailureUserNotRegistered
+	case pqErr.Code.Class() == "23", pqErr.Code.Class() == "22":
+		return FailureInvalidValues
+	}
+
+	return ""
+}
+
+// replayResponse answers a command applied before with its original response
+func (h *CommandHandler) replayResponse(ctx context.Context, cmd *consumer.Command, processed *models.ProcessedCommand) error {
+	log.Printf("Skipping duplicate command %s (%s) for user %d", processed.CommandID, processed.Type, processed.TelegramID)
+
+	if len(processed.Response) == 0 {
+		return nil
+	}
+
+	return h.writeResponse(ctx, cmd, processed.Response)
+}
+
+// sendResponse sends a response to a command back to the frontend via Kafka,
+// wrapped in an envelope of the given message type
+func (h *CommandHandler) sendResponse(ctx context.Context, messageType string, cmd *consumer.Command, response interface{}) error {
+	data, err := models.NewEnvelope(messageType, cmd.CorrelationID, response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	return h.writeResponse(ctx, cmd, data)
+}
+
+// writeResponse writes an enveloped response to the bot-responses topic
+func (h *CommandHandler) writeResponse(ctx context.Context, cmd *consumer.Command, data []byte) error {
+	// Key by user so the responses to the same user keep their order
+	msg := &bus.Message{
+		Topic: h.responseTopic,
+		Key:   []byte(fmt.Sprintf("%d", cmd.TelegramID)),
+		Value: data,
+	}
+
+	_, span := tracer.StartProducerSpan(ctx, msg)
+	err := h.responseWriter.Publish(ctx, msg)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to send response: %w", err)
+	}
+
+	return nil
+}
+
+// WishlistItem represents a wishlist item in the response
+type WishlistItem struct {
+	ID                 int      `json:"id"`
+	ProductName        string   `json:"product_name"`
+	TargetPrice        *float64 `json:"target_price,omitempty"`
+	DiscountPercentage *int     `json:"discount_percentage,omitempty"`
+	MinPrice           *float64 `json:"min_price,omitempty"`
+	ExcludedTerms      []string `json:"excluded_terms,omitempty"`
+	MinCashback        *int     `json:"min_cashback,omitempty"`
+	MaxEffectivePrice  *float64 `json:"max_effective_price,omitempty"`
+}
+
+// newWishlistItem converts a wishlist item to the response format
+func newWishlistItem(w *models.Wishlist) WishlistItem {
+	return WishlistItem{
+		ID:                 w.ID,
+		ProductName:        w.ProductName,
+		TargetPrice:        w.TargetPrice,
+		DiscountPercentage: w.DiscountPercentage,
+		MinPrice:           w.MinPrice,
+		ExcludedTerms:      w.ExcludedTerms,
+		MinCashback:        w.MinCashback,
+		MaxEffectivePrice:  w.MaxEffectivePrice,
+	}
+}
+
+// WishlistResponse represents the response to a list command
+type WishlistResponse struct {
+	ChatID int64          `json:"chat_id"`
+	Items  []WishlistItem `json:"items"`
+}
+
+// WishlistAddedResponse confirms an add command with the saved item
+type WishlistAddedResponse struct {
+	ChatID int64        `json:"chat_id"`
+	Item   WishlistItem `json:"item"`
+}
+
+// WishlistUpdatedResponse confirms an update command with the saved item,
+// nil when the item was not found
+type WishlistUpdatedResponse struct {
+	ChatID  int64         `json:"chat_id"`
+	Success bool          `json:"success"`
+	Item    *WishlistItem `json:"item,omitempty"`
+}
+
+// Reasons of a command failed response
+const (
+	FailureUserNotRegistered = "user_not_registered"
+	FailureInvalidValues     = "invalid_values"
+)
+
+// CommandFailedResponse answers a command the database rejected
+type CommandFailedResponse struct {
+	ChatID  int64  `json:"chat_id"`
+	Command string `json:"command"`
+	Reason  string `json:"reason"`
+}
+
+// DeleteResponse represents the response to a delete command
+type DeleteResponse struct {
+	ChatID  int64 `json:"chat_id"`
+	Success bool  `json:"success"`
+}
+
+// PreferencesResponse confirms a preferences command
+type PreferencesResponse struct {
+	ChatID         int64 `json:"chat_id"`
+	HideSuspicious bool  `json:"hide_suspicious"`
+}
+
+// BacktestSummary is how often a wishlist item would have been notified in the past
+type BacktestSummary struct {
+	WishlistID    int     `json:"wishlist_id"`
+	Days          int     `json:"days"`
+	OffersScanned int     `json:"offers_scanned"`
+	HitCount      int     `json:"hit_count"`
+	DaysWithHits  int     `json:"days_with_hits"`
+	LowestPrice   float64 `json:"lowest_price,omitempty"`
+}
+
+// BacktestResponse represents the backtest summary sent after an add command
+type BacktestResponse struct {
+	ChatID   int64            `json:"chat_id"`
+	Backtest *BacktestSummary `json:"backtest"`
+}
+
+// PriceHistorySummary is the price history of a wishlist item. Chart is a PNG
+// line chart, empty when there are too few days to draw it.
+type PriceHistorySummary struct {
+	WishlistID   int              `json:"wishlist_id"`
+	Found        bool             `json:"found"`
+	ProductName  string           `json:"product_name,omitempty"`
+	Periods      []history.Period `json:"periods,omitempty"`
+	CurrentPrice float64          `json:"current_price,omitempty"`
+	CurrentDay   *time.Time       `json:"current_day,omitempty"`
+	Chart        []byte           `json:"chart,omitempty"`
+}
+
+// HistoryResponse represents the response to a price history command
+type HistoryResponse struct {
+	ChatID  int64                `json:"chat_id"`
+	History *PriceHistorySummary `json:"history"`
+}
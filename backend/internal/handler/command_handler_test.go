@@ -0,0 +1,472 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/backtest"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/history"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/lib/pq"
+)
+
+// downPublisher fails every publish until it is brought up
+type downPublisher struct {
+	*bus.Memory
+	up bool
+}
+
+func (p *downPublisher) Publish(ctx context.Context, msgs ...*bus.Message) error {
+	if !p.up {
+		return errors.New("broker unavailable")
+	}
+	return p.Memory.Publish(ctx, msgs...)
+}
+
+type handlerTest struct {
+	handler   *CommandHandler
+	mock      sqlmock.Sqlmock
+	redis     *miniredis.Miniredis
+	publisher *downPublisher
+	groups    int
+}
+
+// newHandlerTest wires a CommandHandler to a mocked database, an in-memory
+// Redis and the in-memory bus. Queries the test does not expect fail; the
+// handler only logs some of them, so logged failures fail the test.
+func newHandlerTest(t *testing.T) *handlerTest {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		if strings.Contains(logs.String(), "Failed") {
+			t.Errorf("handler logged failures:\n%s", logs.String())
+		}
+	})
+
+	offerMatcher := matcher.NewOfferMatcher(0.5)
+	publisher := &downPublisher{Memory: bus.NewMemory(), up: true}
+	handler := NewCommandHandler(db, redisClient, publisher, "bot-responses",
+		backtest.NewBacktester(repository.NewOfferRepository(db), offerMatcher, 24*time.Hour), 30*24*time.Hour,
+		history.NewPriceHistory(repository.NewPriceHistoryRepository(db), offerMatcher, time.UTC))
+
+	return &handlerTest{handler: handler, mock: mock, redis: redisServer, publisher: publisher}
+}
+
+func (h *handlerTest) handle(t *testing.T, cmd map[string]interface{}) error {
+	t.Helper()
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.handler.HandleCommand(context.Background(), data)
+	h.handler.Wait()
+	return err
+}
+
+// responses returns the envelopes published to the bot-responses topic
+func (h *handlerTest) responses(t *testing.T) []models.Envelope {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A new group reads the topic from its oldest message
+	h.groups++
+	var envelopes []models.Envelope
+	_, err := h.publisher.Subscribe(ctx, bus.Subscription{Topic: "bot-responses", Group: fmt.Sprintf("test-%d", h.groups)},
+		func(ctx context.Context, msg *bus.Message) error {
+			var envelope models.Envelope
+			if err := json.Unmarshal(msg.Value, &envelope); err != nil {
+				t.Errorf("invalid response %s: %v", msg.Value, err)
+			}
+			envelopes = append(envelopes, envelope)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !h.publisher.Drained() {
+		if time.Now().After(deadline) {
+			t.Fatal("responses not consumed in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	return envelopes
+}
+
+var processedColumns = []string{"command_id", "command_type", "telegram_id", "response", "processed_at"}
+
+func (h *handlerTest) expectNotProcessed(commandID string) {
+	h.mock.ExpectQuery("FROM processed_commands").WithArgs(commandID).WillReturnError(sql.ErrNoRows)
+}
+
+// expectAdded expects a wishlist item of user 10 to be saved as id 5 with its
+// outbox event and command record
+func (h *handlerTest) expectAdded(commandID string) {
+	h.expectNotProcessed(commandID)
+	h.mock.ExpectBegin()
+	h.mock.ExpectQuery("INSERT INTO wishlists").
+		WithArgs(int64(10), "geladeira", 3000.0, nil, nil, nil, nil, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	h.mock.ExpectExec("INSERT INTO wishlist_outbox").
+		WithArgs(models.WishlistEventAdded, 5, int64(10), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	h.mock.ExpectExec("INSERT INTO processed_commands").
+		WithArgs(commandID, "add_wishlist", int64(10), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	h.mock.ExpectCommit()
+}
+
+func addCommand(commandID string, chatID int64) map[string]interface{} {
+	return map[string]interface{}{
+		"type":           "add_wishlist",
+		"command_id":     commandID,
+		"correlation_id": "corr-" + commandID,
+		"telegram_id":    10,
+		"chat_id":        chatID,
+		"product_name":   "geladeira",
+		"target_price":   3000.0,
+	}
+}
+
+func (h *handlerTest) expectationsMet(t *testing.T) {
+	t.Helper()
+	if err := h.mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddWishlistRespondsAndSendsBacktestSummary(t *testing.T) {
+	h := newHandlerTest(t)
+	h.redis.Set("wishlist:10", "[]")
+	h.expectAdded("cmd-1")
+
+	h.mock.ExpectQuery("SELECT o.id, o.product_name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_name"}).
+			AddRow(1, "Geladeira Consul 300L").
+			AddRow(2, "Air Fryer Mondial"))
+	h.mock.ExpectQuery("FROM offer_price_events").
+		WithArgs("{1}", sqlmock.AnyArg(), sqlmock.AnyArg(), backtest.MaxEvents).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "offer_key", "external_id", "seller", "product_name", "price", "original_price",
+			"discount_percentage", "cashback_percentage", "url", "image_url", "source", "recorded_at",
+		}).AddRow(1, "promobit:1", "", "", "Geladeira Consul 300L", 2800.0, 0.0, 0, 0, "", "", "promobit", time.Now().Add(-48*time.Hour)))
+
+	if err := h.handle(t, addCommand("cmd-1", 99)); err != nil {
+		t.Fatalf("HandleCommand() error = %v", err)
+	}
+	h.expectationsMet(t)
+
+	if h.redis.Exists("wishlist:10") {
+		t.Error("the user's wishlist cache was not invalidated")
+	}
+
+	responses := h.responses(t)
+	if len(responses) != 2 {
+		t.Fatalf("got %d responses, want the added item and the backtest summary", len(responses))
+	}
+
+	var added WishlistAddedResponse
+	if err := json.Unmarshal(responses[0].Payload, &added); err != nil {
+		t.Fatal(err)
+	}
+	if responses[0].Type != models.MessageTypeWishlistAdded || responses[0].CorrelationID != "corr-cmd-1" || added.ChatID != 99 || added.Item.ID != 5 {
+		t.Errorf("first response = %s %s %s, want the added item 5 for chat 99", responses[0].Type, responses[0].CorrelationID, responses[0].Payload)
+	}
+
+	var summary BacktestResponse
+	if err := json.Unmarshal(responses[1].Payload, &summary); err != nil {
+		t.Fatal(err)
+	}
+	want := BacktestSummary{WishlistID: 5, Days: 30, OffersScanned: 2, HitCount: 1, DaysWithHits: 1, LowestPrice: 2800}
+	if responses[1].Type != models.MessageTypeBacktestSummary || summary.ChatID != 99 || *summary.Backtest != want {
+		t.Errorf("second response = %s %+v, want the backtest summary %+v", responses[1].Type, summary.Backtest, want)
+	}
+}
+
+func TestAddWishlistWithoutChatSkipsBacktest(t *testing.T) {
+	h := newHandlerTest(t)
+	h.expectAdded("cmd-1")
+
+	if err := h.handle(t, addCommand("cmd-1", 0)); err != nil {
+		t.Fatalf("HandleCommand() error = %v", err)
+	}
+	h.expectationsMet(t)
+
+	if responses := h.responses(t); len(responses) != 1 || responses[0].Type != models.MessageTypeWishlistAdded {
+		t.Errorf("got responses %+v, want only the added item", responses)
+	}
+}
+
+func TestBacktestSkippedWhileTooManyRun(t *testing.T) {
+	h := newHandlerTest(t)
+	for i := 0; i < maxConcurrentBacktests; i++ {
+		h.handler.backtests <- struct{}{}
+	}
+	h.expectAdded("cmd-1")
+
+	// No offer queries are expected
+	if err := h.handle(t, addCommand("cmd-1", 99)); err != nil {
+		t.Fatalf("HandleCommand() error = %v", err)
+	}
+	h.expectationsMet(t)
+
+	if responses := h.responses(t); len(responses) != 1 {
+		t.Errorf("got %d responses, want only the added item", len(responses))
+	}
+}
+
+func TestFailedResponseIsReplayedOnRedelivery(t *testing.T) {
+	h := newHandlerTest(t)
+	h.publisher.up = false
+	h.expectAdded("cmd-1")
+
+	err := h.handle(t, addCommand("cmd-1", 0))
+	if err == nil || !strings.Contains(err.Error(), "failed to send response") {
+		t.Fatalf("HandleCommand() error = %v, want the failed send", err)
+	}
+	h.expectationsMet(t)
+
+	// The item is saved, so the redelivered command is not applied again
+	response, _ := models.NewEnvelope(models.MessageTypeWishlistAdded, "corr-cmd-1", WishlistAddedResponse{Item: WishlistItem{ID: 5}})
+	h.mock.ExpectQuery("FROM processed_commands").WithArgs("cmd-1").
+		WillReturnRows(sqlmock.NewRows(processedColumns).AddRow("cmd-1", "add_wishlist", 10, response, time.Now()))
+
+	h.publisher.up = true
+	if err := h.handle(t, addCommand("cmd-1", 0)); err != nil {
+		t.Fatalf("HandleCommand() redelivery error = %v", err)
+	}
+	h.expectationsMet(t)
+
+	responses := h.responses(t)
+	if len(responses) != 1 || responses[0].Type != models.MessageTypeWishlistAdded || responses[0].CorrelationID != "corr-cmd-1" {
+		t.Errorf("got responses %+v, want the original response replayed", responses)
+	}
+}
+
+func TestCommandRecordedByAnotherConsumerIsNotApplied(t *testing.T) {
+	h := newHandlerTest(t)
+	h.expectNotProcessed("cmd-1")
+	h.mock.ExpectBegin()
+	h.mock.ExpectExec("DELETE FROM wishlists").WithArgs(5, int64(10)).WillReturnResult(sqlmock.NewResult(0, 1))
+	h.mock.ExpectExec("INSERT INTO wishlist_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+	h.mock.ExpectExec("INSERT INTO processed_commands").WillReturnError(&pq.Error{Code: "23505"})
+	h.mock.ExpectRollback()
+
+	response, _ := models.NewEnvelope(models.MessageTypeWishlistDeleted, "", DeleteResponse{ChatID: 99, Success: true})
+	h.mock.ExpectQuery("FROM processed_commands").WithArgs("cmd-1").
+		WillReturnRows(sqlmock.NewRows(processedColumns).AddRow("cmd-1", "delete_wishlist", 10, response, time.Now()))
+
+	err := h.handle(t, map[string]interface{}{"type": "delete_wishlist", "command_id": "cmd-1", "telegram_id": 10, "chat_id": 99, "wishlist_id": 5})
+	if err != nil {
+		t.Fatalf("HandleCommand() error = %v", err)
+	}
+	h.expectationsMet(t)
+
+	if responses := h.responses(t); len(responses) != 1 || string(responses[0].Payload) != `{"chat_id":99,"success":true}` {
+		t.Errorf("got responses %+v, want the response of the other consumer", responses)
+	}
+}
+
+func TestDeleteWishlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted int64
+	}{
+		{"own item", 1},
+		{"missing or someone else's item", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandlerTest(t)
+			h.redis.Set("wishlist:10", "[]")
+			h.expectNotProcessed("cmd-1")
+			h.mock.ExpectBegin()
+			h.mock.ExpectExec("DELETE FROM wishlists").WithArgs(5, int64(10)).WillReturnResult(sqlmock.NewResult(0, tt.deleted))
+			if tt.deleted > 0 {
+				h.mock.ExpectExec("INSERT INTO wishlist_outbox").
+					WithArgs(models.WishlistEventDeleted, 5, int64(10), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			h.mock.ExpectExec("INSERT INTO processed_commands").WillReturnResult(sqlmock.NewResult(0, 1))
+			h.mock.ExpectCommit()
+
+			err := h.handle(t, map[string]interface{}{"type": "delete_wishlist", "command_id": "cmd-1", "telegram_id": 10, "chat_id": 99, "wishlist_id": 5})
+			if err != nil {
+				t.Fatalf("HandleCommand() error = %v", err)
+			}
+			h.expectationsMet(t)
+
+			success := tt.deleted > 0
+			if h.redis.Exists("wishlist:10") == success {
+				t.Errorf("cache kept = %t, want it invalidated only when the item was deleted", !success)
+			}
+			want := fmt.Sprintf(`{"chat_id":99,"success":%t}`, success)
+			if responses := h.responses(t); len(responses) != 1 || string(responses[0].Payload) != want {
+				t.Errorf("got responses %+v, want %s", responses, want)
+			}
+		})
+	}
+}
+
+func TestUpdateWishlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		updated bool
+	}{
+		{"own item", true},
+		{"missing or someone else's item", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandlerTest(t)
+			h.redis.Set("wishlist:10", "[]")
+			h.expectNotProcessed("cmd-1")
+			h.mock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{"created_at"})
+			if tt.updated {
+				rows.AddRow(time.Now())
+			}
+			h.mock.ExpectQuery("UPDATE wishlists").
+				WithArgs(5, int64(10), "geladeira inverter", 2800.0, nil, nil, `{"frost"}`, nil, nil).
+				WillReturnRows(rows)
+			if tt.updated {
+				h.mock.ExpectExec("INSERT INTO wishlist_outbox").
+					WithArgs(models.WishlistEventUpdated, 5, int64(10), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			h.mock.ExpectExec("INSERT INTO processed_commands").
+				WithArgs("cmd-1", "update_wishlist", int64(10), sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			h.mock.ExpectCommit()
+
+			err := h.handle(t, map[string]interface{}{
+				"type": "update_wishlist", "command_id": "cmd-1", "correlation_id": "corr-cmd-1", "telegram_id": 10, "chat_id": 99,
+				"wishlist_id": 5, "product_name": "geladeira inverter", "target_price": 2800.0, "excluded_terms": []string{"frost"},
+			})
+			if err != nil {
+				t.Fatalf("HandleCommand() error = %v", err)
+			}
+			h.expectationsMet(t)
+
+			if h.redis.Exists("wishlist:10") == tt.updated {
+				t.Errorf("cache kept = %t, want it invalidated only when the item was updated", !tt.updated)
+			}
+
+			responses := h.responses(t)
+			if len(responses) != 1 || responses[0].Type != models.MessageTypeWishlistUpdated || responses[0].CorrelationID != "corr-cmd-1" {
+				t.Fatalf("got responses %+v, want the update confirmation", responses)
+			}
+			var response WishlistUpdatedResponse
+			if err := json.Unmarshal(responses[0].Payload, &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.ChatID != 99 || response.Success != tt.updated || (response.Item != nil) != tt.updated {
+				t.Errorf("response = %s, want success %t", responses[0].Payload, tt.updated)
+			}
+			if tt.updated && (response.Item.ID != 5 || response.Item.ProductName != "geladeira inverter" || *response.Item.TargetPrice != 2800) {
+				t.Errorf("updated item = %+v, want item 5 with the new product and price", response.Item)
+			}
+		})
+	}
+}
+
+func TestInvalidCommandIsPermanent(t *testing.T) {
+	h := newHandlerTest(t)
+
+	err := h.handler.HandleCommand(context.Background(), []byte("{not json"))
+	if !bus.IsPermanent(err) {
+		t.Errorf("HandleCommand() error = %v, want a permanent error", err)
+	}
+}
+
+func TestRejectedCommandIsPermanent(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		err        error
+		wantReason string
+	}{
+		{"unregistered user", "add_wishlist", &pq.Error{Code: "23503"}, FailureUserNotRegistered},
+		{"check violation", "add_wishlist", &pq.Error{Code: "23514"}, FailureInvalidValues},
+		{"value out of range", "update_wishlist", &pq.Error{Code: "22003"}, FailureInvalidValues},
+		{"database down", "add_wishlist", errors.New("connection refused"), ""},
+		{"serialization failure", "update_wishlist", &pq.Error{Code: "40001"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHandlerTest(t)
+			h.expectNotProcessed("cmd-1")
+			h.mock.ExpectBegin()
+			query := "INSERT INTO wishlists"
+			if tt.command == "update_wishlist" {
+				query = "UPDATE wishlists"
+			}
+			h.mock.ExpectQuery(query).WillReturnError(tt.err)
+			h.mock.ExpectRollback()
+
+			cmd := addCommand("cmd-1", 99)
+			cmd["type"] = tt.command
+			cmd["wishlist_id"] = 5
+			err := h.handle(t, cmd)
+			h.expectationsMet(t)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("HandleCommand() error = %v, want %v", err, tt.err)
+			}
+			if bus.IsPermanent(err) != (tt.wantReason != "") {
+				t.Errorf("HandleCommand() permanent = %t, want %t", bus.IsPermanent(err), tt.wantReason != "")
+			}
+
+			responses := h.responses(t)
+			if tt.wantReason == "" {
+				if len(responses) != 0 {
+					t.Errorf("got responses %+v, want none for a retried command", responses)
+				}
+				return
+			}
+			if len(responses) != 1 || responses[0].Type != models.MessageTypeCommandFailed || responses[0].CorrelationID != "corr-cmd-1" {
+				t.Fatalf("got responses %+v, want the failure", responses)
+			}
+			var response CommandFailedResponse
+			if err := json.Unmarshal(responses[0].Payload, &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.ChatID != 99 || response.Command != tt.command || response.Reason != tt.wantReason {
+				t.Errorf("response = %s, want reason %s for chat 99", responses[0].Payload, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/normalizer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+// ExplainRequest selects the offer and wishlist to explain. The offer is either
+// a stored offer id or a raw offer in the same format published to Kafka.
+type ExplainRequest struct {
+	OfferID    int             `json:"offer_id,omitempty"`
+	Offer      json.RawMessage `json:"offer,omitempty"`
+	WishlistID int             `json:"wishlist_id"`
+}
+
+// ExplainResponse is the matcher decision for the offer and wishlist
+type ExplainResponse struct {
+	Offer         models.Offer        `json:"offer"`
+	Wishlist      models.Wishlist     `json:"wishlist"`
+	Normalization []string            `json:"normalization,omitempty"`
+	Explanation   matcher.Explanation `json:"explanation"`
+}
+
+// ExplainHandler explains why an offer did or did not match a wishlist, using
+// the same normalizer and matcher as the offers consumer
+type ExplainHandler struct {
+	repo       *repository.WishlistRepository
+	offerRepo  *repository.OfferRepository
+	matcher    *matcher.OfferMatcher
+	normalizer *normalizer.OfferNormalizer
+}
+
+func NewExplainHandler(repo *repository.WishlistRepository, offerRepo *repository.OfferRepository, offerMatcher *matcher.OfferMatcher, offerNormalizer *normalizer.OfferNormalizer) *ExplainHandler {
+	return &ExplainHandler{
+		repo:       repo,
+		offerRepo:  offerRepo,
+		matcher:    offerMatcher,
+		normalizer: offerNormalizer,
+	}
+}
+
+// ServeHTTP handles POST /explain
+func (h *ExplainHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ExplainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.WishlistID <= 0 {
+		http.Error(w, "wishlist_id is required", http.StatusBadRequest)
+		return
+	}
+
+	offer, stored, status, err := h.loadOffer(&req)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	wishlist, err := h.repo.GetWishlistByID(req.WishlistID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Wishlist not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to explain match: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ExplainResponse{Wishlist: *wishlist}
+
+	// Offers are normalized before matching, so pasted offers must be too.
+	// Stored offers were normalized when they were received.
+	if !stored {
+		changes, err := h.normalizer.Normalize(offer)
+		for _, change := range changes {
+			resp.Normalization = append(resp.Normalization, change.String())
+		}
+		if err != nil {
+			http.Error(w, "Offer rejected by the normalizer: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	resp.Offer = *offer
+	resp.Explanation = h.matcher.Explain(offer, wishlist)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// loadOffer returns the offer of the request, whether it was loaded from the
+// database and the HTTP status to use on error
+func (h *ExplainHandler) loadOffer(req *ExplainRequest) (*models.Offer, bool, int, error) {
+	if len(req.Offer) > 0 && string(req.Offer) != "null" {
+		var offer models.Offer
+		if err := json.Unmarshal(req.Offer, &offer); err != nil {
+			return nil, false, http.StatusBadRequest, errors.New("Invalid offer JSON")
+		}
+		return &offer, false, 0, nil
+	}
+
+	if req.OfferID <= 0 {
+		return nil, false, http.StatusBadRequest, errors.New("offer_id or offer is required")
+	}
+
+	offer, err := h.offerRepo.GetOfferByID(req.OfferID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, http.StatusNotFound, errors.New("Offer not found")
+	}
+	if err != nil {
+		log.Printf("Failed to explain match: %v", err)
+		return nil, false, http.StatusInternalServerError, err
+	}
+
+	return offer, true, 0, nil
+}
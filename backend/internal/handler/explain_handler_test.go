@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/normalizer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+var wishlistColumns = []string{
+	"id", "telegram_id", "product_name", "target_price", "discount_percentage", "min_price", "excluded_terms",
+	"min_cashback", "max_effective_price", "created_at",
+}
+
+var offerColumns = []string{
+	"id", "offer_key", "external_id", "seller", "product_name", "price", "original_price",
+	"discount_percentage", "cashback_percentage", "url", "image_url", "source", "received_at",
+}
+
+func newExplainTest(t *testing.T) (*ExplainHandler, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	return NewExplainHandler(repository.NewWishlistRepository(db, redisClient), repository.NewOfferRepository(db),
+		matcher.NewOfferMatcher(0.5), normalizer.NewOfferNormalizer()), mock
+}
+
+// expectWishlist expects wishlist 3: "geladeira consul" up to R$ 3000, from
+// R$ 1000 and without used ones
+func expectWishlist(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM wishlists WHERE id = \\$1").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(wishlistColumns).
+			AddRow(3, 10, "geladeira consul", 3000.0, nil, 1000.0, `{"usada"}`, nil, nil, time.Now()))
+}
+
+func explain(t *testing.T, h *ExplainHandler, body string) (int, ExplainResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/explain", strings.NewReader(body)))
+
+	var resp ExplainResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code, resp
+}
+
+// step is the outcome of one rule of an explanation
+type step struct {
+	rule   string
+	passed bool
+}
+
+func steps(explanation matcher.Explanation) []step {
+	var steps []step
+	for _, rule := range explanation.Rules {
+		steps = append(steps, step{rule.Rule, rule.Passed})
+	}
+	return steps
+}
+
+func TestExplainStoredOfferThatMatches(t *testing.T) {
+	h, mock := newExplainTest(t)
+	mock.ExpectQuery("FROM offers WHERE id = \\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(offerColumns).
+			AddRow(7, "kabum:123", "123", "", "Geladeira Consul Frost Free 400L", 2800.0, 3500.0, 20, 0, "", "", "kabum", time.Now()))
+	expectWishlist(mock)
+
+	status, resp := explain(t, h, `{"offer_id": 7, "wishlist_id": 3}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	explanation := resp.Explanation
+	if !explanation.Name.Matched || explanation.Name.MatchedCount != 2 {
+		t.Errorf("name = %+v, want both wishlist words matched", explanation.Name)
+	}
+	want := []step{{matcher.RuleExcludedTerm, true}, {matcher.RuleMinPrice, true}, {models.MatchTypePrice, true}}
+	if got := steps(explanation); !equalSteps(got, want) {
+		t.Errorf("steps = %+v, want %+v", got, want)
+	}
+	if price := explanation.Rules[2]; price.Expected != 3000 || price.Actual != 2800 || !price.Condition {
+		t.Errorf("price step = %+v, want the condition R$ 3000 against R$ 2800", price)
+	}
+	if !explanation.Matched || explanation.MatchType != models.MatchTypePrice {
+		t.Errorf("explanation matched = %v (%q), want a price match", explanation.Matched, explanation.MatchType)
+	}
+	// Stored offers were normalized when they were received
+	if len(resp.Normalization) != 0 {
+		t.Errorf("normalization = %v, want none for a stored offer", resp.Normalization)
+	}
+}
+
+func TestExplainPastedOfferThatIsRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		offer     string
+		wantName  bool
+		wantSteps []step
+	}{
+		{"above the target price",
+			`{"id": 123, "titulo": "Geladeira Consul 400L", "price": 3200, "oldPrice": 4000}`,
+			true, []step{{matcher.RuleExcludedTerm, true}, {matcher.RuleMinPrice, true}, {models.MatchTypePrice, false}}},
+		{"excluded term",
+			`{"id": 123, "titulo": "Geladeira Consul 400L usada", "price": 1500, "oldPrice": 4000}`,
+			true, []step{{matcher.RuleExcludedTerm, false}, {matcher.RuleMinPrice, true}, {models.MatchTypePrice, true}}},
+		{"below the minimum price",
+			`{"id": 123, "titulo": "Prateleira Geladeira Consul", "price": 80, "oldPrice": 100}`,
+			true, []step{{matcher.RuleExcludedTerm, true}, {matcher.RuleMinPrice, false}, {models.MatchTypePrice, true}}},
+		{"other product",
+			`{"id": 123, "titulo": "Fogão Brastemp 5 bocas", "price": 1500, "oldPrice": 2000}`,
+			false, []step{{matcher.RuleExcludedTerm, true}, {matcher.RuleMinPrice, true}, {models.MatchTypePrice, true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, mock := newExplainTest(t)
+			expectWishlist(mock)
+
+			status, resp := explain(t, h, `{"offer": `+tt.offer+`, "wishlist_id": 3}`)
+			if status != http.StatusOK {
+				t.Fatalf("status = %d, want %d", status, http.StatusOK)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+
+			explanation := resp.Explanation
+			if explanation.Name.Matched != tt.wantName {
+				t.Errorf("name matched = %v, want %v (%+v)", explanation.Name.Matched, tt.wantName, explanation.Name)
+			}
+			if got := steps(explanation); !equalSteps(got, tt.wantSteps) {
+				t.Errorf("steps = %+v, want %+v", got, tt.wantSteps)
+			}
+			if explanation.Matched || explanation.MatchType != "" {
+				t.Errorf("explanation matched = %v (%q), want a rejection", explanation.Matched, explanation.MatchType)
+			}
+
+			// Pasted offers go through the normalizer like received ones
+			if len(resp.Normalization) == 0 || !strings.HasPrefix(resp.Normalization[0], "id: 123 -> 0") {
+				t.Errorf("normalization = %v, want the source id moved first", resp.Normalization)
+			}
+			if resp.Offer.ExternalID != "123" {
+				t.Errorf("offer external id = %q, want 123", resp.Offer.ExternalID)
+			}
+		})
+	}
+}
+
+func TestExplainErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		expect     func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{"without wishlist", `{"offer_id": 7}`, nil, http.StatusBadRequest},
+		{"without offer", `{"wishlist_id": 3}`, nil, http.StatusBadRequest},
+		{"offer not found", `{"offer_id": 7, "wishlist_id": 3}`, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery("FROM offers").WillReturnError(sql.ErrNoRows)
+		}, http.StatusNotFound},
+		{"wishlist not found", `{"offer": {"titulo": "Geladeira", "price": 2000}, "wishlist_id": 3}`, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery("FROM wishlists").WillReturnError(sql.ErrNoRows)
+		}, http.StatusNotFound},
+		{"offer rejected by the normalizer", `{"offer": {"titulo": " ", "price": 2000}, "wishlist_id": 3}`, expectWishlist, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, mock := newExplainTest(t)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			if status, _ := explain(t, h, tt.body); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func equalSteps(a, b []step) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// ErrNotEnoughData is returned when there are too few days to draw a line
+var ErrNotEnoughData = errors.New("at least two days of prices are needed")
+
+const (
+	chartWidth  = 800
+	chartHeight = 400
+	maxDayTicks = 8
+)
+
+// RenderChart draws the daily lowest and highest prices as a PNG line chart
+func RenderChart(title string, prices []models.DailyPrice) ([]byte, error) {
+	if len(prices) < 2 {
+		return nil, ErrNotEnoughData
+	}
+
+	days := make([]time.Time, len(prices))
+	lows := make([]float64, len(prices))
+	highs := make([]float64, len(prices))
+	minPrice, maxPrice := prices[0].MinPrice, prices[0].MaxPrice
+	for i, p := range prices {
+		days[i] = p.Day
+		lows[i] = p.MinPrice
+		highs[i] = p.MaxPrice
+		minPrice = min(minPrice, p.MinPrice)
+		maxPrice = max(maxPrice, p.MaxPrice)
+	}
+
+	// Keep some room around the lines, and a range when the price never changed
+	margin := (maxPrice - minPrice) * 0.1
+	if margin == 0 {
+		margin = maxPrice * 0.1
+	}
+
+	graph := chart.Chart{
+		Title:  title,
+		Width:  chartWidth,
+		Height: chartHeight,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 80, Left: 20, Right: 20, Bottom: 20},
+		},
+		XAxis: chart.XAxis{
+			Ticks: dayTicks(days),
+		},
+		YAxis: chart.YAxis{
+			Range: &chart.ContinuousRange{Min: max(minPrice-margin, 0), Max: maxPrice + margin},
+			ValueFormatter: func(v interface{}) string {
+				if price, ok := v.(float64); ok {
+					return fmt.Sprintf("R$ %.0f", price)
+				}
+				return ""
+			},
+		},
+		Series: []chart.Series{
+			chart.TimeSeries{
+				Name: "Maior preço",
+				Style: chart.Style{
+					StrokeColor:     drawing.ColorFromHex("bdbdbd"),
+					StrokeDashArray: []float64{5, 5},
+					StrokeWidth:     1.5,
+				},
+				XValues: days,
+				YValues: highs,
+			},
+			chart.TimeSeries{
+				Name: "Menor preço",
+				Style: chart.Style{
+					StrokeColor: drawing.ColorFromHex("e91e63"),
+					StrokeWidth: 2.5,
+					DotColor:    drawing.ColorFromHex("e91e63"),
+					DotWidth:    3,
+				},
+				XValues: days,
+				YValues: lows,
+			},
+		},
+	}
+	graph.Elements = []chart.Renderable{chart.LegendThin(&graph)}
+
+	var buf bytes.Buffer
+	if err := graph.Render(chart.PNG, &buf); err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// dayTicks labels at most maxDayTicks of the days, always including the last one
+func dayTicks(days []time.Time) []chart.Tick {
+	step := (len(days) + maxDayTicks - 1) / maxDayTicks
+
+	var ticks []chart.Tick
+	for i := 0; i < len(days)-1; i += step {
+		if len(days)-1-i < step/2 {
+			break // Too close to the last day
+		}
+		ticks = append(ticks, chart.Tick{Value: chart.TimeToFloat64(days[i]), Label: days[i].Format("02/01")})
+	}
+
+	last := days[len(days)-1]
+	return append(ticks, chart.Tick{Value: chart.TimeToFloat64(last), Label: last.Format("02/01")})
+}
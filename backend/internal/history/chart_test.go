@@ -0,0 +1,56 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"testing"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+)
+
+func TestRenderChart(t *testing.T) {
+	var ninetyDays []models.DailyPrice
+	for i := 0; i < 90; i++ {
+		ninetyDays = append(ninetyDays, models.DailyPrice{Day: date(7, 19).AddDate(0, 0, i), MinPrice: 2000 + float64(i%7)*50, MaxPrice: 2600})
+	}
+
+	tests := []struct {
+		name   string
+		prices []models.DailyPrice
+	}{
+		{"two days", []models.DailyPrice{
+			{Day: date(10, 15), MinPrice: 2400, MaxPrice: 2600},
+			{Day: date(10, 16), MinPrice: 2300, MaxPrice: 2500},
+		}},
+		{"price never changed", []models.DailyPrice{
+			{Day: date(10, 1), MinPrice: 2500, MaxPrice: 2500},
+			{Day: date(10, 16), MinPrice: 2500, MaxPrice: 2500},
+		}},
+		{"ninety days", ninetyDays},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := RenderChart("Geladeira Consul", tt.prices)
+			if err != nil {
+				t.Fatalf("RenderChart() error = %v", err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("RenderChart() is not a PNG: %v", err)
+			}
+			if size := img.Bounds().Size(); size.X != chartWidth || size.Y != chartHeight {
+				t.Errorf("chart is %dx%d, want %dx%d", size.X, size.Y, chartWidth, chartHeight)
+			}
+		})
+	}
+}
+
+func TestRenderChartNeedsTwoDays(t *testing.T) {
+	_, err := RenderChart("Geladeira Consul", []models.DailyPrice{{Day: date(10, 16), MinPrice: 2500, MaxPrice: 2500}})
+	if !errors.Is(err, ErrNotEnoughData) {
+		t.Errorf("RenderChart() error = %v, want ErrNotEnoughData", err)
+	}
+}
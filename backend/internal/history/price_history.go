@@ -0,0 +1,241 @@
+package history
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/similarity"
+)
+
+// Periods are the windows, in days, summarized by the price history
+var Periods = []int{7, 30, 90}
+
+const (
+	// ReferenceDays is the window of prices an original price is compared against
+	ReferenceDays = 90
+	// MinReferenceDays is how many days of prices are needed to judge an original price
+	MinReferenceDays = 3
+	// referenceTolerance is how much an original price may exceed the highest recorded price
+	referenceTolerance = 0.10
+)
+
+// Period summarizes the daily lowest prices of a product in a window
+type Period struct {
+	Days           int     `json:"days"`
+	MinPrice       float64 `json:"min_price"`
+	AvgPrice       float64 `json:"avg_price"`
+	DaysWithPrices int     `json:"days_with_prices"`
+}
+
+// Summary is the price history of a product. The current price is the lowest
+// price of the last day the product was seen.
+type Summary struct {
+	ProductKey   string              `json:"product_key"`
+	Prices       []models.DailyPrice `json:"prices"`
+	Periods      []Period            `json:"periods"`
+	CurrentPrice float64             `json:"current_price,omitempty"`
+	CurrentDay   time.Time           `json:"current_day,omitempty"`
+}
+
+// PriceHistory keeps the daily price range of every canonical offer and of
+// every wishlist term the offers match. Days are the dates in location, the
+// time zone of the users.
+type PriceHistory struct {
+	repo     *repository.PriceHistoryRepository
+	matcher  *matcher.OfferMatcher
+	location *time.Location
+}
+
+func NewPriceHistory(repo *repository.PriceHistoryRepository, offerMatcher *matcher.OfferMatcher, location *time.Location) *PriceHistory {
+	return &PriceHistory{
+		repo:     repo,
+		matcher:  offerMatcher,
+		location: location,
+	}
+}
+
+// Day returns the date of t in the location of the history. Like the days
+// read from the database, it is midnight UTC of that date.
+func (h *PriceHistory) Day(t time.Time) time.Time {
+	t = t.In(h.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// OfferKey returns the product key of a canonical offer
+func OfferKey(offer *models.Offer) string {
+	return "offer:" + offer.Key
+}
+
+// WishlistKey returns the product key of a wishlist term. Wishlists with the
+// same words, excluded terms and minimum price share their history.
+func WishlistKey(wishlist *models.Wishlist) string {
+	var key strings.Builder
+	key.WriteString("term:")
+	key.WriteString(strings.Join(sortedUnique(similarity.Tokens(wishlist.ProductName)), " "))
+
+	var excluded []string
+	for _, term := range wishlist.ExcludedTerms {
+		excluded = append(excluded, strings.Join(strings.Fields(similarity.Normalize(term)), " "))
+	}
+	for _, term := range sortedUnique(excluded) {
+		key.WriteString(" -")
+		key.WriteString(term)
+	}
+
+	if wishlist.MinPrice != nil {
+		key.WriteString(fmt.Sprintf(" >=%.2f", *wishlist.MinPrice))
+	}
+
+	return key.String()
+}
+
+// Record adds the offer price to the history of the offer and of the candidate
+// wishlists it is a product of
+func (h *PriceHistory) Record(offer *models.Offer, candidates []models.Wishlist) error {
+	// Cashback-only offers have no price to track
+	if offer.Price <= 0 {
+		return nil
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	if offer.Key != "" {
+		add(OfferKey(offer))
+	}
+	for i := range candidates {
+		if h.matcher.MatchesProduct(offer, &candidates[i]) {
+			add(WishlistKey(&candidates[i]))
+		}
+	}
+
+	receivedAt := offer.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	return h.repo.Record(keys, h.Day(receivedAt), offer.Price)
+}
+
+// ReferenceCheck compares the original price of an offer with the prices
+// recorded for a product before the day of the offer
+type ReferenceCheck struct {
+	ProductKey   string  `json:"product_key"`
+	Days         int     `json:"days"` // Days with prices before the offer
+	HighestPrice float64 `json:"highest_price"`
+	Suspicious   bool    `json:"suspicious"`
+}
+
+// Conclusive reports whether there were enough days of prices to judge
+func (c *ReferenceCheck) Conclusive() bool {
+	return c.Days >= MinReferenceDays
+}
+
+// CheckReference flags the original price of the offer as suspicious when it
+// is well above every price recorded for the product in the last
+// ReferenceDays, the "metade do dobro" of inflated Black Friday discounts.
+func (h *PriceHistory) CheckReference(offer *models.Offer, productKey string) (*ReferenceCheck, error) {
+	check := &ReferenceCheck{ProductKey: productKey}
+
+	// No discount claimed
+	if offer.OriginalPrice <= offer.Price {
+		return check, nil
+	}
+
+	receivedAt := offer.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+	offerDay := h.Day(receivedAt)
+
+	prices, err := h.repo.GetDailyPrices(productKey, offerDay.AddDate(0, 0, -ReferenceDays))
+	if err != nil {
+		return nil, err
+	}
+
+	// Prices of the offer day may already include the offer itself
+	for _, p := range prices {
+		if !p.Day.Before(offerDay) {
+			continue
+		}
+		check.HighestPrice = max(check.HighestPrice, p.MaxPrice)
+		check.Days++
+	}
+
+	check.Suspicious = check.Conclusive() && offer.OriginalPrice > check.HighestPrice*(1+referenceTolerance)
+	return check, nil
+}
+
+// ForWishlist summarizes the price history of a wishlist term up to now
+func (h *PriceHistory) ForWishlist(wishlist *models.Wishlist, now time.Time) (*Summary, error) {
+	key := WishlistKey(wishlist)
+	today := h.Day(now)
+	longest := Periods[len(Periods)-1]
+
+	prices, err := h.repo.GetDailyPrices(key, today.AddDate(0, 0, 1-longest))
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		ProductKey: key,
+		Prices:     prices,
+	}
+
+	for _, days := range Periods {
+		since := today.AddDate(0, 0, 1-days)
+		period := Period{Days: days}
+		var sum float64
+
+		for _, p := range prices {
+			if p.Day.Before(since) {
+				continue
+			}
+			if period.DaysWithPrices == 0 || p.MinPrice < period.MinPrice {
+				period.MinPrice = p.MinPrice
+			}
+			sum += p.MinPrice
+			period.DaysWithPrices++
+		}
+
+		if period.DaysWithPrices > 0 {
+			period.AvgPrice = sum / float64(period.DaysWithPrices)
+		}
+		summary.Periods = append(summary.Periods, period)
+	}
+
+	if len(prices) > 0 {
+		last := prices[len(prices)-1]
+		summary.CurrentPrice = last.MinPrice
+		summary.CurrentDay = last.Day
+	}
+
+	log.Printf("Price history for %q: %d day(s)", key, len(prices))
+	return summary, nil
+}
+
+// sortedUnique sorts the values and drops repeated ones
+func sortedUnique(values []string) []string {
+	sort.Strings(values)
+
+	var unique []string
+	for i, v := range values {
+		if v != "" && (i == 0 || v != values[i-1]) {
+			unique = append(unique, v)
+		}
+	}
+
+	return unique
+}
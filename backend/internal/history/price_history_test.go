@@ -0,0 +1,216 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/matcher"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+)
+
+var saoPaulo = mustLoadLocation("America/Sao_Paulo")
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+func newHistoryTest(t *testing.T, location *time.Location) (*PriceHistory, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return NewPriceHistory(repository.NewPriceHistoryRepository(db), matcher.NewOfferMatcher(0.5), location), mock
+}
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// dailyPrices returns price_history rows of key with the lowest and highest
+// price of each day
+func dailyPrices(key string, days ...interface{}) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"product_key", "day", "min_price", "max_price", "samples"})
+	for i := 0; i < len(days); i += 3 {
+		rows.AddRow(key, days[i].(time.Time), days[i+1].(float64), days[i+2].(float64), 1)
+	}
+	return rows
+}
+
+func TestDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		location *time.Location
+		at       time.Time
+		want     time.Time
+	}{
+		{"late evening in Brazil", saoPaulo, time.Date(2026, 10, 16, 23, 30, 0, 0, saoPaulo), date(10, 16)},
+		{"same instant in UTC", time.UTC, time.Date(2026, 10, 16, 23, 30, 0, 0, saoPaulo), date(10, 17)},
+		{"midnight in Brazil", saoPaulo, time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC), date(10, 17)},
+		{"just before midnight in Brazil", saoPaulo, time.Date(2026, 10, 17, 2, 59, 59, 0, time.UTC), date(10, 16)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newHistoryTest(t, tt.location)
+			if got := h.Day(tt.at); !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("Day(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordStoresTheDayOfTheLocation(t *testing.T) {
+	tests := []struct {
+		location *time.Location
+		want     string
+	}{
+		{saoPaulo, "2026-10-16"},
+		{time.UTC, "2026-10-17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location.String(), func(t *testing.T) {
+			h, mock := newHistoryTest(t, tt.location)
+			mock.ExpectExec("INSERT INTO price_history").
+				WithArgs("offer:promobit:1", tt.want, 2500.0).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			offer := &models.Offer{Key: "promobit:1", Price: 2500, ReceivedAt: time.Date(2026, 10, 16, 23, 30, 0, 0, saoPaulo)}
+			if err := h.Record(offer, nil); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestForWishlist(t *testing.T) {
+	h, mock := newHistoryTest(t, saoPaulo)
+	wishlist := &models.Wishlist{ProductName: "Geladeira Consul"}
+	key := WishlistKey(wishlist)
+
+	// 22:30 of Oct 16 in Brazil, already Oct 17 in UTC: Oct 16 is today, the
+	// first day of every window and the current price
+	now := time.Date(2026, 10, 17, 1, 30, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM price_history").
+		WithArgs(key, "2026-07-19").
+		WillReturnRows(dailyPrices(key,
+			date(7, 19), 2000.0, 2100.0,
+			date(9, 16), 2200.0, 2200.0, // One day before the 30-day window
+			date(9, 30), 2300.0, 2600.0,
+			date(10, 9), 2100.0, 2100.0, // One day before the 7-day window
+			date(10, 12), 2400.0, 2500.0,
+			date(10, 16), 2500.0, 2900.0))
+
+	summary, err := h.ForWishlist(wishlist, now)
+	if err != nil {
+		t.Fatalf("ForWishlist() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Period{
+		{Days: 7, MinPrice: 2400, AvgPrice: 2450, DaysWithPrices: 2},
+		{Days: 30, MinPrice: 2100, AvgPrice: 2325, DaysWithPrices: 4},
+		{Days: 90, MinPrice: 2000, AvgPrice: 2250, DaysWithPrices: 6},
+	}
+	for i, period := range summary.Periods {
+		if period != want[i] {
+			t.Errorf("period %d = %+v, want %+v", i, period, want[i])
+		}
+	}
+	if summary.CurrentPrice != 2500 || !summary.CurrentDay.Equal(date(10, 16)) {
+		t.Errorf("current = R$ %.2f on %v, want R$ 2500 on Oct 16", summary.CurrentPrice, summary.CurrentDay)
+	}
+}
+
+func TestForWishlistWithoutPrices(t *testing.T) {
+	h, mock := newHistoryTest(t, saoPaulo)
+	wishlist := &models.Wishlist{ProductName: "Geladeira Consul"}
+	mock.ExpectQuery("FROM price_history").WillReturnRows(dailyPrices(WishlistKey(wishlist)))
+
+	summary, err := h.ForWishlist(wishlist, time.Now())
+	if err != nil {
+		t.Fatalf("ForWishlist() error = %v", err)
+	}
+	for _, period := range summary.Periods {
+		if period.DaysWithPrices != 0 || period.MinPrice != 0 || period.AvgPrice != 0 {
+			t.Errorf("period %+v, want no prices", period)
+		}
+	}
+	if !summary.CurrentDay.IsZero() {
+		t.Errorf("current day = %v, want none", summary.CurrentDay)
+	}
+}
+
+func TestCheckReference(t *testing.T) {
+	// 22:00 of Oct 16 in Brazil, already Oct 17 in UTC
+	receivedAt := time.Date(2026, 10, 17, 1, 0, 0, 0, time.UTC)
+	priorDays := []interface{}{
+		date(10, 13), 2300.0, 2400.0,
+		date(10, 14), 2400.0, 2500.0,
+		date(10, 15), 2350.0, 2450.0,
+	}
+
+	tests := []struct {
+		name           string
+		location       *time.Location
+		originalPrice  float64
+		prices         []interface{} // Nil when no query is expected
+		wantSince      string
+		wantDays       int
+		wantHighest    float64
+		wantSuspicious bool
+	}{
+		{"no discount claimed", saoPaulo, 1900, nil, "", 0, 0, false},
+		{"fewer prior days than needed", saoPaulo, 4000, priorDays[3:], "2026-07-18", 2, 2500, false},
+		{"within the tolerance", saoPaulo, 2750, priorDays, "2026-07-18", 3, 2500, false},
+		{"above the tolerance", saoPaulo, 2760, priorDays, "2026-07-18", 3, 2500, true},
+		{
+			"prices of the offer day are not prior days", saoPaulo, 3000,
+			append(append([]interface{}{}, priorDays...), date(10, 16), 1990.0, 5000.0),
+			"2026-07-18", 3, 2500, true,
+		},
+		{
+			"the offer day depends on the location", time.UTC, 3000,
+			append(append([]interface{}{}, priorDays...), date(10, 16), 1990.0, 5000.0),
+			"2026-07-19", 4, 5000, false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, mock := newHistoryTest(t, tt.location)
+			if tt.prices != nil {
+				mock.ExpectQuery("FROM price_history").
+					WithArgs("offer:promobit:1", tt.wantSince).
+					WillReturnRows(dailyPrices("offer:promobit:1", tt.prices...))
+			}
+
+			offer := &models.Offer{Key: "promobit:1", Price: 2000, OriginalPrice: tt.originalPrice, ReceivedAt: receivedAt}
+			check, err := h.CheckReference(offer, OfferKey(offer))
+			if err != nil {
+				t.Fatalf("CheckReference() error = %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+
+			if check.Days != tt.wantDays || check.HighestPrice != tt.wantHighest || check.Suspicious != tt.wantSuspicious {
+				t.Errorf("CheckReference() = %d days, highest R$ %.2f, suspicious %t, want %d, R$ %.2f, %t",
+					check.Days, check.HighestPrice, check.Suspicious, tt.wantDays, tt.wantHighest, tt.wantSuspicious)
+			}
+		})
+	}
+}
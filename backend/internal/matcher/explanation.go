@@ -0,0 +1,49 @@
+package matcher
+
+import (
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/similarity"
+)
+
+// Rules that filter offers out without being a match condition
+const (
+	RuleExcludedTerm = "excluded_term"
+	RuleMinPrice     = "min_price"
+)
+
+// RuleCheck is the outcome of a single wishlist rule for an offer. Rule is one
+// of the MatchType constants for conditions, or RuleExcludedTerm / RuleMinPrice.
+type RuleCheck struct {
+	Rule      string  `json:"rule"`
+	Condition bool    `json:"condition"`          // Price, discount, cashback or effective price condition
+	Term      string  `json:"term,omitempty"`     // Excluded term being checked
+	Expected  float64 `json:"expected,omitempty"` // Value set on the wishlist
+	Actual    float64 `json:"actual,omitempty"`   // Value of the offer
+	Passed    bool    `json:"passed"`
+}
+
+// Explanation is the step by step decision of the matcher for one offer and
+// one wishlist
+type Explanation struct {
+	Name      similarity.Result `json:"name"`
+	Rules     []RuleCheck       `json:"rules"`
+	MatchType string            `json:"match_type,omitempty"`
+	Matched   bool              `json:"matched"`
+}
+
+// Explain runs the same checks as MatchOffer for a single wishlist and reports
+// every step instead of stopping at the first failure
+func (m *OfferMatcher) Explain(offer *models.Offer, wishlist *models.Wishlist) Explanation {
+	explanation := Explanation{
+		Name:  similarity.Compare(offer.ProductName, wishlist.ProductName, m.threshold),
+		Rules: m.checkRules(offer, wishlist),
+	}
+
+	matchType, rulesMatched := matchRules(explanation.Rules)
+	if explanation.Name.Matched && rulesMatched {
+		explanation.MatchType = matchType
+		explanation.Matched = true
+	}
+
+	return explanation
+}
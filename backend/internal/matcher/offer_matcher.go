@@ -1,130 +1,216 @@
-package matcher
-
-import (
-	"fmt"
-	"log"
-	"strings"
-
-	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
-)
-
-type OfferMatcher struct{}
-
-func NewOfferMatcher() *OfferMatcher {
-	return &OfferMatcher{}
-}
-
-// MatchOffer checks if an offer matches any wishlist items
-func (m *OfferMatcher) MatchOffer(offer *models.Offer, wishlists []models.Wishlist) []models.OfferNotification {
-	var notifications []models.OfferNotification
-
-	for _, wishlist := range wishlists {
-		// Check if product names match (case-insensitive, partial match)
-		if !m.productMatches(offer.ProductName, wishlist.ProductName) {
-			continue
-		}
-
-		// Check if price or discount matches
-		matchType := ""
-		matched := false
-
-		// Check target price match
-		if wishlist.TargetPrice != nil && offer.Price > 0 {
-			if offer.Price <= *wishlist.TargetPrice {
-				matchType = "price"
-				matched = true
-			}
-		}
-
-		// Check discount percentage match
-		if wishlist.DiscountPercentage != nil && offer.DiscountPercentage > 0 {
-			if offer.DiscountPercentage >= *wishlist.DiscountPercentage {
-				matchType = "discount"
-				matched = true
-			}
-		}
-
-		if matched {
-			notification := models.OfferNotification{
-				TelegramID:         wishlist.TelegramID,
-				ProductName:        offer.ProductName,
-				Price:              offer.Price,
-				OriginalPrice:      offer.OriginalPrice,
-				DiscountPercentage: offer.DiscountPercentage,
-				CashbackPercentage: offer.CashbackPercentage,
-				WishlistID:         wishlist.ID,
-				MatchType:          matchType,
-			}
-			notifications = append(notifications, notification)
-			log.Printf("Match found: Product '%s' for user %d (match type: %s)",
-				offer.ProductName, wishlist.TelegramID, matchType)
-		}
-	}
-
-	return notifications
-}
-
-// productMatches checks if the offer product name matches the wishlist product name
-// Uses case-insensitive partial matching
-func (m *OfferMatcher) productMatches(offerProduct, wishlistProduct string) bool {
-	offerLower := strings.ToLower(offerProduct)
-	wishlistLower := strings.ToLower(wishlistProduct)
-
-	// Check if either contains the other
-	if strings.Contains(offerLower, wishlistLower) || strings.Contains(wishlistLower, offerLower) {
-		return true
-	}
-
-	// Check for word-by-word matching (at least 50% of words must match)
-	offerWords := strings.Fields(offerLower)
-	wishlistWords := strings.Fields(wishlistLower)
-
-	if len(wishlistWords) == 0 {
-		return false
-	}
-
-	matchCount := 0
-	for _, wishlistWord := range wishlistWords {
-		for _, offerWord := range offerWords {
-			if strings.Contains(offerWord, wishlistWord) || strings.Contains(wishlistWord, offerWord) {
-				matchCount++
-				break
-			}
-		}
-	}
-
-	matchPercentage := float64(matchCount) / float64(len(wishlistWords))
-	return matchPercentage >= 0.5
-}
-
-// FormatNotificationMessage creates a formatted message for the notification
-func (m *OfferMatcher) FormatNotificationMessage(notification *models.OfferNotification) string {
-	var msg strings.Builder
-
-	msg.WriteString("🎉 *Oferta Encontrada!*\n\n")
-	msg.WriteString(fmt.Sprintf("📦 *Produto:* %s\n", notification.ProductName))
-
-	if notification.Price > 0 {
-		msg.WriteString(fmt.Sprintf("💰 *Preço:* R$ %.2f\n", notification.Price))
-	}
-
-	if notification.OriginalPrice > 0 && notification.OriginalPrice > notification.Price {
-		msg.WriteString(fmt.Sprintf("~~R$ %.2f~~\n", notification.OriginalPrice))
-	}
-
-	if notification.DiscountPercentage > 0 {
-		msg.WriteString(fmt.Sprintf("🔥 *Desconto:* %d%%\n", notification.DiscountPercentage))
-	}
-
-	if notification.CashbackPercentage > 0 {
-		msg.WriteString(fmt.Sprintf("💸 *Cashback:* %d%%\n", notification.CashbackPercentage))
-	}
-
-	if notification.MatchType == "price" {
-		msg.WriteString("\n✅ *Atingiu seu preço desejado!*")
-	} else if notification.MatchType == "discount" {
-		msg.WriteString("\n✅ *Atingiu o desconto desejado!*")
-	}
-
-	return msg.String()
-}
+package matcher
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/similarity"
+)
+
+type OfferMatcher struct {
+	threshold float64 // Minimum share of wishlist words found in the offer
+}
+
+func NewOfferMatcher(threshold float64) *OfferMatcher {
+	if threshold <= 0 || threshold > 1 {
+		threshold = similarity.DefaultThreshold
+	}
+	return &OfferMatcher{threshold: threshold}
+}
+
+// Threshold returns the minimum share of wishlist words an offer must contain
+func (m *OfferMatcher) Threshold() float64 {
+	return m.threshold
+}
+
+// MatchesProduct checks if an offer is the product of a wishlist item: the
+// names match and no excluded term or minimum price filters it out. Price,
+// discount and cashback conditions are not checked.
+func (m *OfferMatcher) MatchesProduct(offer *models.Offer, wishlist *models.Wishlist) bool {
+	if !m.productMatches(offer.ProductName, wishlist.ProductName) {
+		return false
+	}
+
+	for _, rule := range m.checkRules(offer, wishlist) {
+		if !rule.Condition && !rule.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchOffer checks if an offer matches any wishlist items
+func (m *OfferMatcher) MatchOffer(offer *models.Offer, wishlists []models.Wishlist) []models.OfferNotification {
+	var notifications []models.OfferNotification
+
+	for _, wishlist := range wishlists {
+		// Check if product names match (accent-insensitive, typo-tolerant)
+		if !m.productMatches(offer.ProductName, wishlist.ProductName) {
+			continue
+		}
+
+		// Check excluded terms, minimum price and price, discount, cashback or
+		// effective price conditions
+		matchType, matched := matchRules(m.checkRules(offer, &wishlist))
+
+		if matched {
+			notification := models.OfferNotification{
+				TelegramID:         wishlist.TelegramID,
+				ProductName:        offer.ProductName,
+				Price:              offer.Price,
+				OriginalPrice:      offer.OriginalPrice,
+				DiscountPercentage: offer.DiscountPercentage,
+				CashbackPercentage: offer.CashbackPercentage,
+				EffectivePrice:     offer.EffectivePrice(),
+				URL:                offer.URL,
+				ImageURL:           offer.ImageURL,
+				WishlistID:         wishlist.ID,
+				MatchType:          matchType,
+			}
+			notifications = append(notifications, notification)
+			log.Printf("Match found: Product '%s' for user %d (match type: %s)",
+				offer.ProductName, wishlist.TelegramID, matchType)
+		}
+	}
+
+	return notifications
+}
+
+// checkRules checks every rule of a wishlist besides the product name, in the
+// order they are applied. Conditions (price, discount, cashback and effective
+// price) must all be met; excluded terms and the minimum price filter offers out.
+func (m *OfferMatcher) checkRules(offer *models.Offer, wishlist *models.Wishlist) []RuleCheck {
+	var rules []RuleCheck
+
+	// Skip offers with excluded terms (e.g. "capa" for a phone)
+	for _, term := range wishlist.ExcludedTerms {
+		rules = append(rules, RuleCheck{
+			Rule:   RuleExcludedTerm,
+			Term:   term,
+			Passed: !similarity.ContainsTerm(offer.ProductName, term),
+		})
+	}
+
+	// Skip offers below the minimum price (usually accessories)
+	if wishlist.MinPrice != nil {
+		rules = append(rules, RuleCheck{
+			Rule:     RuleMinPrice,
+			Expected: *wishlist.MinPrice,
+			Actual:   offer.Price,
+			Passed:   offer.Price <= 0 || offer.Price >= *wishlist.MinPrice,
+		})
+	}
+
+	// Check target price match
+	if wishlist.TargetPrice != nil {
+		rules = append(rules, RuleCheck{
+			Rule:      models.MatchTypePrice,
+			Condition: true,
+			Expected:  *wishlist.TargetPrice,
+			Actual:    offer.Price,
+			Passed:    offer.Price > 0 && offer.Price <= *wishlist.TargetPrice,
+		})
+	}
+
+	// Check discount percentage match
+	if wishlist.DiscountPercentage != nil {
+		rules = append(rules, RuleCheck{
+			Rule:      models.MatchTypeDiscount,
+			Condition: true,
+			Expected:  float64(*wishlist.DiscountPercentage),
+			Actual:    float64(offer.DiscountPercentage),
+			Passed:    offer.DiscountPercentage > 0 && offer.DiscountPercentage >= *wishlist.DiscountPercentage,
+		})
+	}
+
+	// Check minimum cashback match
+	if wishlist.MinCashback != nil {
+		rules = append(rules, RuleCheck{
+			Rule:      models.MatchTypeCashback,
+			Condition: true,
+			Expected:  float64(*wishlist.MinCashback),
+			Actual:    float64(offer.CashbackPercentage),
+			Passed:    offer.CashbackPercentage > 0 && offer.CashbackPercentage >= *wishlist.MinCashback,
+		})
+	}
+
+	// Check price after cashback match
+	if wishlist.MaxEffectivePrice != nil {
+		rules = append(rules, RuleCheck{
+			Rule:      models.MatchTypeEffectivePrice,
+			Condition: true,
+			Expected:  *wishlist.MaxEffectivePrice,
+			Actual:    offer.EffectivePrice(),
+			Passed:    offer.Price > 0 && offer.EffectivePrice() <= *wishlist.MaxEffectivePrice,
+		})
+	}
+
+	return rules
+}
+
+// matchRules returns the match type when every rule passed and at least one
+// condition is set. The match type is the last condition checked.
+func matchRules(rules []RuleCheck) (string, bool) {
+	matchType := ""
+
+	for _, rule := range rules {
+		if !rule.Passed {
+			return "", false
+		}
+		if rule.Condition {
+			matchType = rule.Rule
+		}
+	}
+
+	return matchType, matchType != ""
+}
+
+// productMatches checks if the offer product name matches the wishlist product name
+// Uses accent-insensitive, typo-tolerant word matching (see package similarity)
+func (m *OfferMatcher) productMatches(offerProduct, wishlistProduct string) bool {
+	return similarity.Matches(offerProduct, wishlistProduct, m.threshold)
+}
+
+// FormatNotificationMessage creates a formatted message for the notification
+func (m *OfferMatcher) FormatNotificationMessage(notification *models.OfferNotification) string {
+	var msg strings.Builder
+
+	msg.WriteString("🎉 *Oferta Encontrada!*\n\n")
+	msg.WriteString(fmt.Sprintf("📦 *Produto:* %s\n", notification.ProductName))
+
+	if notification.Price > 0 {
+		msg.WriteString(fmt.Sprintf("💰 *Preço:* R$ %.2f\n", notification.Price))
+	}
+
+	if notification.OriginalPrice > 0 && notification.OriginalPrice > notification.Price {
+		msg.WriteString(fmt.Sprintf("~~R$ %.2f~~\n", notification.OriginalPrice))
+	}
+
+	if notification.DiscountPercentage > 0 {
+		msg.WriteString(fmt.Sprintf("🔥 *Desconto:* %d%%\n", notification.DiscountPercentage))
+	}
+
+	if notification.CashbackPercentage > 0 {
+		msg.WriteString(fmt.Sprintf("💸 *Cashback:* %d%%\n", notification.CashbackPercentage))
+	}
+
+	if notification.MatchType == models.MatchTypeEffectivePrice && notification.EffectivePrice > 0 {
+		msg.WriteString(fmt.Sprintf("💳 *Preço com cashback:* R$ %.2f\n", notification.EffectivePrice))
+	}
+
+	switch notification.MatchType {
+	case models.MatchTypePrice:
+		msg.WriteString("\n✅ *Atingiu seu preço desejado!*")
+	case models.MatchTypeDiscount:
+		msg.WriteString("\n✅ *Atingiu o desconto desejado!*")
+	case models.MatchTypeCashback:
+		msg.WriteString("\n✅ *Atingiu o cashback desejado!*")
+	case models.MatchTypeEffectivePrice:
+		msg.WriteString("\n✅ *Com o cashback, atingiu seu preço desejado!*")
+	}
+
+	return msg.String()
+}
@@ -0,0 +1,224 @@
+package matcher
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/similarity"
+)
+
+// maxTypoEdits is the largest number of typos similarity tolerates in any word
+const maxTypoEdits = 2
+
+// WishlistIndex is an in-memory inverted index of wishlists keyed by the
+// normalized words of their product names. It only narrows down which wishlists
+// an offer has to be checked against: every wishlist that productMatches could
+// accept is returned as a candidate, so MatchOffer over the candidates gives the
+// same result as MatchOffer over all wishlists.
+type WishlistIndex struct {
+	mu        sync.RWMutex
+	wishlists map[int]models.Wishlist
+	words     map[string]map[int]struct{} // whole wishlist word -> wishlist IDs
+	fragments map[string]map[int]struct{} // any substring of a wishlist word -> wishlist IDs
+	deletes   map[string]map[int]struct{} // wishlist word with tolerated typos deleted -> wishlist IDs
+	matchAll  map[int]struct{}            // wishlists without words match every offer
+}
+
+func NewWishlistIndex() *WishlistIndex {
+	idx := &WishlistIndex{}
+	idx.reset()
+	return idx
+}
+
+func (idx *WishlistIndex) reset() {
+	idx.wishlists = make(map[int]models.Wishlist)
+	idx.words = make(map[string]map[int]struct{})
+	idx.fragments = make(map[string]map[int]struct{})
+	idx.deletes = make(map[string]map[int]struct{})
+	idx.matchAll = make(map[int]struct{})
+}
+
+// Load replaces the whole index content with the given wishlists
+func (idx *WishlistIndex) Load(wishlists []models.Wishlist) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.reset()
+	for _, w := range wishlists {
+		idx.add(w)
+	}
+}
+
+// Add inserts or replaces a wishlist in the index
+func (idx *WishlistIndex) Add(wishlist models.Wishlist) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(wishlist.ID)
+	idx.add(wishlist)
+}
+
+// Remove deletes a wishlist from the index
+func (idx *WishlistIndex) Remove(wishlistID int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(wishlistID)
+}
+
+// Len returns the number of indexed wishlists
+func (idx *WishlistIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.wishlists)
+}
+
+// Candidates returns the wishlists that share at least one word fragment or
+// typo variant with the offer, ordered like the repository (newest first)
+func (idx *WishlistIndex) Candidates(offer *models.Offer) []models.Wishlist {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	offerTokens := similarity.Tokens(offer.ProductName)
+
+	// An empty offer name is contained in every wishlist name
+	if len(offerTokens) == 0 {
+		return idx.sorted(idx.allIDs())
+	}
+
+	ids := make(map[int]struct{}, len(idx.matchAll))
+	for id := range idx.matchAll {
+		ids[id] = struct{}{}
+	}
+
+	for _, term := range similarity.OfferTerms(offerTokens) {
+		// Wishlist words that contain the offer word
+		for id := range idx.fragments[term] {
+			ids[id] = struct{}{}
+		}
+		// Wishlist words contained in the offer word
+		for _, fragment := range substrings(term) {
+			for id := range idx.words[fragment] {
+				ids[id] = struct{}{}
+			}
+		}
+		// Wishlist words within the tolerated edit distance share a deletion
+		// variant with the offer word
+		if similarity.MaxEdits(term) == 0 {
+			continue
+		}
+		for _, variant := range similarity.Deletes(term, maxTypoEdits) {
+			for id := range idx.deletes[variant] {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+
+	return idx.sorted(ids)
+}
+
+func (idx *WishlistIndex) add(wishlist models.Wishlist) {
+	idx.wishlists[wishlist.ID] = wishlist
+
+	words := similarity.Tokens(wishlist.ProductName)
+	if len(words) == 0 {
+		idx.matchAll[wishlist.ID] = struct{}{}
+		return
+	}
+
+	for _, word := range words {
+		addPosting(idx.words, word, wishlist.ID)
+		for _, fragment := range substrings(word) {
+			addPosting(idx.fragments, fragment, wishlist.ID)
+		}
+		if maxEdits := similarity.MaxEdits(word); maxEdits > 0 {
+			for _, variant := range similarity.Deletes(word, maxEdits) {
+				addPosting(idx.deletes, variant, wishlist.ID)
+			}
+		}
+	}
+}
+
+func (idx *WishlistIndex) remove(wishlistID int) {
+	wishlist, ok := idx.wishlists[wishlistID]
+	if !ok {
+		return
+	}
+	delete(idx.wishlists, wishlistID)
+	delete(idx.matchAll, wishlistID)
+
+	for _, word := range similarity.Tokens(wishlist.ProductName) {
+		removePosting(idx.words, word, wishlistID)
+		for _, fragment := range substrings(word) {
+			removePosting(idx.fragments, fragment, wishlistID)
+		}
+		if maxEdits := similarity.MaxEdits(word); maxEdits > 0 {
+			for _, variant := range similarity.Deletes(word, maxEdits) {
+				removePosting(idx.deletes, variant, wishlistID)
+			}
+		}
+	}
+}
+
+func (idx *WishlistIndex) allIDs() map[int]struct{} {
+	ids := make(map[int]struct{}, len(idx.wishlists))
+	for id := range idx.wishlists {
+		ids[id] = struct{}{}
+	}
+	return ids
+}
+
+func (idx *WishlistIndex) sorted(ids map[int]struct{}) []models.Wishlist {
+	wishlists := make([]models.Wishlist, 0, len(ids))
+	for id := range ids {
+		wishlists = append(wishlists, idx.wishlists[id])
+	}
+
+	sort.Slice(wishlists, func(i, j int) bool {
+		if !wishlists[i].CreatedAt.Equal(wishlists[j].CreatedAt) {
+			return wishlists[i].CreatedAt.After(wishlists[j].CreatedAt)
+		}
+		return wishlists[i].ID > wishlists[j].ID
+	})
+
+	return wishlists
+}
+
+func addPosting(postings map[string]map[int]struct{}, key string, id int) {
+	ids, ok := postings[key]
+	if !ok {
+		ids = make(map[int]struct{})
+		postings[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removePosting(postings map[string]map[int]struct{}, key string, id int) {
+	ids, ok := postings[key]
+	if !ok {
+		return
+	}
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(postings, key)
+	}
+}
+
+// substrings returns every non-empty substring of word cut at rune boundaries
+func substrings(word string) []string {
+	bounds := make([]int, 0, len(word)+1)
+	for i := range word {
+		bounds = append(bounds, i)
+	}
+	bounds = append(bounds, len(word))
+
+	subs := make([]string, 0, len(bounds)*(len(bounds)-1)/2)
+	for i := 0; i < len(bounds)-1; i++ {
+		for j := i + 1; j < len(bounds); j++ {
+			subs = append(subs, word[bounds[i]:bounds[j]])
+		}
+	}
+	return subs
+}
@@ -0,0 +1,106 @@
+package matcher
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+)
+
+// indexWords are product words with the spellings users and stores write them
+// in: accents, plurals, compounds, typos and model numbers
+var indexWords = []string{
+	"iphone", "iPhone", "phone", "fone", "iphnoe",
+	"15", "128gb", "256GB", "pro", "max", "promax",
+	"geladeira", "geladeiras", "gelandeira", "frost", "free", "frostfree", "Frost-Free",
+	"fogão", "fogao", "fogões", "fogaõ",
+	"smart", "tv", "smarttv", "televisão", "televisao", "televisor",
+	"notebook", "notebok", "note", "book", "dell", "inspiron",
+	"air", "fryer", "airfryer", "fritadeira", "elétrica", "eletrica",
+	"lavadora", "lava", "roupas", "roupa", "de", "com", "para",
+	"capa", "pelicula", "ção", "x", "é",
+}
+
+func randomName(r *rand.Rand, maxWords int) string {
+	n := r.Intn(maxWords + 1)
+	words := make([]string, n)
+	for i := range words {
+		words[i] = indexWords[r.Intn(len(indexWords))]
+	}
+	return strings.Join(words, " ")
+}
+
+// TestCandidatesContainEveryMatch checks that the index never leaves out a
+// wishlist that MatchOffer accepts, over random offer and wishlist names
+func TestCandidatesContainEveryMatch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	targetPrice := 1000000.0
+	offerMatcher := NewOfferMatcher(0.5)
+
+	for round := 0; round < 300; round++ {
+		index := NewWishlistIndex()
+		wishlists := make([]models.Wishlist, 20)
+		for i := range wishlists {
+			wishlists[i] = models.Wishlist{
+				ID:          i + 1,
+				TelegramID:  int64(i + 1),
+				ProductName: randomName(r, 4),
+				TargetPrice: &targetPrice,
+				CreatedAt:   time.Unix(int64(i), 0),
+			}
+		}
+		index.Load(wishlists)
+
+		for o := 0; o < 20; o++ {
+			offer := &models.Offer{ProductName: randomName(r, 6), Price: 100}
+
+			candidates := make(map[int]bool)
+			for _, w := range index.Candidates(offer) {
+				candidates[w.ID] = true
+			}
+
+			for _, n := range offerMatcher.MatchOffer(offer, wishlists) {
+				if !candidates[n.WishlistID] {
+					t.Fatalf("offer %q matches wishlist %q but the index did not return it",
+						offer.ProductName, wishlists[n.WishlistID-1].ProductName)
+				}
+			}
+		}
+	}
+}
+
+func TestCandidatesAfterAddAndRemove(t *testing.T) {
+	targetPrice := 5000.0
+	index := NewWishlistIndex()
+	index.Add(models.Wishlist{ID: 1, ProductName: "Geladeira Frost Free", TargetPrice: &targetPrice})
+	index.Add(models.Wishlist{ID: 2, ProductName: "iPhone 15", TargetPrice: &targetPrice})
+
+	offer := &models.Offer{ProductName: "Geladeira Brastemp Frostfree 375L", Price: 3000}
+	if got := ids(index.Candidates(offer)); !got[1] || got[2] {
+		t.Fatalf("candidates = %v, want only wishlist 1", got)
+	}
+
+	// Replacing a wishlist drops the words of its old name
+	index.Add(models.Wishlist{ID: 1, ProductName: "Fogão quatro bocas", TargetPrice: &targetPrice})
+	if got := ids(index.Candidates(offer)); got[1] {
+		t.Fatalf("candidates = %v, replaced wishlist 1 still returned", got)
+	}
+
+	index.Remove(2)
+	if index.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", index.Len())
+	}
+	if got := ids(index.Candidates(&models.Offer{ProductName: "iPhone 15 128GB"})); got[2] {
+		t.Fatalf("candidates = %v, removed wishlist 2 still returned", got)
+	}
+}
+
+func ids(wishlists []models.Wishlist) map[int]bool {
+	found := make(map[int]bool, len(wishlists))
+	for _, w := range wishlists {
+		found[w.ID] = true
+	}
+	return found
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "offerbot_backend"
+
+var (
+	// OffersReceived counts the offers consumed from Kafka by source
+	OffersReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "offers_received_total",
+		Help:      "Offers consumed from the offers topic, by source.",
+	}, []string{"source"})
+
+	// MatchDuration measures matching an offer against the wishlists, from the
+	// index lookup to the filtered notifications
+	MatchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "match_duration_seconds",
+		Help:      "Time spent matching an offer against the wishlists.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	})
+
+	// Notifications counts the offer notifications published to the frontend,
+	// by status (sent or failed)
+	Notifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Offer notifications published to the bot responses topic, by status.",
+	}, []string{"status"})
+
+	// Commands counts the handled bot commands by type and result (ok, error
+	// or duplicate)
+	Commands = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Bot commands handled, by type and result.",
+	}, []string{"type", "result"})
+
+	// WishlistCache counts the wishlist cache lookups by cache (all or user)
+	// and result (hit or miss)
+	WishlistCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wishlist_cache_requests_total",
+		Help:      "Wishlist Redis cache lookups, by cache and result.",
+	}, []string{"cache", "result"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// CacheResult returns the result label of a cache lookup
+func CacheResult(hit bool) string {
+	if hit {
+		return "hit"
+	}
+	return "miss"
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// EnvelopeVersion is the version of the envelope written to the bot-responses topic
+const EnvelopeVersion = 1
+
+// Message types of the bot-responses topic
+const (
+	MessageTypeOfferNotification = "offer_notification"
+	MessageTypeWishlistList      = "wishlist_list"
+	MessageTypeWishlistAdded     = "wishlist_added"
+	MessageTypeWishlistUpdated   = "wishlist_updated"
+	MessageTypeWishlistDeleted   = "wishlist_deleted"
+	MessageTypeBacktestSummary   = "backtest_summary"
+	MessageTypePriceHistory      = "price_history"
+	MessageTypePreferences       = "preferences_updated"
+	MessageTypeCommandFailed     = "command_failed"
+)
+
+// Envelope wraps every message of the bot-responses topic so the frontend can
+// route it by type instead of guessing from its fields. CorrelationID is the
+// id of the command a response answers.
+type Envelope struct {
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// NewEnvelope marshals the payload into an envelope of the given type
+func NewEnvelope(messageType, correlationID string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", messageType, err)
+	}
+
+	return json.Marshal(Envelope{
+		Type:          messageType,
+		Version:       EnvelopeVersion,
+		CorrelationID: correlationID,
+		Payload:       data,
+	})
+}
@@ -1,67 +1,169 @@
-package models
-
-import "time"
-
-// Offer represents a product offer from Kafka
-type Offer struct {
-	ID                 int       `json:"id"`
-	ProductName        string    `json:"titulo"`
-	Price              float64   `json:"price"`
-	OriginalPrice      float64   `json:"oldPrice"`
-	Details            string    `json:"details"`
-	CashbackPercentage int       `json:"percentCashback"`
-	DiscountPercentage int       `json:"-"` // Calculated or not present in new schema
-	Source             string    `json:"source,omitempty"`
-	ReceivedAt         time.Time `json:"received_at"`
-}
-
-// WishlistEvent represents an event when a wishlist item is added
-type WishlistEvent struct {
-	Type               string  `json:"type"` // "wishlist_item_added"
-	TelegramID         int64   `json:"telegram_id"`
-	ProductName        string  `json:"product_name"`
-	TargetPrice        *float64 `json:"target_price,omitempty"`
-	DiscountPercentage *int     `json:"discount_percentage,omitempty"`
-	Timestamp          time.Time `json:"timestamp"`
-}
-
-// User represents a Telegram user
-type User struct {
-	TelegramID int64     `json:"telegram_id"`
-	Username   string    `json:"username"`
-	FirstName  string    `json:"first_name"`
-	LastName   string    `json:"last_name"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-}
-
-// Wishlist represents a user's wishlist item
-type Wishlist struct {
-	ID                 int      `json:"id"`
-	TelegramID         int64    `json:"telegram_id"`
-	ProductName        string   `json:"product_name"`
-	TargetPrice        *float64 `json:"target_price,omitempty"`
-	DiscountPercentage *int     `json:"discount_percentage,omitempty"`
-	CreatedAt          time.Time `json:"created_at"`
-}
-
-// Notification represents a sent notification
-type Notification struct {
-	ID         int       `json:"id"`
-	TelegramID int64     `json:"telegram_id"`
-	WishlistID *int      `json:"wishlist_id,omitempty"`
-	OfferID    *int      `json:"offer_id,omitempty"`
-	SentAt     time.Time `json:"sent_at"`
-}
-
-// OfferNotification represents a notification to be sent via Kafka
-type OfferNotification struct {
-	TelegramID         int64   `json:"telegram_id"`
-	ProductName        string  `json:"product_name"`
-	Price              float64 `json:"price"`
-	OriginalPrice      float64 `json:"original_price"`
-	DiscountPercentage int     `json:"discount_percentage"`
-	CashbackPercentage int     `json:"cashback_percentage"`
-	WishlistID         int     `json:"wishlist_id"`
-	MatchType          string  `json:"match_type"` // "price" or "discount"
-}
+package models
+
+import "time"
+
+// Offer represents a product offer from Kafka
+type Offer struct {
+	ID                 int       `json:"id"`                   // Our id, set when the offer is stored
+	ExternalID         string    `json:"externalId,omitempty"` // Id of the offer at its source
+	Seller             string    `json:"seller,omitempty"`
+	ProductName        string    `json:"titulo"`
+	Price              float64   `json:"price"`
+	OriginalPrice      float64   `json:"oldPrice"`
+	Details            string    `json:"details"`
+	CashbackPercentage int       `json:"percentCashback"`
+	URL                string    `json:"url,omitempty"`
+	ImageURL           string    `json:"imageUrl,omitempty"`
+	DiscountPercentage int       `json:"-"` // Calculated by the normalizer from OriginalPrice and Price
+	Source             string    `json:"source,omitempty"`
+	Key                string    `json:"-"` // Canonical identity, set by the normalizer
+	ReceivedAt         time.Time `json:"received_at"`
+}
+
+// OfferPriceEvent records a new offer or a change of its price or cashback
+type OfferPriceEvent struct {
+	ID                 int       `json:"id"`
+	OfferID            int       `json:"offer_id"`
+	Price              float64   `json:"price"`
+	PreviousPrice      *float64  `json:"previous_price,omitempty"` // Nil when the offer was first seen
+	OriginalPrice      float64   `json:"original_price"`
+	DiscountPercentage int       `json:"discount_percentage"`
+	CashbackPercentage int       `json:"cashback_percentage"`
+	RecordedAt         time.Time `json:"recorded_at"`
+}
+
+// DailyPrice is the price range of a product on one day. ProductKey is either a
+// canonical offer or a wishlist term (see the history package).
+type DailyPrice struct {
+	ProductKey string    `json:"product_key"`
+	Day        time.Time `json:"day"`
+	MinPrice   float64   `json:"min_price"`
+	MaxPrice   float64   `json:"max_price"`
+	Samples    int       `json:"samples"`
+}
+
+// WishlistEvent represents an event when a wishlist item is added, updated or deleted
+type WishlistEvent struct {
+	Type               string  `json:"type"` // One of the WishlistEvent constants
+	WishlistID         int     `json:"wishlist_id,omitempty"`
+	TelegramID         int64   `json:"telegram_id"`
+	ProductName        string  `json:"product_name"`
+	TargetPrice        *float64 `json:"target_price,omitempty"`
+	DiscountPercentage *int     `json:"discount_percentage,omitempty"`
+	MinPrice           *float64 `json:"min_price,omitempty"`
+	ExcludedTerms      []string `json:"excluded_terms,omitempty"`
+	MinCashback        *int     `json:"min_cashback,omitempty"`
+	MaxEffectivePrice  *float64 `json:"max_effective_price,omitempty"`
+	Timestamp          time.Time `json:"timestamp"`
+}
+
+// Types of a WishlistEvent
+const (
+	WishlistEventAdded   = "wishlist_item_added"
+	WishlistEventUpdated = "wishlist_item_updated"
+	WishlistEventDeleted = "wishlist_item_deleted"
+)
+
+// OutboxEvent is a wishlist event written in the transaction of the wishlist
+// change and waiting to be published. Payload is the JSON WishlistEvent and
+// TraceContext the trace of the command that changed the wishlist.
+type OutboxEvent struct {
+	ID           int64
+	Type         string
+	TelegramID   int64
+	Payload      []byte
+	TraceContext map[string]string
+	Attempts     int
+	CreatedAt    time.Time
+}
+
+// User represents a Telegram user
+type User struct {
+	TelegramID int64     `json:"telegram_id"`
+	Username   string    `json:"username"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Wishlist represents a user's wishlist item
+type Wishlist struct {
+	ID                 int      `json:"id"`
+	TelegramID         int64    `json:"telegram_id"`
+	ProductName        string   `json:"product_name"`
+	TargetPrice        *float64 `json:"target_price,omitempty"`
+	DiscountPercentage *int     `json:"discount_percentage,omitempty"`
+	MinPrice           *float64 `json:"min_price,omitempty"`      // Offers below this price are ignored (accessories, parts)
+	ExcludedTerms      []string `json:"excluded_terms,omitempty"` // Offers containing any of these terms are ignored
+	MinCashback        *int     `json:"min_cashback,omitempty"`
+	MaxEffectivePrice  *float64 `json:"max_effective_price,omitempty"` // Target for price after cashback
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// Notification represents a sent notification
+type Notification struct {
+	ID                 int       `json:"id"`
+	TelegramID         int64     `json:"telegram_id"`
+	WishlistID         *int      `json:"wishlist_id,omitempty"`
+	OfferID            *int      `json:"offer_id,omitempty"`
+	OfferKey           string    `json:"offer_key,omitempty"`
+	ProductName        string    `json:"product_name"`
+	Source             string    `json:"source"`
+	Price              *float64  `json:"price,omitempty"`
+	DiscountPercentage int       `json:"discount_percentage"`
+	CashbackPercentage int       `json:"cashback_percentage"`
+	SentAt             time.Time `json:"sent_at"`
+}
+
+// OfferNotification represents a notification to be sent via Kafka
+type OfferNotification struct {
+	TelegramID         int64   `json:"telegram_id"`
+	ProductName        string  `json:"product_name"`
+	Price              float64 `json:"price"`
+	OriginalPrice      float64 `json:"original_price"`
+	DiscountPercentage int     `json:"discount_percentage"`
+	CashbackPercentage int     `json:"cashback_percentage"`
+	EffectivePrice     float64 `json:"effective_price,omitempty"` // Price after cashback
+	URL                string  `json:"url,omitempty"`
+	ImageURL           string  `json:"image_url,omitempty"`
+	WishlistID         int     `json:"wishlist_id"`
+	MatchType          string  `json:"match_type"` // One of the MatchType constants
+
+	// Set when the original price is above every price recorded for the
+	// product, i.e. the discount is likely inflated
+	SuspiciousReference bool    `json:"suspicious_reference,omitempty"`
+	HighestPrice        float64 `json:"highest_price,omitempty"` // Highest recorded price before the offer
+}
+
+// Match types of an OfferNotification
+const (
+	MatchTypePrice          = "price"
+	MatchTypeDiscount       = "discount"
+	MatchTypeCashback       = "cashback"
+	MatchTypeEffectivePrice = "effective_price"
+)
+
+// EffectivePrice returns the price of the offer after cashback
+func (o *Offer) EffectivePrice() float64 {
+	return o.Price * (1 - float64(o.CashbackPercentage)/100)
+}
+
+// EffectivePrice returns the notified price after cashback, 0 when the price
+// was not recorded
+func (n *Notification) EffectivePrice() float64 {
+	if n.Price == nil {
+		return 0
+	}
+	return *n.Price * (1 - float64(n.CashbackPercentage)/100)
+}
+
+// ProcessedCommand is a command already applied. Response is the enveloped
+// response sent for it, empty when the command has no response.
+type ProcessedCommand struct {
+	CommandID   string
+	Type        string
+	TelegramID  int64
+	Response    []byte
+	ProcessedAt time.Time
+}
@@ -0,0 +1,168 @@
+package normalizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/similarity"
+)
+
+// ErrInvalidOffer is returned when an offer cannot be fixed and must be dropped
+var ErrInvalidOffer = errors.New("invalid offer")
+
+// Change describes a single field modified while normalizing an offer
+type Change struct {
+	Field  string
+	From   string
+	To     string
+	Reason string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s (%s)", c.Field, c.From, c.To, c.Reason)
+}
+
+// OfferNormalizer cleans up offers coming from any source (scraper, sns-bridge,
+// S3 imports) before they are stored and matched, so sources only need to
+// publish the raw values they have.
+type OfferNormalizer struct{}
+
+func NewOfferNormalizer() *OfferNormalizer {
+	return &OfferNormalizer{}
+}
+
+// Normalize fixes the offer in place, sets its canonical key and returns the
+// list of changes made. It returns ErrInvalidOffer when the offer has values
+// that cannot be fixed.
+func (n *OfferNormalizer) Normalize(offer *models.Offer) ([]Change, error) {
+	var changes []Change
+
+	// Our ids are assigned when offers are stored; older producers sent the
+	// id of the offer at the source in this field
+	if offer.ID != 0 {
+		if offer.ExternalID == "" {
+			offer.ExternalID = strconv.Itoa(offer.ID)
+		}
+		changes = append(changes, Change{
+			Field:  "id",
+			From:   strconv.Itoa(offer.ID),
+			To:     "0",
+			Reason: "source id moved to external_id",
+		})
+		offer.ID = 0
+	}
+	offer.ExternalID = strings.TrimSpace(offer.ExternalID)
+
+	if name := strings.TrimSpace(offer.ProductName); name != offer.ProductName {
+		changes = append(changes, Change{
+			Field:  "product_name",
+			From:   fmt.Sprintf("%q", offer.ProductName),
+			To:     fmt.Sprintf("%q", name),
+			Reason: "trimmed whitespace",
+		})
+		offer.ProductName = name
+	}
+
+	if offer.ProductName == "" {
+		return changes, fmt.Errorf("%w: empty product name", ErrInvalidOffer)
+	}
+
+	if offer.Price < 0 || math.IsNaN(offer.Price) || math.IsInf(offer.Price, 0) {
+		return changes, fmt.Errorf("%w: price %.2f", ErrInvalidOffer, offer.Price)
+	}
+
+	if offer.OriginalPrice < 0 || math.IsNaN(offer.OriginalPrice) || math.IsInf(offer.OriginalPrice, 0) {
+		return changes, fmt.Errorf("%w: original price %.2f", ErrInvalidOffer, offer.OriginalPrice)
+	}
+
+	// An original price of 0 means the source has none; a lower one than the
+	// price is a broken offer
+	if offer.OriginalPrice > 0 && offer.OriginalPrice < offer.Price {
+		return changes, fmt.Errorf("%w: original price %.2f below price %.2f", ErrInvalidOffer, offer.OriginalPrice, offer.Price)
+	}
+
+	if offer.CashbackPercentage < 0 || offer.CashbackPercentage > 100 {
+		changes = append(changes, percentChange("cashback_percentage", offer.CashbackPercentage, 0, "cashback outside 0-100%"))
+		offer.CashbackPercentage = 0
+	}
+
+	// Links are sent to Telegram, which only accepts absolute http(s) URLs
+	changes = appendURLChange(changes, "url", &offer.URL)
+	changes = appendURLChange(changes, "image_url", &offer.ImageURL)
+
+	discount := calculateDiscount(offer.Price, offer.OriginalPrice)
+	if discount != offer.DiscountPercentage {
+		changes = append(changes, percentChange("discount_percentage", offer.DiscountPercentage, discount, "derived from original price and price"))
+		offer.DiscountPercentage = discount
+	}
+
+	offer.Key = offerKey(offer)
+
+	return changes, nil
+}
+
+// offerKey returns the canonical identity of an offer: its source and the id
+// at the source, or a fingerprint of the normalized title and seller when the
+// source has no id. Receipts of the same offer share a key.
+func offerKey(offer *models.Offer) string {
+	source := strings.ToLower(strings.TrimSpace(offer.Source))
+	if offer.ExternalID != "" {
+		return source + ":" + offer.ExternalID
+	}
+
+	title := strings.Join(strings.Fields(similarity.Normalize(offer.ProductName)), " ")
+	seller := strings.Join(strings.Fields(similarity.Normalize(offer.Seller)), " ")
+	sum := sha256.Sum256([]byte(title + "|" + seller))
+
+	return source + ":fp:" + hex.EncodeToString(sum[:16])
+}
+
+// calculateDiscount returns the discount in whole percent, rounded down so an
+// offer never looks better than it is
+func calculateDiscount(price, originalPrice float64) int {
+	if price <= 0 || originalPrice <= 0 || originalPrice <= price {
+		return 0
+	}
+
+	// The epsilon keeps values like 28.999999999999996 from rounding down to 28
+	return int(math.Floor((originalPrice-price)/originalPrice*100 + 1e-9))
+}
+
+// appendURLChange trims the link and drops it when it is not an absolute
+// http(s) URL
+func appendURLChange(changes []Change, field string, link *string) []Change {
+	url := strings.TrimSpace(*link)
+	reason := "trimmed whitespace"
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		url = ""
+		reason = "not an http(s) URL"
+	}
+
+	if url == *link {
+		return changes
+	}
+
+	changes = append(changes, Change{
+		Field:  field,
+		From:   fmt.Sprintf("%q", *link),
+		To:     fmt.Sprintf("%q", url),
+		Reason: reason,
+	})
+	*link = url
+	return changes
+}
+
+func percentChange(field string, from, to int, reason string) Change {
+	return Change{
+		Field:  field,
+		From:   fmt.Sprintf("%d%%", from),
+		To:     fmt.Sprintf("%d%%", to),
+		Reason: reason,
+	}
+}
@@ -0,0 +1,181 @@
+package normalizer
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+)
+
+func TestNormalizeDerivesDiscount(t *testing.T) {
+	tests := []struct {
+		name          string
+		price         float64
+		originalPrice float64
+		sent          int
+		want          int
+	}{
+		{"whole percent", 80, 100, 0, 20},
+		{"rounded down", 3999, 4999, 0, 20},
+		{"float error does not round down", 71, 100, 0, 29},
+		{"just below a whole percent", 70.01, 100, 0, 29},
+		{"no original price", 80, 0, 0, 0},
+		{"same price", 100, 100, 0, 0},
+		{"free offer", 0, 100, 0, 0},
+		{"sent discount replaced", 80, 100, 50, 20},
+		{"sent discount without original price dropped", 80, 0, 30, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offer := &models.Offer{
+				ProductName:        "Smart TV 50",
+				Price:              tt.price,
+				OriginalPrice:      tt.originalPrice,
+				DiscountPercentage: tt.sent,
+			}
+
+			changes, err := NewOfferNormalizer().Normalize(offer)
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if offer.DiscountPercentage != tt.want {
+				t.Errorf("DiscountPercentage = %d, want %d", offer.DiscountPercentage, tt.want)
+			}
+
+			changed := hasChange(changes, "discount_percentage")
+			if changed != (tt.sent != tt.want) {
+				t.Errorf("discount change recorded = %t, want %t (changes: %v)", changed, tt.sent != tt.want, changes)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejectsImpossibleValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		offer models.Offer
+	}{
+		{"empty name", models.Offer{ProductName: "  ", Price: 10}},
+		{"negative price", models.Offer{ProductName: "TV", Price: -1}},
+		{"NaN price", models.Offer{ProductName: "TV", Price: math.NaN()}},
+		{"infinite price", models.Offer{ProductName: "TV", Price: math.Inf(1)}},
+		{"negative original price", models.Offer{ProductName: "TV", Price: 10, OriginalPrice: -20}},
+		{"NaN original price", models.Offer{ProductName: "TV", Price: 10, OriginalPrice: math.NaN()}},
+		{"infinite original price", models.Offer{ProductName: "TV", Price: 10, OriginalPrice: math.Inf(1)}},
+		{"original price below price", models.Offer{ProductName: "TV", Price: 100, OriginalPrice: 90}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offer := tt.offer
+			if _, err := NewOfferNormalizer().Normalize(&offer); !errors.Is(err, ErrInvalidOffer) {
+				t.Fatalf("Normalize() error = %v, want ErrInvalidOffer", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeFixesFields(t *testing.T) {
+	offer := &models.Offer{
+		ID:                 42,
+		ProductName:        "  iPhone 15  ",
+		Price:              3999,
+		CashbackPercentage: 150,
+		URL:                " https://loja.com/iphone ",
+		ImageURL:           "javascript:alert(1)",
+		Source:             "Promobit",
+	}
+
+	changes, err := NewOfferNormalizer().Normalize(offer)
+	if err != nil {
+		t.Fatalf("Normalize() error = %v", err)
+	}
+
+	if offer.ID != 0 || offer.ExternalID != "42" {
+		t.Errorf("ID = %d, ExternalID = %q, want the source id moved to ExternalID", offer.ID, offer.ExternalID)
+	}
+	if offer.ProductName != "iPhone 15" {
+		t.Errorf("ProductName = %q, want trimmed", offer.ProductName)
+	}
+	if offer.CashbackPercentage != 0 {
+		t.Errorf("CashbackPercentage = %d, want 0", offer.CashbackPercentage)
+	}
+	if offer.URL != "https://loja.com/iphone" {
+		t.Errorf("URL = %q, want trimmed", offer.URL)
+	}
+	if offer.ImageURL != "" {
+		t.Errorf("ImageURL = %q, want dropped", offer.ImageURL)
+	}
+	if offer.Key != "promobit:42" {
+		t.Errorf("Key = %q, want promobit:42", offer.Key)
+	}
+
+	for _, field := range []string{"id", "product_name", "cashback_percentage", "url", "image_url"} {
+		if !hasChange(changes, field) {
+			t.Errorf("no change recorded for %s (changes: %v)", field, changes)
+		}
+	}
+}
+
+func TestNormalizeURLs(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://loja.com/p/1", "https://loja.com/p/1"},
+		{"http://loja.com/p/1", "http://loja.com/p/1"},
+		{"  https://loja.com/p/1\n", "https://loja.com/p/1"},
+		{"loja.com/p/1", ""},
+		{"ftp://loja.com/p/1", ""},
+		{"/p/1", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		offer := &models.Offer{ProductName: "TV", Price: 10, URL: tt.url}
+		changes, err := NewOfferNormalizer().Normalize(offer)
+		if err != nil {
+			t.Fatalf("Normalize(%q) error = %v", tt.url, err)
+		}
+		if offer.URL != tt.want {
+			t.Errorf("URL %q normalized to %q, want %q", tt.url, offer.URL, tt.want)
+		}
+		if hasChange(changes, "url") != (tt.url != tt.want) {
+			t.Errorf("URL %q: change recorded = %t, want %t", tt.url, hasChange(changes, "url"), tt.url != tt.want)
+		}
+	}
+}
+
+func TestOfferKeyFingerprint(t *testing.T) {
+	a := &models.Offer{ProductName: "Geladeira Frost Free", Seller: "Loja X", Price: 10, Source: "sns"}
+	b := &models.Offer{ProductName: "GELADEIRA  frost free", Seller: "loja x", Price: 12, Source: "SNS"}
+	c := &models.Offer{ProductName: "Geladeira Frost Free", Seller: "Loja Y", Price: 10, Source: "sns"}
+
+	for _, offer := range []*models.Offer{a, b, c} {
+		if _, err := NewOfferNormalizer().Normalize(offer); err != nil {
+			t.Fatalf("Normalize() error = %v", err)
+		}
+	}
+
+	if !strings.HasPrefix(a.Key, "sns:fp:") {
+		t.Errorf("Key = %q, want a sns fingerprint", a.Key)
+	}
+	if a.Key != b.Key {
+		t.Errorf("receipts of the same offer have keys %q and %q", a.Key, b.Key)
+	}
+	if a.Key == c.Key {
+		t.Errorf("offers of different sellers share key %q", a.Key)
+	}
+}
+
+func hasChange(changes []Change, field string) bool {
+	for _, c := range changes {
+		if c.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+)
+
+// tracer starts the spans of this package
+var tracer = tracing.NewTracer("github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/outbox")
+
+// batchSize is the maximum number of events published per poll
+const batchSize = 100
+
+// Relay publishes the wishlist events of the outbox table to Kafka
+type Relay struct {
+	repo      *repository.OutboxRepository
+	publisher bus.Publisher
+	topic     string
+	interval  time.Duration
+	retention time.Duration
+}
+
+// NewRelay creates a relay polling the outbox every interval. Published events
+// are deleted after retention.
+func NewRelay(repo *repository.OutboxRepository, publisher bus.Publisher, topic string, interval, retention time.Duration) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		topic:     topic,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Run publishes pending events until the context is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.publishPending()
+
+			if time.Since(lastCleanup) >= time.Hour {
+				r.cleanup()
+				lastCleanup = time.Now()
+			}
+		}
+	}
+}
+
+// publishPending publishes pending events in batches until none are left or
+// publishing fails. Failed events are retried on the next poll.
+func (r *Relay) publishPending() {
+	for {
+		published, err := r.repo.PublishPending(batchSize, r.publish)
+		if published > 0 {
+			log.Printf("Published %d wishlist events", published)
+		}
+		if err != nil {
+			log.Printf("Failed to publish wishlist events: %v", err)
+			return
+		}
+		if published < batchSize {
+			return
+		}
+	}
+}
+
+// publish writes an event to Kafka, keyed by user so add, update and delete
+// events of the same user keep their order. The message continues the trace
+// of the command that wrote the event.
+func (r *Relay) publish(event models.OutboxEvent) error {
+	msg := &bus.Message{
+		Topic: r.topic,
+		Key:   []byte(fmt.Sprintf("%d", event.TelegramID)),
+		Value: event.Payload,
+	}
+
+	ctx := tracing.Extract(context.Background(), event.TraceContext)
+	_, span := tracer.StartProducerSpan(ctx, msg)
+	err := r.publisher.Publish(ctx, msg)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to send %s event %d: %w", event.Type, event.ID, err)
+	}
+
+	return nil
+}
+
+// cleanup deletes events published before the retention
+func (r *Relay) cleanup() {
+	deleted, err := r.repo.DeleteSentBefore(time.Now().Add(-r.retention))
+	if err != nil {
+		log.Printf("Failed to delete published wishlist events: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d published wishlist events", deleted)
+	}
+}
@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/repository"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+)
+
+// downPublisher fails every publish while down is set
+type downPublisher struct {
+	down   bool
+	memory *bus.Memory
+}
+
+func (p *downPublisher) Publish(ctx context.Context, msgs ...*bus.Message) error {
+	if p.down {
+		return errors.New("kafka: client has run out of available brokers")
+	}
+	return p.memory.Publish(ctx, msgs...)
+}
+
+var eventColumns = []string{"id", "event_type", "telegram_id", "payload", "trace_context", "attempts", "created_at"}
+
+func expectPending(mock sqlmock.Sqlmock, attempts int) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery("FROM wishlist_outbox").
+		WithArgs(batchSize).
+		WillReturnRows(sqlmock.NewRows(eventColumns).
+			AddRow(1, "wishlist_item_added", 10, []byte(`{"wishlist_id":1}`), []byte(`{}`), attempts, time.Now()).
+			AddRow(2, "wishlist_item_deleted", 10, []byte(`{"wishlist_id":2}`), []byte(`{}`), 0, time.Now()))
+}
+
+func TestFailedPublishLeavesEventsPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	publisher := &downPublisher{down: true, memory: bus.NewMemory()}
+	relay := NewRelay(repository.NewOutboxRepository(db), publisher, "wishlist-events", time.Second, time.Hour)
+
+	// The first event fails: its attempt is recorded, it is not marked as sent
+	// and the event after it is not published out of order
+	expectPending(mock, 0)
+	mock.ExpectExec("SET attempts = attempts \\+ 1").
+		WithArgs(int64(1), "failed to send wishlist_item_added event 1: kafka: client has run out of available brokers").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	relay.publishPending()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both events are still pending on the next poll and are published in order
+	publisher.down = false
+	expectPending(mock, 1)
+	mock.ExpectExec("SET sent_at = NOW\\(\\)").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET sent_at = NOW\\(\\)").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	relay.publishPending()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	publisher.memory.Subscribe(ctx, bus.Subscription{Topic: "wishlist-events", Group: "test"}, func(_ context.Context, msg *bus.Message) error {
+		received <- string(msg.Key) + " " + string(msg.Value)
+		return nil
+	})
+	for _, want := range []string{`10 {"wishlist_id":1}`, `10 {"wishlist_id":2}`} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Errorf("published %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("events were not published")
+		}
+	}
+}
+
+func TestRelayPublishesUpdatedItems(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	payload := `{"type":"wishlist_item_updated","wishlist_id":3,"telegram_id":10,"product_name":"geladeira inverter"}`
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery("FROM wishlist_outbox").
+		WithArgs(batchSize).
+		WillReturnRows(sqlmock.NewRows(eventColumns).
+			AddRow(3, "wishlist_item_updated", 10, []byte(payload), []byte(`{}`), 0, time.Now()))
+	mock.ExpectExec("SET sent_at = NOW\\(\\)").WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	publisher := &downPublisher{memory: bus.NewMemory()}
+	NewRelay(repository.NewOutboxRepository(db), publisher, "wishlist-events", time.Second, time.Hour).publishPending()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan string, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	publisher.memory.Subscribe(ctx, bus.Subscription{Topic: "wishlist-events", Group: "test"}, func(_ context.Context, msg *bus.Message) error {
+		received <- string(msg.Key) + " " + string(msg.Value)
+		return nil
+	})
+	select {
+	case got := <-received:
+		if want := "10 " + payload; got != want {
+			t.Errorf("published %s, want %s", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("updated event was not published")
+	}
+}
+
+func TestRelaySkipsWhileAnotherReplicaPublishes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+	mock.ExpectRollback()
+
+	publisher := &downPublisher{memory: bus.NewMemory()}
+	NewRelay(repository.NewOutboxRepository(db), publisher, "wishlist-events", time.Second, time.Hour).publishPending()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
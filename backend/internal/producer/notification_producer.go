@@ -0,0 +1,59 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+)
+
+// tracer starts the spans of this package
+var tracer = tracing.NewTracer("github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/producer")
+
+// NotificationProducer publishes offer notifications to the responses topic
+type NotificationProducer struct {
+	publisher bus.Publisher
+	topic     string
+}
+
+func NewNotificationProducer(publisher bus.Publisher, topic string) *NotificationProducer {
+	return &NotificationProducer{
+		publisher: publisher,
+		topic:     topic,
+	}
+}
+
+// SendNotification sends an offer notification to the bus
+func (p *NotificationProducer) SendNotification(ctx context.Context, notification *models.OfferNotification) error {
+	msg, err := p.message(notification.TelegramID, notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	_, span := tracer.StartProducerSpan(ctx, msg)
+	err = p.publisher.Publish(ctx, msg)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to write message to kafka: %w", err)
+	}
+
+	log.Printf("Message sent to partition %d at offset %d", msg.Partition, msg.Offset)
+	return nil
+}
+
+// message wraps a notification in the response envelope, keyed by user
+func (p *NotificationProducer) message(telegramID int64, notification interface{}) (*bus.Message, error) {
+	data, err := models.NewEnvelope(models.MessageTypeOfferNotification, "", notification)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bus.Message{
+		Topic: p.topic,
+		Key:   []byte(fmt.Sprintf("%d", telegramID)),
+		Value: data,
+	}, nil
+}
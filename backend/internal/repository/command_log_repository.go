@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErrCommandProcessed is returned when another consumer recorded the command first
+var ErrCommandProcessed = errors.New("command already processed")
+
+// CommandLogRepository keeps the commands already applied and their responses
+type CommandLogRepository struct {
+	db *sql.DB
+}
+
+func NewCommandLogRepository(db *sql.DB) *CommandLogRepository {
+	return &CommandLogRepository{db: db}
+}
+
+// GetProcessedCommand returns the command with the id, or sql.ErrNoRows when it
+// was not processed yet
+func (r *CommandLogRepository) GetProcessedCommand(commandID string) (*models.ProcessedCommand, error) {
+	query := `
+		SELECT command_id, command_type, telegram_id, response, processed_at
+		FROM processed_commands
+		WHERE command_id = $1
+	`
+
+	var command models.ProcessedCommand
+	err := r.db.QueryRow(query, commandID).Scan(
+		&command.CommandID,
+		&command.Type,
+		&command.TelegramID,
+		&command.Response,
+		&command.ProcessedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &command, nil
+}
+
+// RecordCommand records a command in the transaction that applies it, so the
+// command is logged if and only if its changes are. It returns
+// ErrCommandProcessed when the command was recorded by another transaction.
+func (r *CommandLogRepository) RecordCommand(tx *sql.Tx, command *models.ProcessedCommand) error {
+	query := `
+		INSERT INTO processed_commands (command_id, command_type, telegram_id, response, processed_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	// JSONB parameters must be sent as text
+	var response interface{}
+	if len(command.Response) > 0 {
+		response = string(command.Response)
+	}
+
+	_, err := tx.Exec(query, command.CommandID, command.Type, command.TelegramID, response, command.ProcessedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return ErrCommandProcessed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record command %s: %w", command.CommandID, err)
+	}
+
+	return nil
+}
+
+// DeleteProcessedBefore deletes commands processed before the given time.
+// Redeliveries happen within minutes, so old commands are not needed.
+func (r *CommandLogRepository) DeleteProcessedBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM processed_commands WHERE processed_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete processed commands: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+)
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// GetLastNotification returns the most recent notification sent since the given
+// time for the wishlist and offer key, or nil when there is none
+func (r *NotificationRepository) GetLastNotification(wishlistID int, offerKey string, since time.Time) (*models.Notification, error) {
+	query := `
+		SELECT id, telegram_id, wishlist_id, offer_id, offer_key, product_name, source, price,
+			COALESCE(discount_percentage, 0), COALESCE(cashback_percentage, 0), sent_at
+		FROM notifications
+		WHERE wishlist_id = $1 AND offer_key = $2 AND sent_at > $3
+		ORDER BY sent_at DESC
+		LIMIT 1
+	`
+
+	var n models.Notification
+	err := r.db.QueryRow(query, wishlistID, offerKey, since).Scan(
+		&n.ID,
+		&n.TelegramID,
+		&n.WishlistID,
+		&n.OfferID,
+		&n.OfferKey,
+		&n.ProductName,
+		&n.Source,
+		&n.Price,
+		&n.DiscountPercentage,
+		&n.CashbackPercentage,
+		&n.SentAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last notification: %w", err)
+	}
+
+	return &n, nil
+}
+
+// SaveNotification records a sent notification
+func (r *NotificationRepository) SaveNotification(n *models.Notification) error {
+	query := `
+		INSERT INTO notifications (telegram_id, wishlist_id, offer_id, offer_key, product_name, source, price,
+			discount_percentage, cashback_percentage, sent_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+
+	err := r.db.QueryRow(
+		query,
+		n.TelegramID,
+		n.WishlistID,
+		n.OfferID,
+		n.OfferKey,
+		n.ProductName,
+		n.Source,
+		n.Price,
+		n.DiscountPercentage,
+		n.CashbackPercentage,
+		n.SentAt,
+	).Scan(&n.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+
+	return nil
+}
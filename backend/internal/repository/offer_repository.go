@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+type OfferRepository struct {
+	db *sql.DB
+}
+
+func NewOfferRepository(db *sql.DB) *OfferRepository {
+	return &OfferRepository{db: db}
+}
+
+// UpsertOffer stores the offer under its canonical key, updating the existing
+// row when the offer was seen before. It returns the price event recorded for
+// a new offer or a price/cashback change, or nil when nothing changed. Repeated
+// reports an unchanged offer that was already received on the same day.
+func (r *OfferRepository) UpsertOffer(offer *models.Offer) (*models.OfferPriceEvent, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO offers (offer_key, external_id, seller, product_name, price, original_price,
+			discount_percentage, cashback_percentage, url, image_url, source, first_seen_at, received_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11, $12, $12)
+		ON CONFLICT (offer_key) DO NOTHING
+		RETURNING id
+	`,
+		offer.Key,
+		offer.ExternalID,
+		offer.Seller,
+		offer.ProductName,
+		offer.Price,
+		offer.OriginalPrice,
+		offer.DiscountPercentage,
+		offer.CashbackPercentage,
+		offer.URL,
+		offer.ImageURL,
+		offer.Source,
+		offer.ReceivedAt,
+	).Scan(&offer.ID)
+
+	var previousPrice *float64
+	switch {
+	case err == nil:
+		// First time this offer is seen
+	case err == sql.ErrNoRows:
+		var price, originalPrice float64
+		var cashback int
+		var lastReceivedAt sql.NullTime
+		err = tx.QueryRow(`
+			SELECT id, COALESCE(price, 0), COALESCE(original_price, 0), COALESCE(cashback_percentage, 0), received_at
+			FROM offers
+			WHERE offer_key = $1
+			FOR UPDATE
+		`, offer.Key).Scan(&offer.ID, &price, &originalPrice, &cashback, &lastReceivedAt)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get offer %s: %w", offer.Key, err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE offers
+			SET product_name = $2, seller = NULLIF($3, ''), price = $4, original_price = $5,
+			    discount_percentage = $6, cashback_percentage = $7,
+			    url = COALESCE(NULLIF($8, ''), url), image_url = COALESCE(NULLIF($9, ''), image_url),
+			    received_at = $10
+			WHERE id = $1
+		`,
+			offer.ID,
+			offer.ProductName,
+			offer.Seller,
+			offer.Price,
+			offer.OriginalPrice,
+			offer.DiscountPercentage,
+			offer.CashbackPercentage,
+			offer.URL,
+			offer.ImageURL,
+			offer.ReceivedAt,
+		)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update offer %d: %w", offer.ID, err)
+		}
+
+		if sameCents(price, offer.Price) && sameCents(originalPrice, offer.OriginalPrice) && cashback == offer.CashbackPercentage {
+			repeated := lastReceivedAt.Valid && sameDay(lastReceivedAt.Time, offer.ReceivedAt)
+			return nil, repeated, tx.Commit()
+		}
+		previousPrice = &price
+	default:
+		return nil, false, fmt.Errorf("failed to save offer: %w", err)
+	}
+
+	event := &models.OfferPriceEvent{
+		OfferID:            offer.ID,
+		Price:              offer.Price,
+		PreviousPrice:      previousPrice,
+		OriginalPrice:      offer.OriginalPrice,
+		DiscountPercentage: offer.DiscountPercentage,
+		CashbackPercentage: offer.CashbackPercentage,
+		RecordedAt:         offer.ReceivedAt,
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO offer_price_events (offer_id, price, previous_price, original_price,
+			discount_percentage, cashback_percentage, recorded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`,
+		event.OfferID,
+		event.Price,
+		event.PreviousPrice,
+		event.OriginalPrice,
+		event.DiscountPercentage,
+		event.CashbackPercentage,
+		event.RecordedAt,
+	).Scan(&event.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record price event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit offer: %w", err)
+	}
+
+	return event, false, nil
+}
+
+// GetOfferByID retrieves a stored offer from the database
+func (r *OfferRepository) GetOfferByID(id int) (*models.Offer, error) {
+	query := `
+		SELECT id, COALESCE(offer_key, ''), COALESCE(external_id, ''), COALESCE(seller, ''), product_name,
+		       COALESCE(price, 0), COALESCE(original_price, 0),
+		       COALESCE(discount_percentage, 0), COALESCE(cashback_percentage, 0),
+		       COALESCE(url, ''), COALESCE(image_url, ''), COALESCE(source, ''), received_at
+		FROM offers
+		WHERE id = $1
+	`
+
+	var offer models.Offer
+	err := r.db.QueryRow(query, id).Scan(
+		&offer.ID,
+		&offer.Key,
+		&offer.ExternalID,
+		&offer.Seller,
+		&offer.ProductName,
+		&offer.Price,
+		&offer.OriginalPrice,
+		&offer.DiscountPercentage,
+		&offer.CashbackPercentage,
+		&offer.URL,
+		&offer.ImageURL,
+		&offer.Source,
+		&offer.ReceivedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offer %d: %w", id, err)
+	}
+
+	return &offer, nil
+}
+
+// GetOfferNamesBetween retrieves the id and product name of the offers with a
+// price event recorded in a time range
+func (r *OfferRepository) GetOfferNamesBetween(ctx context.Context, from, to time.Time) ([]models.Offer, error) {
+	query := `
+		SELECT o.id, o.product_name
+		FROM offers o
+		WHERE EXISTS (
+			SELECT 1 FROM offer_price_events e
+			WHERE e.offer_id = o.id AND e.recorded_at >= $1 AND e.recorded_at < $2
+		)
+		ORDER BY o.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query offer names: %w", err)
+	}
+	defer rows.Close()
+
+	var offers []models.Offer
+	for rows.Next() {
+		var offer models.Offer
+		if err := rows.Scan(&offer.ID, &offer.ProductName); err != nil {
+			return nil, fmt.Errorf("failed to scan offer name: %w", err)
+		}
+		offers = append(offers, offer)
+	}
+
+	return offers, rows.Err()
+}
+
+// GetOffersBetween retrieves the state of the given offers at every price event
+// recorded in a time range, oldest first, up to limit events. ReceivedAt is the
+// time of the event.
+func (r *OfferRepository) GetOffersBetween(ctx context.Context, offerIDs []int, from, to time.Time, limit int) ([]models.Offer, error) {
+	query := `
+		SELECT o.id, COALESCE(o.offer_key, ''), COALESCE(o.external_id, ''), COALESCE(o.seller, ''), o.product_name,
+		       COALESCE(e.price, 0), COALESCE(e.original_price, 0),
+		       COALESCE(e.discount_percentage, 0), COALESCE(e.cashback_percentage, 0),
+		       COALESCE(o.url, ''), COALESCE(o.image_url, ''), COALESCE(o.source, ''), e.recorded_at
+		FROM offer_price_events e
+		JOIN offers o ON o.id = e.offer_id
+		WHERE e.offer_id = ANY($1) AND e.recorded_at >= $2 AND e.recorded_at < $3
+		ORDER BY e.recorded_at, e.id
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(offerIDs), from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query offers: %w", err)
+	}
+	defer rows.Close()
+
+	var offers []models.Offer
+	for rows.Next() {
+		var offer models.Offer
+		err := rows.Scan(
+			&offer.ID,
+			&offer.Key,
+			&offer.ExternalID,
+			&offer.Seller,
+			&offer.ProductName,
+			&offer.Price,
+			&offer.OriginalPrice,
+			&offer.DiscountPercentage,
+			&offer.CashbackPercentage,
+			&offer.URL,
+			&offer.ImageURL,
+			&offer.Source,
+			&offer.ReceivedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan offer: %w", err)
+		}
+		offers = append(offers, offer)
+	}
+
+	return offers, rows.Err()
+}
+
+// sameDay reports whether two times fall on the same day in the location of b
+func sameDay(a, b time.Time) bool {
+	return a.In(b.Location()).Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+// sameCents compares prices stored with two decimal places
+func sameCents(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
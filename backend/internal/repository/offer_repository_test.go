@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/normalizer"
+)
+
+var storedOfferColumns = []string{"id", "price", "original_price", "cashback_percentage", "received_at"}
+
+func newOfferRepositoryMock(t *testing.T) (*OfferRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return NewOfferRepository(db), mock
+}
+
+// keyPrefix matches an offer key argument starting with a prefix
+type keyPrefix string
+
+func (p keyPrefix) Match(v driver.Value) bool {
+	key, ok := v.(string)
+	return ok && strings.HasPrefix(key, string(p))
+}
+
+func TestUpsertOfferNew(t *testing.T) {
+	repo, mock := newOfferRepositoryMock(t)
+	receivedAt := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	offer := &models.Offer{
+		Key: "kabum:123", ExternalID: "123", ProductName: "Geladeira Consul", Price: 2999, OriginalPrice: 3999,
+		DiscountPercentage: 25, CashbackPercentage: 5, Source: "kabum", ReceivedAt: receivedAt,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO offers").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("INSERT INTO offer_price_events").
+		WithArgs(7, 2999.0, nil, 3999.0, 25, 5, receivedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	event, repeated, err := repo.UpsertOffer(offer)
+	if err != nil {
+		t.Fatalf("UpsertOffer() error = %v", err)
+	}
+	if repeated {
+		t.Error("UpsertOffer() repeated = true for a new offer")
+	}
+	if event == nil || event.ID != 1 || event.OfferID != 7 || event.PreviousPrice != nil {
+		t.Errorf("UpsertOffer() event = %+v, want event 1 of offer 7 without previous price", event)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpsertOfferExisting(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 00:30 in São Paulo is 03:30 UTC, so 23:30 of the day before is the
+	// same day in UTC but not in São Paulo
+	receivedAt := time.Date(2026, 10, 17, 0, 30, 0, 0, saoPaulo)
+
+	tests := []struct {
+		name           string
+		price          float64
+		originalPrice  float64
+		cashback       int
+		lastReceivedAt interface{}
+		wantEvent      bool
+		wantRepeated   bool
+	}{
+		{"price change", 3199, 3999, 5, receivedAt.Add(-time.Hour).UTC(), true, false},
+		{"original price change", 2999, 4299, 5, receivedAt.Add(-time.Hour).UTC(), true, false},
+		{"cashback change", 2999, 3999, 0, receivedAt.Add(-time.Hour).UTC(), true, false},
+		{"change below a cent", 2999.004, 3999, 5, receivedAt.Add(-10 * time.Minute).UTC(), false, true},
+		{"unchanged on the same day", 2999, 3999, 5, receivedAt.Add(-10 * time.Minute).UTC(), false, true},
+		{"unchanged on the day before", 2999, 3999, 5, receivedAt.Add(-time.Hour).UTC(), false, false},
+		{"unchanged and never received", 2999, 3999, 5, nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newOfferRepositoryMock(t)
+			offer := &models.Offer{
+				Key: "kabum:123", ExternalID: "123", ProductName: "Geladeira Consul", Price: 2999, OriginalPrice: 3999,
+				DiscountPercentage: 25, CashbackPercentage: 5, Source: "kabum", ReceivedAt: receivedAt,
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("INSERT INTO offers").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectQuery("SELECT id, .* FROM offers WHERE offer_key = \\$1 FOR UPDATE").
+				WithArgs("kabum:123").
+				WillReturnRows(sqlmock.NewRows(storedOfferColumns).
+					AddRow(7, tt.price, tt.originalPrice, tt.cashback, tt.lastReceivedAt))
+			mock.ExpectExec("UPDATE offers").
+				WithArgs(7, "Geladeira Consul", "", 2999.0, 3999.0, 25, 5, "", "", receivedAt).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			if tt.wantEvent {
+				mock.ExpectQuery("INSERT INTO offer_price_events").
+					WithArgs(7, 2999.0, tt.price, 3999.0, 25, 5, receivedAt).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			}
+			mock.ExpectCommit()
+
+			event, repeated, err := repo.UpsertOffer(offer)
+			if err != nil {
+				t.Fatalf("UpsertOffer() error = %v", err)
+			}
+			if repeated != tt.wantRepeated {
+				t.Errorf("UpsertOffer() repeated = %v, want %v", repeated, tt.wantRepeated)
+			}
+			if !tt.wantEvent {
+				if event != nil {
+					t.Errorf("UpsertOffer() event = %+v, want none", event)
+				}
+			} else if event == nil || event.ID != 2 || event.PreviousPrice == nil || *event.PreviousPrice != tt.price {
+				t.Errorf("UpsertOffer() event = %+v, want event 2 with previous price %.2f", event, tt.price)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestUpsertOfferFingerprintKey(t *testing.T) {
+	repo, mock := newOfferRepositoryMock(t)
+	receivedAt := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	// Sources without an id are keyed by the normalized title and seller, so
+	// the second receipt updates the offer stored by the first
+	first := &models.Offer{ProductName: "Geladeira Consul 400L", Seller: "Loja X", Price: 2999, Source: "SNS", ReceivedAt: receivedAt}
+	second := &models.Offer{ProductName: "  geladeira   consul 400l ", Seller: "LOJA X", Price: 2999, Source: "sns", ReceivedAt: receivedAt}
+	for _, offer := range []*models.Offer{first, second} {
+		if _, err := normalizer.NewOfferNormalizer().Normalize(offer); err != nil {
+			t.Fatalf("Normalize() error = %v", err)
+		}
+	}
+	if first.Key != second.Key {
+		t.Fatalf("keys = %q and %q, want the same key", first.Key, second.Key)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO offers").
+		WithArgs(keyPrefix("sns:fp:"), "", "Loja X", "Geladeira Consul 400L", 2999.0, 0.0, 0, 0, "", "", "SNS", receivedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("INSERT INTO offer_price_events").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO offers").
+		WithArgs(first.Key, "", "LOJA X", "geladeira   consul 400l", 2999.0, 0.0, 0, 0, "", "", "sns", receivedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT id, .* FROM offers WHERE offer_key = \\$1 FOR UPDATE").
+		WithArgs(first.Key).
+		WillReturnRows(sqlmock.NewRows(storedOfferColumns).AddRow(7, 2999.0, 0.0, 0, receivedAt))
+	mock.ExpectExec("UPDATE offers").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if _, _, err := repo.UpsertOffer(first); err != nil {
+		t.Fatalf("UpsertOffer() error = %v", err)
+	}
+	event, repeated, err := repo.UpsertOffer(second)
+	if err != nil {
+		t.Fatalf("UpsertOffer() error = %v", err)
+	}
+	if event != nil || !repeated {
+		t.Errorf("UpsertOffer() = %+v, %v, want a repeated receipt without event", event, repeated)
+	}
+	if second.ID != 7 {
+		t.Errorf("offer id = %d, want 7", second.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
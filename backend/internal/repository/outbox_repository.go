@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+)
+
+// outboxLockKey is the advisory lock held while publishing, so only one
+// backend replica publishes at a time and events keep their order
+const outboxLockKey = 7_001_017
+
+// OutboxRepository stores wishlist events until they are published to Kafka
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// AddEvent writes an event in the transaction of the wishlist change, so the
+// event is published if and only if the change is committed. The trace
+// context of ctx is stored so the published event continues the trace.
+func (r *OutboxRepository) AddEvent(ctx context.Context, tx *sql.Tx, event *models.WishlistEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	traceContext, err := json.Marshal(tracing.Inject(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace context: %w", err)
+	}
+
+	query := `
+		INSERT INTO wishlist_outbox (event_type, wishlist_id, telegram_id, payload, trace_context, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	// JSONB parameters must be sent as text
+	_, err = tx.Exec(query, event.Type, event.WishlistID, event.TelegramID, string(payload), string(traceContext), event.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to add %s event for wishlist %d: %w", event.Type, event.WishlistID, err)
+	}
+
+	return nil
+}
+
+// PublishPending passes up to limit pending events to publish, oldest first,
+// and marks the published ones as sent. It stops at the first failure, which
+// is recorded on the event, so later events are not published before it.
+// It returns the number of events published; 0 without error when another
+// replica is publishing.
+func (r *OutboxRepository) PublishPending(limit int, publish func(models.OutboxEvent) error) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRow(`SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, event_type, telegram_id, payload, trace_context, attempts, created_at
+		FROM wishlist_outbox
+		WHERE sent_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending events: %w", err)
+	}
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		var traceContext []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.TelegramID, &event.Payload, &traceContext, &event.Attempts, &event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		// Events without a trace context start a new trace when published
+		if err := json.Unmarshal(traceContext, &event.TraceContext); err != nil {
+			log.Printf("Invalid trace context for outbox event %d: %v", event.ID, err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get pending events: %w", err)
+	}
+
+	published := 0
+	var publishErr error
+	for _, event := range events {
+		if publishErr = publish(event); publishErr != nil {
+			_, err := tx.Exec(`
+				UPDATE wishlist_outbox
+				SET attempts = attempts + 1, last_error = $2
+				WHERE id = $1
+			`, event.ID, publishErr.Error())
+			if err != nil {
+				return 0, fmt.Errorf("failed to record failure of event %d: %w", event.ID, err)
+			}
+			break
+		}
+
+		if _, err := tx.Exec(`UPDATE wishlist_outbox SET sent_at = NOW() WHERE id = $1`, event.ID); err != nil {
+			return 0, fmt.Errorf("failed to mark event %d as sent: %w", event.ID, err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox: %w", err)
+	}
+
+	if publishErr != nil {
+		return published, fmt.Errorf("failed to publish event: %w", publishErr)
+	}
+
+	return published, nil
+}
+
+// DeleteSentBefore deletes events published before the given time
+func (r *OutboxRepository) DeleteSentBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM wishlist_outbox WHERE sent_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent events: %w", err)
+	}
+
+	return result.RowsAffected()
+}
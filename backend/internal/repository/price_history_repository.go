@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/models"
+)
+
+type PriceHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPriceHistoryRepository(db *sql.DB) *PriceHistoryRepository {
+	return &PriceHistoryRepository{db: db}
+}
+
+// Record adds a price seen on the given day to the daily range of each product
+// key. Days are dates: only the year, month and day of day are stored.
+func (r *PriceHistoryRepository) Record(productKeys []string, day time.Time, price float64) error {
+	query := `
+		INSERT INTO price_history (product_key, day, min_price, max_price, samples, updated_at)
+		VALUES ($1, $2, $3, $3, 1, NOW())
+		ON CONFLICT (product_key, day)
+		DO UPDATE SET min_price = LEAST(price_history.min_price, EXCLUDED.min_price),
+		              max_price = GREATEST(price_history.max_price, EXCLUDED.max_price),
+		              samples = price_history.samples + 1,
+		              updated_at = NOW()
+	`
+
+	for _, key := range productKeys {
+		if _, err := r.db.Exec(query, key, day.Format("2006-01-02"), price); err != nil {
+			return fmt.Errorf("failed to record price for %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// GetDailyPrices retrieves the daily prices of a product since the date of the
+// given day, oldest first. Days are read as midnight UTC.
+func (r *PriceHistoryRepository) GetDailyPrices(productKey string, since time.Time) ([]models.DailyPrice, error) {
+	query := `
+		SELECT product_key, day, min_price, max_price, samples
+		FROM price_history
+		WHERE product_key = $1 AND day >= $2
+		ORDER BY day
+	`
+
+	rows, err := r.db.Query(query, productKey, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []models.DailyPrice
+	for rows.Next() {
+		var p models.DailyPrice
+		if err := rows.Scan(&p.ProductKey, &p.Day, &p.MinPrice, &p.MaxPrice, &p.Samples); err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %w", err)
+		}
+		prices = append(prices, p)
+	}
+
+	return prices, rows.Err()
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// SetHideSuspiciousOffers sets whether the user receives offers with a
+// suspicious reference price, in the transaction of the command that sets it
+func (r *UserRepository) SetHideSuspiciousOffers(tx *sql.Tx, telegramID int64, hide bool) error {
+	query := `
+		INSERT INTO users (telegram_id, hide_suspicious_offers)
+		VALUES ($1, $2)
+		ON CONFLICT (telegram_id)
+		DO UPDATE SET hide_suspicious_offers = $2, updated_at = NOW()
+	`
+
+	if _, err := tx.Exec(query, telegramID, hide); err != nil {
+		return fmt.Errorf("failed to update user %d: %w", telegramID, err)
+	}
+
+	return nil
+}
+
+// HidesSuspiciousOffers reports whether the user opted out of offers with a
+// suspicious reference price
+func (r *UserRepository) HidesSuspiciousOffers(telegramID int64) (bool, error) {
+	query := `
+		SELECT COALESCE(hide_suspicious_offers, false)
+		FROM users
+		WHERE telegram_id = $1
+	`
+
+	var hide bool
+	err := r.db.QueryRow(query, telegramID).Scan(&hide)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get user %d: %w", telegramID, err)
+	}
+
+	return hide, nil
+}
@@ -0,0 +1,350 @@
+package similarity
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultThreshold is the minimum share of wishlist words that must be found in
+// an offer for the names to match
+const DefaultThreshold = 0.5
+
+// Match kinds of a TokenMatch
+const (
+	KindExact    = "exact"    // same word
+	KindContains = "contains" // one word contains the other ("phone" in "iphone")
+	KindJoined   = "joined"   // word equals two offer words written together ("frost free")
+	KindTypo     = "typo"     // words within the allowed edit distance
+)
+
+// TokenMatch describes how a wishlist word was found in an offer
+type TokenMatch struct {
+	WishlistToken string `json:"wishlist_token"`
+	OfferToken    string `json:"offer_token,omitempty"`
+	Kind          string `json:"kind,omitempty"`
+	Distance      int    `json:"distance,omitempty"`
+}
+
+// Result is the step by step comparison of an offer name with a wishlist name
+type Result struct {
+	OfferTokens    []string     `json:"offer_tokens"`
+	WishlistTokens []string     `json:"wishlist_tokens"`
+	Contained      bool         `json:"contained"` // one normalized name contains the other
+	Matches        []TokenMatch `json:"matches"`   // one entry per wishlist word, matched or not
+	MatchedCount   int          `json:"matched_count"`
+	Score          float64      `json:"score"`
+	Threshold      float64      `json:"threshold"`
+	Matched        bool         `json:"matched"`
+}
+
+// Compare compares an offer name with a wishlist name. Names match when one
+// normalized name contains the other or when the share of wishlist words found
+// in the offer reaches the threshold.
+func Compare(offerName, wishlistName string, threshold float64) Result {
+	offerTokens := Tokens(offerName)
+	wishlistTokens := Tokens(wishlistName)
+
+	result := Result{
+		OfferTokens:    offerTokens,
+		WishlistTokens: wishlistTokens,
+		Threshold:      threshold,
+	}
+
+	offerJoined := strings.Join(offerTokens, " ")
+	wishlistJoined := strings.Join(wishlistTokens, " ")
+	result.Contained = strings.Contains(offerJoined, wishlistJoined) || strings.Contains(wishlistJoined, offerJoined)
+
+	terms := OfferTerms(offerTokens)
+	for _, wishlistToken := range wishlistTokens {
+		match := matchToken(wishlistToken, offerTokens, terms[len(offerTokens):])
+		if match.Kind != "" {
+			result.MatchedCount++
+		}
+		result.Matches = append(result.Matches, match)
+	}
+
+	switch {
+	case result.Contained:
+		result.Score = 1
+	case len(wishlistTokens) > 0:
+		result.Score = float64(result.MatchedCount) / float64(len(wishlistTokens))
+	}
+
+	result.Matched = result.Contained || (len(wishlistTokens) > 0 && result.Score >= threshold)
+	return result
+}
+
+// Matches reports whether an offer name matches a wishlist name
+func Matches(offerName, wishlistName string, threshold float64) bool {
+	return Compare(offerName, wishlistName, threshold).Matched
+}
+
+// ContainsTerm reports whether the normalized name contains the normalized term
+func ContainsTerm(name, term string) bool {
+	normalizedTerm := strings.Join(Tokens(term), " ")
+	if normalizedTerm == "" {
+		return false
+	}
+	return strings.Contains(strings.Join(Tokens(name), " "), normalizedTerm)
+}
+
+// matchToken finds the best offer word for a wishlist word
+func matchToken(wishlistToken string, offerTokens, joinedTokens []string) TokenMatch {
+	match := TokenMatch{WishlistToken: wishlistToken}
+
+	for _, offerToken := range offerTokens {
+		if offerToken == wishlistToken {
+			match.OfferToken, match.Kind = offerToken, KindExact
+			return match
+		}
+	}
+
+	for _, joined := range joinedTokens {
+		if joined == wishlistToken {
+			match.OfferToken, match.Kind = joined, KindJoined
+			return match
+		}
+	}
+
+	for _, offerToken := range offerTokens {
+		if strings.Contains(offerToken, wishlistToken) || strings.Contains(wishlistToken, offerToken) {
+			match.OfferToken, match.Kind = offerToken, KindContains
+			return match
+		}
+	}
+
+	maxEdits := MaxEdits(wishlistToken)
+	if maxEdits == 0 {
+		return match
+	}
+
+	best := maxEdits + 1
+	for _, tokens := range [][]string{offerTokens, joinedTokens} {
+		for _, offerToken := range tokens {
+			if MaxEdits(offerToken) == 0 {
+				continue
+			}
+			if d := editDistance(wishlistToken, offerToken, maxEdits); d < best {
+				best = d
+				match.OfferToken, match.Kind, match.Distance = offerToken, KindTypo, d
+			}
+		}
+	}
+
+	return match
+}
+
+// Tokens normalizes a product name into comparable words: lowercase, accents
+// folded, punctuation removed, known compounds split, stopwords removed and
+// plurals stemmed
+func Tokens(name string) []string {
+	var tokens []string
+
+	for _, field := range strings.Fields(Normalize(name)) {
+		if split, ok := compounds[field]; ok {
+			for _, part := range split {
+				tokens = append(tokens, stem(part))
+			}
+			continue
+		}
+		if stopwords[field] {
+			continue
+		}
+		tokens = append(tokens, stem(field))
+	}
+
+	return tokens
+}
+
+// OfferTerms returns the offer words followed by every pair of adjacent words
+// written together, so "frost free" in an offer also matches "frostfree"
+func OfferTerms(offerTokens []string) []string {
+	terms := make([]string, 0, 2*len(offerTokens))
+	terms = append(terms, offerTokens...)
+	for i := 0; i+1 < len(offerTokens); i++ {
+		terms = append(terms, offerTokens[i]+offerTokens[i+1])
+	}
+	return terms
+}
+
+// Normalize lowercases the text, folds accents and replaces punctuation with
+// spaces. Hyphens between letters are dropped so "Wi-Fi" becomes "wifi".
+func Normalize(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+
+	runes := []rune(strings.ToLower(text))
+	for i, r := range runes {
+		if folded, ok := accents[r]; ok {
+			r = folded
+		}
+
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '-' && i > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1]):
+			// Join hyphenated words
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	return b.String()
+}
+
+// MaxEdits returns how many typos are tolerated in a word. Short words and
+// words with digits (models, capacities) must match exactly.
+func MaxEdits(token string) int {
+	if strings.IndexFunc(token, unicode.IsDigit) >= 0 {
+		return 0
+	}
+
+	switch n := utf8.RuneCountInString(token); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Deletes returns the word and every variant with up to maxEdits runes
+// removed. Two words within edit distance k always share a variant with up to
+// k deletions, which lets an index find typo candidates without comparing
+// every pair of words.
+func Deletes(token string, maxEdits int) []string {
+	seen := map[string]bool{token: true}
+	variants := []string{token}
+	current := []string{token}
+
+	for edit := 0; edit < maxEdits; edit++ {
+		var next []string
+		for _, variant := range current {
+			runes := []rune(variant)
+			if len(runes) <= 1 {
+				continue
+			}
+			for i := range runes {
+				deleted := string(runes[:i]) + string(runes[i+1:])
+				if !seen[deleted] {
+					seen[deleted] = true
+					variants = append(variants, deleted)
+					next = append(next, deleted)
+				}
+			}
+		}
+		current = next
+	}
+
+	return variants
+}
+
+// editDistance returns the Levenshtein distance between a and b, or max+1 when
+// it is larger than max
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+
+	if prev[len(rb)] > max {
+		return max + 1
+	}
+	return prev[len(rb)]
+}
+
+// stem removes Portuguese plural endings so "geladeiras" matches "geladeira"
+func stem(token string) string {
+	if utf8.RuneCountInString(token) <= 3 || strings.IndexFunc(token, unicode.IsDigit) >= 0 {
+		return token
+	}
+
+	switch {
+	case strings.HasSuffix(token, "oes"), strings.HasSuffix(token, "aes"):
+		return token[:len(token)-3] + "ao"
+	case strings.HasSuffix(token, "ais"):
+		return token[:len(token)-3] + "al"
+	case strings.HasSuffix(token, "eis"):
+		return token[:len(token)-3] + "el"
+	case strings.HasSuffix(token, "ns"):
+		return token[:len(token)-2] + "m"
+	case strings.HasSuffix(token, "res"), strings.HasSuffix(token, "zes"):
+		return token[:len(token)-2]
+	case strings.HasSuffix(token, "s") && !strings.HasSuffix(token, "ss"):
+		return token[:len(token)-1]
+	}
+
+	return token
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+var accents = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+// stopwords are Portuguese words that carry no meaning in product names
+var stopwords = map[string]bool{
+	"a": true, "o": true, "as": true, "os": true, "e": true,
+	"de": true, "da": true, "do": true, "das": true, "dos": true,
+	"em": true, "na": true, "no": true, "nas": true, "nos": true,
+	"um": true, "uma": true, "com": true, "para": true, "pra": true, "por": true,
+}
+
+// compounds are product words commonly written together that are split into
+// their parts, so both spellings produce the same words
+var compounds = map[string][]string{
+	"frostfree":  {"frost", "free"},
+	"smarttv":    {"smart", "tv"},
+	"airfryer":   {"air", "fryer"},
+	"smartwatch": {"smart", "watch"},
+	"homeoffice": {"home", "office"},
+	"powerbank":  {"power", "bank"},
+}
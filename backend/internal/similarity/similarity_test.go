@@ -0,0 +1,183 @@
+package similarity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Câmera Fotográfica", "camera fotografica"},
+		{"FOGÃO 5 Bocas", "fogao 5 bocas"},
+		{"Ação/Aventura", "acao aventura"},
+		{"Wi-Fi", "wifi"},
+		{"Frost-Free", "frostfree"},
+		{"Galaxy S23-Ultra", "galaxy s23 ultra"},
+		{"-promo-", " promo "},
+		{"iPhone 15 (128GB)", "iphone 15  128gb "},
+		{"Pingüim Ñandu", "pinguim nandu"},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.text); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"geladeiras", "geladeira"},
+		{"fogoes", "fogao"},
+		{"paes", "pao"},
+		{"animais", "animal"},
+		{"papeis", "papel"},
+		{"bombons", "bombom"},
+		{"televisores", "televisor"},
+		{"luzes", "luz"},
+		{"tenis", "teni"},
+		{"vidross", "vidross"},
+		{"gas", "gas"},
+		{"128gbs", "128gbs"},
+		{"tv", "tv"},
+	}
+
+	for _, tt := range tests {
+		if got := stem(tt.token); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestMaxEdits(t *testing.T) {
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{"tv", 0},
+		{"pro", 0},
+		{"fone", 1},
+		{"iphone", 1},
+		{"geladeira", 2},
+		{"camera", 1},
+		{"câmera", 1},
+		{"128gb", 0},
+		{"galaxys23", 0},
+	}
+
+	for _, tt := range tests {
+		if got := MaxEdits(tt.token); got != tt.want {
+			t.Errorf("MaxEdits(%q) = %d, want %d", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Geladeiras Frost Free", []string{"geladeira", "frost", "free"}},
+		{"Geladeira FrostFree", []string{"geladeira", "frost", "free"}},
+		{"Fritadeira de Ar Airfryer", []string{"fritadeira", "ar", "air", "fryer"}},
+		{"Kit com 2 Fogões", []string{"kit", "2", "fogao"}},
+		{"de para com", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Tokens(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokens(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		offer    string
+		wishlist string
+		want     bool
+	}{
+		{"exact", "iPhone 15 128GB", "iPhone 15", true},
+		{"accents", "Câmera Canon EOS", "camera canon", true},
+		{"accents in the wishlist", "Fogao 5 Bocas Atlas", "fogão atlas", true},
+		{"plural", "Kit 2 Geladeiras Brastemp", "geladeira brastemp", true},
+		{"compound written together", "Geladeira Brastemp Frost Free", "geladeira frostfree", true},
+		{"compound written apart", "Geladeira Brastemp Frostfree", "geladeira frost free", true},
+		{"joined words", "Smart TV Samsung 50", "smarttv samsung", true},
+		{"one typo", "Notebook Dell Inspiron", "notebok dell", true},
+		{"two typos in a long word", "Geladeira Consul", "gelaedira consul", true},
+		{"too many typos", "Geladeira Consul", "glaedira", false},
+		{"typo in a short word", "Kit Pia Inox", "pio", false},
+		{"typo in a model number", "Galaxy S23", "s24", false},
+		{"contained word", "iPhone 15", "phone", true},
+		{"below the threshold", "Samsung Galaxy S23", "iphone 15 pro max", false},
+		{"half of the words", "Samsung Galaxy S23", "galaxy tab", true},
+		{"unrelated", "Air Fryer Mondial", "geladeira", false},
+		{"stopwords only", "Smart TV", "de para", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Compare(tt.offer, tt.wishlist, DefaultThreshold)
+			if result.Matched != tt.want {
+				t.Errorf("Compare(%q, %q) matched = %t, want %t (score %.2f, matches %+v)",
+					tt.offer, tt.wishlist, result.Matched, tt.want, result.Score, result.Matches)
+			}
+			if Matches(tt.offer, tt.wishlist, DefaultThreshold) != result.Matched {
+				t.Errorf("Matches(%q, %q) disagrees with Compare", tt.offer, tt.wishlist)
+			}
+		})
+	}
+}
+
+func TestCompareExplainsEachWord(t *testing.T) {
+	result := Compare("Geladeira Consul Frost Free", "geladera frostfree brastemp", DefaultThreshold)
+
+	want := []TokenMatch{
+		{WishlistToken: "geladera", OfferToken: "geladeira", Kind: KindTypo, Distance: 1},
+		{WishlistToken: "frost", OfferToken: "frost", Kind: KindExact},
+		{WishlistToken: "free", OfferToken: "free", Kind: KindExact},
+		{WishlistToken: "brastemp"},
+	}
+	if !reflect.DeepEqual(result.Matches, want) {
+		t.Errorf("Matches = %+v, want %+v", result.Matches, want)
+	}
+	if result.MatchedCount != 3 || result.Score != 0.75 || !result.Matched {
+		t.Errorf("MatchedCount = %d, Score = %.2f, Matched = %t, want 3, 0.75, true", result.MatchedCount, result.Score, result.Matched)
+	}
+}
+
+func TestDeletesShareAVariantWithinEditDistance(t *testing.T) {
+	pairs := [][2]string{
+		{"geladeira", "gelaedira"},
+		{"geladeira", "geladera"},
+		{"notebook", "notebok"},
+		{"camera", "camara"},
+	}
+
+	for _, pair := range pairs {
+		k := MaxEdits(pair[0])
+		if d := editDistance(pair[0], pair[1], k); d > k {
+			t.Fatalf("editDistance(%q, %q) = %d, want at most %d", pair[0], pair[1], d, k)
+		}
+
+		variants := make(map[string]bool)
+		for _, v := range Deletes(pair[0], k) {
+			variants[v] = true
+		}
+		shared := false
+		for _, v := range Deletes(pair[1], k) {
+			shared = shared || variants[v]
+		}
+		if !shared {
+			t.Errorf("%q and %q share no deletion variant", pair[0], pair[1])
+		}
+	}
+}
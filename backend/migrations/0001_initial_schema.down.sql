@@ -0,0 +1,12 @@
+-- Drop the whole schema, data included
+DROP TABLE IF EXISTS import_templates;
+DROP TABLE IF EXISTS message_templates;
+DROP TABLE IF EXISTS wishlist_outbox;
+DROP TABLE IF EXISTS processed_commands;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS price_history;
+DROP TABLE IF EXISTS offer_price_events;
+DROP TABLE IF EXISTS offers;
+DROP TABLE IF EXISTS wishlists;
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS update_updated_at_column();
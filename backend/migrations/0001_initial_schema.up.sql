@@ -1,115 +1,192 @@
--- Create database schema for Telegram Offer Bot
-
--- Users table
-CREATE TABLE IF NOT EXISTS users (
-    telegram_id BIGINT PRIMARY KEY,
-    username VARCHAR(255),
-    first_name VARCHAR(255),
-    last_name VARCHAR(255),
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
-);
-
--- Wishlists table
-CREATE TABLE IF NOT EXISTS wishlists (
-    id SERIAL PRIMARY KEY,
-    telegram_id BIGINT NOT NULL REFERENCES users(telegram_id) ON DELETE CASCADE,
-    product_name VARCHAR(500) NOT NULL,
-    target_price DECIMAL(10,2),
-    discount_percentage INT,
-    created_at TIMESTAMP DEFAULT NOW(),
-    CONSTRAINT check_target CHECK (
-        (target_price IS NOT NULL AND discount_percentage IS NULL) OR
-        (target_price IS NULL AND discount_percentage IS NOT NULL)
-    )
-);
-
--- Offers table (for tracking and analytics)
-CREATE TABLE IF NOT EXISTS offers (
-    id SERIAL PRIMARY KEY,
-    product_name VARCHAR(500) NOT NULL,
-    price DECIMAL(10,2),
-    original_price DECIMAL(10,2),
-    discount_percentage INT,
-    cashback_percentage INT,
-    source VARCHAR(255),
-    received_at TIMESTAMP DEFAULT NOW()
-);
-
--- Notifications table (for tracking sent notifications)
-CREATE TABLE IF NOT EXISTS notifications (
-    id SERIAL PRIMARY KEY,
-    telegram_id BIGINT NOT NULL REFERENCES users(telegram_id) ON DELETE CASCADE,
-    wishlist_id INT REFERENCES wishlists(id) ON DELETE SET NULL,
-    offer_id INT REFERENCES offers(id) ON DELETE SET NULL,
-    sent_at TIMESTAMP DEFAULT NOW()
-);
-
--- Create indexes for better performance
-CREATE INDEX IF NOT EXISTS idx_wishlists_telegram_id ON wishlists(telegram_id);
-CREATE INDEX IF NOT EXISTS idx_offers_product_name ON offers(product_name);
-CREATE INDEX IF NOT EXISTS idx_notifications_telegram_id ON notifications(telegram_id);
-CREATE INDEX IF NOT EXISTS idx_notifications_sent_at ON notifications(sent_at);
-
--- Create updated_at trigger function
-CREATE OR REPLACE FUNCTION update_updated_at_column()
-RETURNS TRIGGER AS $$
-BEGIN
-    NEW.updated_at = NOW();
-    RETURN NEW;
-END;
-$$ language 'plpgsql';
-
--- Create trigger for users table
-CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
-    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-
--- Message templates table (for web dashboard)
-CREATE TABLE IF NOT EXISTS message_templates (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL UNIQUE,
-    product_model VARCHAR(255) NOT NULL,
-    
-    -- Structured fields for offer data
-    title_field VARCHAR(100) NOT NULL,              -- Campo para título do produto/oferta
-    description_field VARCHAR(100),                  -- Campo para descrição
-    price_field VARCHAR(100) NOT NULL,               -- Campo para preço
-    discount_field VARCHAR(100),                     -- Campo para desconto (opcional)
-    details_fields TEXT,                             -- Campos concatenados para busca (JSON array)
-    
-    -- Original schema for backward compatibility
-    message_schema JSONB NOT NULL,
-    
-    sns_topic_arn VARCHAR(500),
-    is_active BOOLEAN DEFAULT true,
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
-);
-
--- Create trigger for message_templates table
-CREATE TRIGGER update_message_templates_updated_at BEFORE UPDATE ON message_templates
-    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-
--- Create index for message templates
-CREATE INDEX IF NOT EXISTS idx_message_templates_active ON message_templates(is_active);
-CREATE INDEX IF NOT EXISTS idx_message_templates_product_model ON message_templates(product_model);
-
--- Import templates table (for S3 JSON imports)
-CREATE TABLE IF NOT EXISTS import_templates (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL UNIQUE,
-    s3_url TEXT NOT NULL,
-    mapping_schema JSONB NOT NULL,  -- Maps Offer fields to JSON paths, e.g. {"ProductName": "$.titulo", "Price": "$.price"}
-    is_active BOOLEAN DEFAULT true,
-    last_run_at TIMESTAMP,
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
-);
-
--- Create trigger for import_templates table
-CREATE TRIGGER update_import_templates_updated_at BEFORE UPDATE ON import_templates
-    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-
--- Create index for import templates
-CREATE INDEX IF NOT EXISTS idx_import_templates_active ON import_templates(is_active);
-
+-- Schema of the Telegram Offer Bot before versioned migrations. Databases
+-- created from init.sql are baselined at this version instead of running it.
+
+-- Users table
+CREATE TABLE IF NOT EXISTS users (
+    telegram_id BIGINT PRIMARY KEY,
+    username VARCHAR(255),
+    first_name VARCHAR(255),
+    last_name VARCHAR(255),
+    hide_suspicious_offers BOOLEAN DEFAULT false,  -- Skip offers with an inflated original price
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Wishlists table
+CREATE TABLE IF NOT EXISTS wishlists (
+    id SERIAL PRIMARY KEY,
+    telegram_id BIGINT NOT NULL REFERENCES users(telegram_id) ON DELETE CASCADE,
+    product_name VARCHAR(500) NOT NULL,
+    target_price DECIMAL(10,2),
+    discount_percentage INT,
+    min_price DECIMAL(10,2),
+    excluded_terms TEXT[],
+    min_cashback INT,
+    max_effective_price DECIMAL(10,2),
+    created_at TIMESTAMP DEFAULT NOW(),
+    -- At least one condition; conditions can be combined
+    CONSTRAINT check_target CHECK (
+        target_price IS NOT NULL OR discount_percentage IS NOT NULL OR
+        min_cashback IS NOT NULL OR max_effective_price IS NOT NULL
+    ),
+    CONSTRAINT check_price_range CHECK (
+        min_price IS NULL OR target_price IS NULL OR min_price <= target_price
+    )
+);
+
+-- Offers table (for tracking and analytics)
+CREATE TABLE IF NOT EXISTS offers (
+    id SERIAL PRIMARY KEY,
+    offer_key VARCHAR(255) UNIQUE,  -- Canonical identity: source + id at the source, or a title/seller fingerprint
+    external_id VARCHAR(255),
+    seller VARCHAR(255),
+    product_name VARCHAR(500) NOT NULL,
+    price DECIMAL(10,2),
+    original_price DECIMAL(10,2),
+    discount_percentage INT,
+    cashback_percentage INT,
+    url TEXT,
+    image_url TEXT,
+    source VARCHAR(255),
+    first_seen_at TIMESTAMP DEFAULT NOW(),
+    received_at TIMESTAMP DEFAULT NOW()  -- Last time the offer was received
+);
+
+-- Offer price events (one row when an offer is first seen and on every price or cashback change)
+CREATE TABLE IF NOT EXISTS offer_price_events (
+    id SERIAL PRIMARY KEY,
+    offer_id INT NOT NULL REFERENCES offers(id) ON DELETE CASCADE,
+    price DECIMAL(10,2),
+    previous_price DECIMAL(10,2),
+    original_price DECIMAL(10,2),
+    discount_percentage INT,
+    cashback_percentage INT,
+    recorded_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Daily price history keyed by product: a canonical offer ("offer:<offer_key>")
+-- or a wishlist term ("term:<words> -<excluded> >=<min price>")
+CREATE TABLE IF NOT EXISTS price_history (
+    product_key TEXT NOT NULL,
+    day DATE NOT NULL,
+    min_price DECIMAL(10,2) NOT NULL,
+    max_price DECIMAL(10,2) NOT NULL,
+    samples INT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (product_key, day)
+);
+
+-- Notifications table (for tracking sent notifications)
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    telegram_id BIGINT NOT NULL REFERENCES users(telegram_id) ON DELETE CASCADE,
+    wishlist_id INT REFERENCES wishlists(id) ON DELETE SET NULL,
+    offer_id INT REFERENCES offers(id) ON DELETE SET NULL,
+    offer_key VARCHAR(255),
+    product_name VARCHAR(500),
+    source VARCHAR(255),
+    price DECIMAL(10,2),
+    sent_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Commands already applied, so a command redelivered by Kafka is answered
+-- with its original response instead of being applied again
+CREATE TABLE IF NOT EXISTS processed_commands (
+    command_id VARCHAR(64) PRIMARY KEY,
+    command_type VARCHAR(50) NOT NULL,
+    telegram_id BIGINT NOT NULL,
+    response JSONB,
+    processed_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Wishlist events written in the transaction of each wishlist change and
+-- published to the wishlist-events topic by the backend outbox relay
+CREATE TABLE IF NOT EXISTS wishlist_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    wishlist_id INT NOT NULL,
+    telegram_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    trace_context JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW(),
+    sent_at TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+-- Create indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_wishlists_telegram_id ON wishlists(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_offers_product_name ON offers(product_name);
+CREATE INDEX IF NOT EXISTS idx_offers_received_at ON offers(received_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_telegram_id ON notifications(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_sent_at ON notifications(sent_at);
+CREATE INDEX IF NOT EXISTS idx_offer_price_events_offer_id ON offer_price_events(offer_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_offer_price_events_recorded_at ON offer_price_events(recorded_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_wishlist_offer_key ON notifications(wishlist_id, offer_key, sent_at);
+CREATE INDEX IF NOT EXISTS idx_processed_commands_processed_at ON processed_commands(processed_at);
+CREATE INDEX IF NOT EXISTS idx_wishlist_outbox_pending ON wishlist_outbox(id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_wishlist_outbox_sent_at ON wishlist_outbox(sent_at);
+
+-- Create updated_at trigger function
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Create trigger for users table
+CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Message templates table (for web dashboard)
+CREATE TABLE IF NOT EXISTS message_templates (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    product_model VARCHAR(255) NOT NULL,
+    
+    -- Structured fields for offer data
+    title_field VARCHAR(100) NOT NULL,              -- Campo para título do produto/oferta
+    description_field VARCHAR(100),                  -- Campo para descrição
+    price_field VARCHAR(100) NOT NULL,               -- Campo para preço
+    discount_field VARCHAR(100),                     -- Campo para desconto (opcional)
+    details_fields TEXT,                             -- Campos concatenados para busca (JSON array)
+    url_field VARCHAR(100),                          -- Campo para link da oferta
+    image_field VARCHAR(100),                        -- Campo para imagem do produto
+    
+    -- Original schema for backward compatibility
+    message_schema JSONB NOT NULL,
+    
+    sns_topic_arn VARCHAR(500),
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Create trigger for message_templates table
+CREATE TRIGGER update_message_templates_updated_at BEFORE UPDATE ON message_templates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Create index for message templates
+CREATE INDEX IF NOT EXISTS idx_message_templates_active ON message_templates(is_active);
+CREATE INDEX IF NOT EXISTS idx_message_templates_product_model ON message_templates(product_model);
+
+-- Import templates table (for S3 JSON imports)
+CREATE TABLE IF NOT EXISTS import_templates (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    s3_url TEXT NOT NULL,
+    mapping_schema JSONB NOT NULL,  -- Maps Offer fields to JSON paths, e.g. {"ProductName": "$.titulo", "Price": "$.price"}
+    is_active BOOLEAN DEFAULT true,
+    last_run_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Create trigger for import_templates table
+CREATE TRIGGER update_import_templates_updated_at BEFORE UPDATE ON import_templates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Create index for import templates
+CREATE INDEX IF NOT EXISTS idx_import_templates_active ON import_templates(is_active);
@@ -0,0 +1,4 @@
+-- Irreversible: databases created from init.sql already had most of these
+-- changes before they were baselined at the initial schema, so they cannot be
+-- told apart and dropping them would break those databases. The migrator
+-- refuses to revert a down file without statements.
@@ -0,0 +1,137 @@
+-- Changes that were applied by hand with the migration_*.sql files, in the
+-- order they were added. They are idempotent: a database created from the
+-- latest init.sql is unchanged, and one that missed some of them catches up.
+
+-- migration_add_notification_dedupe.sql
+-- Add offer details to notifications so repeated alerts can be suppressed
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS product_name VARCHAR(500);
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS source VARCHAR(255);
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS price DECIMAL(10,2);
+
+-- Create index for notification deduplication lookups
+CREATE INDEX IF NOT EXISTS idx_notifications_wishlist_offer ON notifications(wishlist_id, product_name, source, sent_at);
+
+-- migration_add_wishlist_filters.sql
+-- Add price range and excluded terms to wishlists table
+ALTER TABLE wishlists ADD COLUMN IF NOT EXISTS min_price DECIMAL(10,2);
+ALTER TABLE wishlists ADD COLUMN IF NOT EXISTS excluded_terms TEXT[];
+
+-- Ensure the minimum price never exceeds the target price
+ALTER TABLE wishlists DROP CONSTRAINT IF EXISTS check_price_range;
+ALTER TABLE wishlists ADD CONSTRAINT check_price_range CHECK (
+    min_price IS NULL OR target_price IS NULL OR min_price <= target_price
+);
+
+-- migration_add_cashback_alerts.sql
+-- Add cashback and effective price (price after cashback) conditions to wishlists table
+ALTER TABLE wishlists ADD COLUMN IF NOT EXISTS min_cashback INT;
+ALTER TABLE wishlists ADD COLUMN IF NOT EXISTS max_effective_price DECIMAL(10,2);
+
+-- Allow combining conditions: at least one must be set
+ALTER TABLE wishlists DROP CONSTRAINT IF EXISTS check_target;
+ALTER TABLE wishlists ADD CONSTRAINT check_target CHECK (
+    target_price IS NOT NULL OR discount_percentage IS NOT NULL OR
+    min_cashback IS NOT NULL OR max_effective_price IS NOT NULL
+);
+
+-- migration_add_offers_received_at_index.sql
+-- Create index for backtests over a date range of stored offers
+CREATE INDEX IF NOT EXISTS idx_offers_received_at ON offers(received_at);
+
+-- migration_add_offer_links.sql
+-- Add offer link and image so notifications can show a photo and a "Ver oferta" button
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS url TEXT;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS image_url TEXT;
+
+-- Add link and image fields to SNS message templates
+ALTER TABLE message_templates ADD COLUMN IF NOT EXISTS url_field VARCHAR(100);
+ALTER TABLE message_templates ADD COLUMN IF NOT EXISTS image_field VARCHAR(100);
+
+-- migration_add_offer_identity.sql
+-- Give offers a canonical identity so receipts of the same offer update one row
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS offer_key VARCHAR(255);
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS seller VARCHAR(255);
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS first_seen_at TIMESTAMP DEFAULT NOW();
+CREATE UNIQUE INDEX IF NOT EXISTS offers_offer_key_key ON offers(offer_key);
+
+-- Existing rows keep a NULL key (new receipts create keyed rows); they were first seen when received
+UPDATE offers SET first_seen_at = received_at WHERE offer_key IS NULL;
+
+-- Price events: one row when an offer is first seen and on every price or cashback change
+CREATE TABLE IF NOT EXISTS offer_price_events (
+    id SERIAL PRIMARY KEY,
+    offer_id INT NOT NULL REFERENCES offers(id) ON DELETE CASCADE,
+    price DECIMAL(10,2),
+    previous_price DECIMAL(10,2),
+    original_price DECIMAL(10,2),
+    discount_percentage INT,
+    cashback_percentage INT,
+    recorded_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_offer_price_events_offer_id ON offer_price_events(offer_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_offer_price_events_recorded_at ON offer_price_events(recorded_at);
+
+-- Keep existing offers visible to backtests
+INSERT INTO offer_price_events (offer_id, price, original_price, discount_percentage, cashback_percentage, recorded_at)
+SELECT id, price, original_price, discount_percentage, cashback_percentage, received_at
+FROM offers o
+WHERE NOT EXISTS (SELECT 1 FROM offer_price_events e WHERE e.offer_id = o.id);
+
+-- Deduplicate notifications by offer key instead of product name and source
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS offer_key VARCHAR(255);
+DROP INDEX IF EXISTS idx_notifications_wishlist_offer;
+CREATE INDEX IF NOT EXISTS idx_notifications_wishlist_offer_key ON notifications(wishlist_id, offer_key, sent_at);
+
+-- migration_add_price_history.sql
+-- Add daily price history used by the /historico command
+-- Daily price history keyed by product: a canonical offer ("offer:<offer_key>")
+-- or a wishlist term ("term:<words> -<excluded> >=<min price>")
+CREATE TABLE IF NOT EXISTS price_history (
+    product_key TEXT NOT NULL,
+    day DATE NOT NULL,
+    min_price DECIMAL(10,2) NOT NULL,
+    max_price DECIMAL(10,2) NOT NULL,
+    samples INT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (product_key, day)
+);
+
+-- migration_add_hide_suspicious_offers.sql
+-- Let users opt out of offers with a suspicious reference (original) price
+ALTER TABLE users ADD COLUMN IF NOT EXISTS hide_suspicious_offers BOOLEAN DEFAULT false;
+
+-- migration_add_processed_commands.sql
+-- Log of applied commands, so commands redelivered by Kafka are not applied twice
+CREATE TABLE IF NOT EXISTS processed_commands (
+    command_id VARCHAR(64) PRIMARY KEY,
+    command_type VARCHAR(50) NOT NULL,
+    telegram_id BIGINT NOT NULL,
+    response JSONB,
+    processed_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_commands_processed_at ON processed_commands(processed_at);
+
+-- migration_add_wishlist_outbox.sql
+-- Outbox of wishlist events, published to Kafka by the backend relay
+CREATE TABLE IF NOT EXISTS wishlist_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    wishlist_id INT NOT NULL,
+    telegram_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    sent_at TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlist_outbox_pending ON wishlist_outbox(id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_wishlist_outbox_sent_at ON wishlist_outbox(sent_at);
+
+-- migration_add_outbox_trace_context.sql
+-- Trace context of the command that wrote each outbox event, so the published
+-- event continues its trace
+ALTER TABLE wishlist_outbox ADD COLUMN IF NOT EXISTS trace_context JSONB NOT NULL DEFAULT '{}';
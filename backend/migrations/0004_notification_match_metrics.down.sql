@@ -0,0 +1,2 @@
+ALTER TABLE notifications DROP COLUMN IF EXISTS cashback_percentage;
+ALTER TABLE notifications DROP COLUMN IF EXISTS discount_percentage;
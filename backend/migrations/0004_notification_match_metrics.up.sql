@@ -0,0 +1,4 @@
+-- Discount and cashback of the notified offer, so a repeated alert can be
+-- compared on the metric that made the match
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS discount_percentage INT;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS cashback_percentage INT;
@@ -0,0 +1,56 @@
+package migrations
+
+import "testing"
+
+func TestLoadReturnsTheEmbeddedMigrationsInOrder(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := []string{
+		"0001_initial_schema",
+		"0002_manual_migrations",
+		"0003_users_blacklist",
+		"0004_notification_match_metrics",
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("Load() returned %d migrations, want %d", len(migrations), len(want))
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 || migration.String() != want[i] {
+			t.Errorf("migration %d = %s (version %d), want %s", i, migration, migration.Version, want[i])
+		}
+		if migration.Up == "" || migration.Down == "" {
+			t.Errorf("migration %s has an empty up or down script", migration)
+		}
+	}
+}
+
+func TestIrreversible(t *testing.T) {
+	tests := []struct {
+		down string
+		want bool
+	}{
+		{"-- Irreversible: nothing to drop\n-- see the up file\n", true},
+		{"\r\n  -- indented comment\r\n\r\n", true},
+		{"DROP TABLE users;\n", false},
+		{"-- drop the users\nDROP TABLE users;\n", false},
+	}
+
+	for _, tt := range tests {
+		if got := (Migration{Down: tt.down}).Irreversible(); got != tt.want {
+			t.Errorf("Irreversible() for %q = %t, want %t", tt.down, got, tt.want)
+		}
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, migration := range migrations {
+		if migration.Irreversible() != (migration.Version == 2) {
+			t.Errorf("migration %s irreversible = %t", migration, migration.Irreversible())
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package migrations
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newMigratorTest(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+	return migrator, mock
+}
+
+// expectLock expects the migration lock and the schema_migrations table
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectRecorded(mock sqlmock.Sqlmock, recorded bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM schema_migrations)")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(recorded))
+}
+
+func expectExistingSchema(mock sqlmock.Sqlmock, existing bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT to_regclass('users') IS NOT NULL")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(existing))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, versions ...int) {
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, time.Now())
+	}
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WillReturnRows(rows)
+}
+
+// expectRun expects a migration script and its record statement in a transaction
+func expectRun(mock sqlmock.Sqlmock, script, record string, args ...driver.Value) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(script)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(record).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestUpBaselinesAnExistingDatabase(t *testing.T) {
+	migrator, mock := newMigratorTest(t)
+	expectLock(mock)
+	expectRecorded(mock, false)
+	expectExistingSchema(mock, true)
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(BaselineVersion, "initial_schema").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectApplied(mock, BaselineVersion)
+	for _, migration := range migrator.migrations[1:] {
+		expectRun(mock, migration.Up, "INSERT INTO schema_migrations", migration.Version, migration.Name)
+	}
+	expectUnlock(mock)
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if applied != 3 {
+		t.Errorf("Up() applied %d migrations, want 3", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpRunsEveryMigrationOnAnEmptyDatabase(t *testing.T) {
+	migrator, mock := newMigratorTest(t)
+	expectLock(mock)
+	expectRecorded(mock, false)
+	expectExistingSchema(mock, false)
+	expectApplied(mock)
+	for _, migration := range migrator.migrations {
+		expectRun(mock, migration.Up, "INSERT INTO schema_migrations", migration.Version, migration.Name)
+	}
+	expectUnlock(mock)
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if applied != 4 {
+		t.Errorf("Up() applied %d migrations, want 4", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpSkipsTheBaselineOnceMigrationsAreRecorded(t *testing.T) {
+	migrator, mock := newMigratorTest(t)
+	expectLock(mock)
+	expectRecorded(mock, true)
+	expectApplied(mock, 1, 2, 3, 4)
+	expectUnlock(mock)
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("Up() applied %d migrations, want 0", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownRevertsNewestFirst(t *testing.T) {
+	migrator, mock := newMigratorTest(t)
+	expectLock(mock)
+	expectRecorded(mock, true)
+	expectApplied(mock, 1, 2, 3, 4)
+	for _, migration := range []Migration{migrator.migrations[3], migrator.migrations[2]} {
+		expectRun(mock, migration.Down, "DELETE FROM schema_migrations", migration.Version)
+	}
+	expectUnlock(mock)
+
+	reverted, err := migrator.Down(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if reverted != 2 {
+		t.Errorf("Down() reverted %d migrations, want 2", reverted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownRefusesToRevertAnIrreversibleMigration(t *testing.T) {
+	migrator, mock := newMigratorTest(t)
+	expectLock(mock)
+	expectRecorded(mock, true)
+	expectApplied(mock, 1, 2, 3, 4)
+	expectUnlock(mock)
+
+	reverted, err := migrator.Down(context.Background(), 3)
+	if err == nil || !strings.Contains(err.Error(), "0002_manual_migrations cannot be reverted") {
+		t.Fatalf("Down() error = %v, want 0002_manual_migrations to be irreversible", err)
+	}
+	if reverted != 0 {
+		t.Errorf("Down() reverted %d migrations, want none", reverted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,109 @@
+// Package bus is the message bus shared by the services. Kafka (Sarama) is used
+// in production; the in-memory bus runs the services in a single process and in
+// tests, without a broker.
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Message is a message published to or consumed from a topic
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+
+	// Set once the message is published or consumed
+	Partition int32
+	Offset    int64
+}
+
+// Handler processes a consumed message. A message is acknowledged once its
+// handler returns nil; on error it is consumed again.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Subscription selects the messages consumed by a handler
+type Subscription struct {
+	Topic string
+	Group string // Members of a group share the messages of the topic
+
+	// FromNewest starts a group without committed offsets at the next
+	// published message instead of the oldest one
+	FromNewest bool
+}
+
+// Publisher publishes messages to topics
+type Publisher interface {
+	// Publish sends the messages, in order, and sets their partition and offset
+	Publish(ctx context.Context, msgs ...*Message) error
+}
+
+// Subscriber consumes the messages of topics
+type Subscriber interface {
+	// Subscribe calls handler for the messages of the subscription until ctx is
+	// cancelled. The returned status reports whether the subscription is
+	// consuming, for readiness checks.
+	Subscribe(ctx context.Context, sub Subscription, handler Handler) (*Status, error)
+}
+
+// Bus publishes and consumes messages
+type Bus interface {
+	Publisher
+	Subscriber
+	Close() error
+}
+
+// Status tracks whether a subscription is consuming, for readiness checks
+type Status struct {
+	mu      sync.Mutex
+	active  bool
+	lastErr error
+}
+
+// SetActive records whether the subscription has an active session
+func (s *Status) SetActive(active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active = active
+	if active {
+		s.lastErr = nil
+	}
+}
+
+// SetError records the last consume error
+func (s *Status) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastErr = err
+}
+
+// Check returns an error while the subscription has no session, i.e. before
+// joining the group, during rebalances and after consume errors
+func (s *Status) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active {
+		return nil
+	}
+	if s.lastErr != nil {
+		return fmt.Errorf("no active session: %w", s.lastErr)
+	}
+	return errors.New("no active session")
+}
+
+// clone copies a message, so handlers and publishers don't share headers
+func clone(msg *Message) *Message {
+	c := *msg
+	c.Headers = make(map[string]string, len(msg.Headers))
+	for k, v := range msg.Headers {
+		c.Headers[k] = v
+	}
+	return &c
+}
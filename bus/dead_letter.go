@@ -0,0 +1,74 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeadLetterSuffix is appended to a topic name to get its dead-letter topic
+const DeadLetterSuffix = ".dlq"
+
+// Headers added to dead-lettered messages, next to the original headers
+const (
+	HeaderOriginalTopic     = "dlq-original-topic"
+	HeaderOriginalPartition = "dlq-original-partition"
+	HeaderOriginalOffset    = "dlq-original-offset"
+	HeaderConsumerGroup     = "dlq-consumer-group"
+	HeaderError             = "dlq-error"
+	HeaderAttempts          = "dlq-attempts"
+	HeaderFailedAt          = "dlq-failed-at"
+)
+
+// DeadLetterTopic returns the dead-letter topic of a topic
+func DeadLetterTopic(topic string) string {
+	return topic + DeadLetterSuffix
+}
+
+// IsDeadLetterHeader reports whether a header was added by the DeadLetterWriter
+func IsDeadLetterHeader(key string) bool {
+	return strings.HasPrefix(key, "dlq-")
+}
+
+// DeadLetterWriter publishes messages that could not be handled to the
+// dead-letter topic of their topic, keeping key, value and headers
+type DeadLetterWriter struct {
+	publisher Publisher
+}
+
+func NewDeadLetterWriter(publisher Publisher) *DeadLetterWriter {
+	return &DeadLetterWriter{publisher: publisher}
+}
+
+// Send publishes the message to its dead-letter topic with the error metadata in headers
+func (w *DeadLetterWriter) Send(ctx context.Context, message *Message, groupID string, handlerErr error, attempts int) error {
+	headers := make(map[string]string, len(message.Headers)+7)
+	for k, v := range message.Headers {
+		if !IsDeadLetterHeader(k) {
+			headers[k] = v
+		}
+	}
+
+	headers[HeaderOriginalTopic] = message.Topic
+	headers[HeaderOriginalPartition] = strconv.Itoa(int(message.Partition))
+	headers[HeaderOriginalOffset] = strconv.FormatInt(message.Offset, 10)
+	headers[HeaderConsumerGroup] = groupID
+	headers[HeaderError] = handlerErr.Error()
+	headers[HeaderAttempts] = strconv.Itoa(attempts)
+	headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	msg := &Message{
+		Topic:   DeadLetterTopic(message.Topic),
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	}
+
+	if err := w.publisher.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send message to %s: %w", msg.Topic, err)
+	}
+
+	return nil
+}
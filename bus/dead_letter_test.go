@@ -0,0 +1,105 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsDeadLetterHeader(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{HeaderOriginalTopic, true},
+		{HeaderOriginalPartition, true},
+		{HeaderOriginalOffset, true},
+		{HeaderConsumerGroup, true},
+		{HeaderError, true},
+		{HeaderAttempts, true},
+		{HeaderFailedAt, true},
+		{"traceparent", false},
+		{"content-type", false},
+		{"x-dlq-note", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsDeadLetterHeader(tt.key); got != tt.want {
+			t.Errorf("IsDeadLetterHeader(%q) = %t, want %t", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestDeadLetterWriterSend(t *testing.T) {
+	memory := NewMemory()
+	message := &Message{
+		Topic:     "offers",
+		Key:       []byte("promobit:1"),
+		Value:     []byte(`{"product_name":"TV"}`),
+		Partition: 2,
+		Offset:    41,
+		Headers: map[string]string{
+			"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			HeaderError:   "error of an earlier dead-lettering",
+		},
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	err := NewDeadLetterWriter(memory).Send(context.Background(), message, "backend-offers-consumer", errors.New("database down"), 5)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	messages := memory.topics["offers.dlq"].messages
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages on offers.dlq, want 1", len(messages))
+	}
+	got := messages[0]
+
+	if string(got.Key) != string(message.Key) || string(got.Value) != string(message.Value) {
+		t.Errorf("key/value = %s/%s, want the original ones", got.Key, got.Value)
+	}
+
+	want := map[string]string{
+		"traceparent":           message.Headers["traceparent"],
+		HeaderOriginalTopic:     "offers",
+		HeaderOriginalPartition: "2",
+		HeaderOriginalOffset:    "41",
+		HeaderConsumerGroup:     "backend-offers-consumer",
+		HeaderError:             "database down",
+		HeaderAttempts:          "5",
+	}
+	for k, v := range want {
+		if got.Headers[k] != v {
+			t.Errorf("header %s = %q, want %q", k, got.Headers[k], v)
+		}
+	}
+	if len(got.Headers) != len(want)+1 {
+		t.Errorf("headers = %v, want only the original and dead-letter headers", got.Headers)
+	}
+
+	failedAt, err := time.Parse(time.RFC3339, got.Headers[HeaderFailedAt])
+	if err != nil || failedAt.Before(before) {
+		t.Errorf("header %s = %q, want the RFC 3339 time of the failure", HeaderFailedAt, got.Headers[HeaderFailedAt])
+	}
+
+	// The original message keeps its headers
+	if message.Headers[HeaderError] != "error of an earlier dead-lettering" {
+		t.Error("Send() changed the headers of the original message")
+	}
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(context.Context, ...*Message) error {
+	return errors.New("broker unavailable")
+}
+
+func TestDeadLetterWriterSendFails(t *testing.T) {
+	message := &Message{Topic: "bot-commands", Value: []byte("{}")}
+	err := NewDeadLetterWriter(failingPublisher{}).Send(context.Background(), message, "group", errors.New("failed"), 1)
+	if err == nil || err.Error() != "failed to send message to bot-commands.dlq: broker unavailable" {
+		t.Errorf("Send() error = %v, want the publish error", err)
+	}
+}
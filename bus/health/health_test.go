@@ -0,0 +1,147 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveHandler(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("postgres", func(context.Context) error { return errors.New("connection refused") })
+
+	rec := httptest.NewRecorder()
+	checker.LiveHandler(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 even with a dependency down", rec.Code)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"status":"up"}` {
+		t.Errorf("body = %s, want {\"status\":\"up\"}", got)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		wantCode   int
+		wantStatus string
+	}{
+		{"no dependencies", nil, http.StatusOK, StatusUp},
+		{"all up", map[string]Check{"postgres": up, "redis": up}, http.StatusOK, StatusUp},
+		{"one down", map[string]Check{"postgres": up, "redis": down}, http.StatusServiceUnavailable, StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(time.Second)
+			for name, check := range tt.checks {
+				checker.Add(name, check)
+			}
+
+			rec := httptest.NewRecorder()
+			checker.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+
+			var report Report
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("invalid body %s: %v", rec.Body, err)
+			}
+			if report.Status != tt.wantStatus || len(report.Dependencies) != len(tt.checks) {
+				t.Errorf("report = %+v, want status %s with %d dependencies", report, tt.wantStatus, len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestReadyReportShape(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("postgres", func(context.Context) error { return nil })
+	checker.Add("kafka", func(context.Context) error { return errors.New("no brokers") })
+
+	rec := httptest.NewRecorder()
+	checker.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body %s: %v", rec.Body, err)
+	}
+	if body["status"] != StatusDown {
+		t.Errorf("status = %v, want down", body["status"])
+	}
+
+	dependencies, ok := body["dependencies"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("dependencies = %v, want an object keyed by dependency", body["dependencies"])
+	}
+
+	postgres := dependencies["postgres"].(map[string]interface{})
+	if _, hasError := postgres["error"]; postgres["status"] != StatusUp || hasError {
+		t.Errorf("postgres = %v, want up without error", postgres)
+	}
+	if _, ok := postgres["latency_ms"].(float64); !ok {
+		t.Errorf("postgres = %v, want a numeric latency_ms", postgres)
+	}
+
+	kafka := dependencies["kafka"].(map[string]interface{})
+	if kafka["status"] != StatusDown || kafka["error"] != "no brokers" {
+		t.Errorf("kafka = %v, want down with the check error", kafka)
+	}
+}
+
+func TestRunTimesOutChecksIgnoringTheContext(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	checker.Add("stuck", func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	start := time.Now()
+	report := checker.Run(context.Background())
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run took %s, want it to stop at the timeout", elapsed)
+	}
+	stuck := report.Dependencies["stuck"]
+	if report.Status != StatusDown || stuck.Status != StatusDown || stuck.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("report = %+v, want stuck down with the deadline error", report)
+	}
+}
+
+func TestAddReplacesACheck(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("redis", func(context.Context) error { return errors.New("down") })
+	checker.Add("redis", func(context.Context) error { return nil })
+
+	if report := checker.Run(context.Background()); report.Status != StatusUp || len(report.Dependencies) != 1 {
+		t.Errorf("report = %+v, want only the last redis check", report)
+	}
+}
+
+func TestKafkaCheckWithoutBrokers(t *testing.T) {
+	listener := httptest.NewServer(http.NotFoundHandler())
+	addr := strings.TrimPrefix(listener.URL, "http://")
+	listener.Close()
+
+	err := NewKafkaCheck([]string{addr})(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to connect to Kafka") {
+		t.Errorf("check error = %v, want a connection error", err)
+	}
+}
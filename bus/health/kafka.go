@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// kafkaCheck keeps a client open between checks, so a check costs one
+// metadata request
+type kafkaCheck struct {
+	mu      sync.Mutex
+	brokers []string
+	client  sarama.Client
+}
+
+// NewKafkaCheck returns a check that the Kafka brokers used by the producers
+// answer metadata requests
+func NewKafkaCheck(brokers []string) Check {
+	k := &kafkaCheck{brokers: brokers}
+	return k.check
+}
+
+func (k *kafkaCheck) check(ctx context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.client == nil || k.client.Closed() {
+		config := sarama.NewConfig()
+		config.Net.DialTimeout = 3 * time.Second
+		config.Metadata.Retry.Max = 0
+
+		client, err := sarama.NewClient(k.brokers, config)
+		if err != nil {
+			return fmt.Errorf("failed to connect to Kafka: %w", err)
+		}
+		k.client = client
+	}
+
+	if err := k.client.RefreshMetadata(); err != nil {
+		return fmt.Errorf("failed to get Kafka metadata: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// redeliveryDelay is how long the buses wait before handing a failed message to
+// its handler again
+const redeliveryDelay = time.Second
+
+// Memory is an in-process bus. Topics have a single partition and keep their
+// messages until every group of the topic handled them, so groups subscribing
+// to a topic nobody read yet still start from its oldest message. A group has
+// one member per topic at a time and resumes from its last acknowledged message
+// when it subscribes again.
+type Memory struct {
+	mu     sync.Mutex
+	topics map[string]*memoryTopic
+}
+
+type memoryTopic struct {
+	messages []*Message
+	base     int64 // Offset of the first message kept
+	groups   map[string]*memoryGroup
+	changed  chan struct{} // Closed when a message is published
+}
+
+type memoryGroup struct {
+	subscribed bool
+	next       int64 // Offset of the next message to hand
+}
+
+func NewMemory() *Memory {
+	return &Memory{topics: make(map[string]*memoryTopic)}
+}
+
+// Publish appends the messages to their topics
+func (b *Memory) Publish(ctx context.Context, msgs ...*Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, msg := range msgs {
+		topic := b.topic(msg.Topic)
+
+		msg.Partition = 0
+		msg.Offset = topic.end()
+		topic.messages = append(topic.messages, clone(msg))
+
+		close(topic.changed)
+		topic.changed = make(chan struct{})
+	}
+	return nil
+}
+
+// Subscribe hands the messages of the topic to handler, one at a time. A
+// failed message is handed again after a second.
+func (b *Memory) Subscribe(ctx context.Context, sub Subscription, handler Handler) (*Status, error) {
+	b.mu.Lock()
+	topic := b.topic(sub.Topic)
+	group, ok := topic.groups[sub.Group]
+	if !ok {
+		group = &memoryGroup{next: topic.base}
+		if sub.FromNewest {
+			group.next = topic.end()
+		}
+		topic.groups[sub.Group] = group
+	}
+	if group.subscribed {
+		b.mu.Unlock()
+		return nil, fmt.Errorf("group %s already subscribed to %s", sub.Group, sub.Topic)
+	}
+	group.subscribed = true
+	b.mu.Unlock()
+
+	status := &Status{}
+	status.SetActive(true)
+
+	go func() {
+		defer func() {
+			b.mu.Lock()
+			group.subscribed = false
+			b.mu.Unlock()
+			status.SetActive(false)
+		}()
+
+		for {
+			b.mu.Lock()
+			if group.next == topic.end() {
+				changed := topic.changed
+				b.mu.Unlock()
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-changed:
+				}
+				continue
+			}
+			msg := clone(topic.messages[group.next-topic.base])
+			b.mu.Unlock()
+
+			if err := handler(ctx, msg); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("Failed to handle message from %s at offset %d: %v", msg.Topic, msg.Offset, err)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(redeliveryDelay):
+				}
+				continue
+			}
+
+			b.mu.Lock()
+			group.next++
+			topic.compact()
+			b.mu.Unlock()
+		}
+	}()
+
+	log.Printf("In-memory consumer group %s started for topic %s", sub.Group, sub.Topic)
+	return status, nil
+}
+
+// Drained reports whether every subscribed group handled all the messages of
+// its topic. Tests use it to wait for the consumers to catch up.
+func (b *Memory) Drained() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, topic := range b.topics {
+		for _, group := range topic.groups {
+			if group.subscribed && group.next < topic.end() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Close does nothing: subscriptions stop with their context
+func (b *Memory) Close() error {
+	return nil
+}
+
+// end returns the offset of the next message published to the topic
+func (t *memoryTopic) end() int64 {
+	return t.base + int64(len(t.messages))
+}
+
+// compact drops the messages every group of the topic handled. Groups that are
+// not subscribed count too, as they resume from their last message.
+func (t *memoryTopic) compact() {
+	next := t.end()
+	for _, group := range t.groups {
+		if group.next < next {
+			next = group.next
+		}
+	}
+
+	handled := int(next - t.base)
+	if handled == 0 {
+		return
+	}
+
+	// Clear the dropped messages so they are freed before the next append
+	// moves the slice to a new array
+	for i := 0; i < handled; i++ {
+		t.messages[i] = nil
+	}
+	t.messages = t.messages[handled:]
+	t.base = next
+}
+
+// topic returns the topic, creating it on first use. Callers hold b.mu.
+func (b *Memory) topic(name string) *memoryTopic {
+	topic, ok := b.topics[name]
+	if !ok {
+		topic = &memoryTopic{
+			groups:  make(map[string]*memoryGroup),
+			changed: make(chan struct{}),
+		}
+		b.topics[name] = topic
+	}
+	return topic
+}
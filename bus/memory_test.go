@@ -0,0 +1,259 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a handler keeping the values of the messages it was handed
+type recorder struct {
+	mu     sync.Mutex
+	values []string
+	fail   map[string]int // Failures left for each value
+}
+
+func (r *recorder) handle(ctx context.Context, msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value := string(msg.Value)
+	r.values = append(r.values, value)
+	if r.fail[value] > 0 {
+		r.fail[value]--
+		return errors.New("database down")
+	}
+	return nil
+}
+
+func (r *recorder) handled() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return strings.Join(r.values, ",")
+}
+
+// waitFor polls condition until it holds or fails the test after timeout
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func publish(t *testing.T, b *Memory, topic string, values ...string) {
+	t.Helper()
+	for _, value := range values {
+		if err := b.Publish(context.Background(), &Message{Topic: topic, Value: []byte(value)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// subscribe subscribes r until the test ends, or cancel is called
+func subscribe(t *testing.T, b *Memory, sub Subscription, r *recorder) (*Status, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	status, err := b.Subscribe(ctx, sub, r.handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status, cancel
+}
+
+func TestMemoryPublishSetsOffsetsAndCopiesMessages(t *testing.T) {
+	b := NewMemory()
+	first := &Message{Topic: "offers", Value: []byte("a"), Headers: map[string]string{"traceparent": "1"}}
+	second := &Message{Topic: "offers", Value: []byte("b"), Partition: 3}
+	other := &Message{Topic: "bot-responses", Value: []byte("c")}
+
+	if err := b.Publish(context.Background(), first, second, other); err != nil {
+		t.Fatal(err)
+	}
+	if first.Offset != 0 || second.Offset != 1 || second.Partition != 0 || other.Offset != 0 {
+		t.Errorf("offsets = %d, %d (partition %d), %d, want 0, 1 (partition 0), 0", first.Offset, second.Offset, second.Partition, other.Offset)
+	}
+
+	first.Headers["traceparent"] = "changed"
+	if got := b.topics["offers"].messages[0].Headers["traceparent"]; got != "1" {
+		t.Errorf("stored header = %q, want the published one", got)
+	}
+}
+
+func TestMemoryHandsMessagesInOrder(t *testing.T) {
+	b := NewMemory()
+	publish(t, b, "offers", "a", "b")
+
+	r := &recorder{}
+	status, _ := subscribe(t, b, Subscription{Topic: "offers", Group: "backend"}, r)
+	if err := status.Check(context.Background()); err != nil {
+		t.Errorf("Check() = %v, want an active subscription", err)
+	}
+
+	publish(t, b, "offers", "c")
+	publish(t, b, "bot-responses", "x")
+	waitFor(t, time.Second, b.Drained)
+
+	if got := r.handled(); got != "a,b,c" {
+		t.Errorf("handled %s, want a,b,c", got)
+	}
+}
+
+func TestMemoryRedeliversFailedMessage(t *testing.T) {
+	b := NewMemory()
+	publish(t, b, "offers", "a", "b")
+
+	r := &recorder{fail: map[string]int{"a": 1}}
+	start := time.Now()
+	subscribe(t, b, Subscription{Topic: "offers", Group: "backend"}, r)
+	waitFor(t, 3*redeliveryDelay, b.Drained)
+
+	// The next message waits for the failed one
+	if got := r.handled(); got != "a,a,b" {
+		t.Errorf("handled %s, want a,a,b", got)
+	}
+	if elapsed := time.Since(start); elapsed < redeliveryDelay {
+		t.Errorf("redelivered after %s, want at least %s", elapsed, redeliveryDelay)
+	}
+}
+
+func TestMemoryGroupResumesFromLastHandledMessage(t *testing.T) {
+	b := NewMemory()
+	publish(t, b, "offers", "a", "b")
+
+	first := &recorder{}
+	status, cancel := subscribe(t, b, Subscription{Topic: "offers", Group: "backend"}, first)
+	waitFor(t, time.Second, b.Drained)
+
+	if _, err := b.Subscribe(context.Background(), Subscription{Topic: "offers", Group: "backend"}, first.handle); err == nil {
+		t.Error("Subscribe() of a subscribed group succeeded, want an error")
+	}
+
+	cancel()
+	waitFor(t, time.Second, func() bool { return status.Check(context.Background()) != nil })
+	publish(t, b, "offers", "c")
+
+	// Messages published while the group is down wait for it
+	if !b.Drained() {
+		t.Error("Drained() = false without subscribed groups")
+	}
+
+	second := &recorder{}
+	subscribe(t, b, Subscription{Topic: "offers", Group: "backend", FromNewest: true}, second)
+	other := &recorder{}
+	subscribe(t, b, Subscription{Topic: "offers", Group: "dlq-replay"}, other)
+	waitFor(t, time.Second, b.Drained)
+
+	if got := second.handled(); got != "c" {
+		t.Errorf("resumed group handled %s, want c", got)
+	}
+	// a and b were handled by every group and dropped before it subscribed
+	if got := other.handled(); got != "c" {
+		t.Errorf("new group handled %s, want c", got)
+	}
+}
+
+func TestMemoryDropsMessagesHandledByEveryGroup(t *testing.T) {
+	b := NewMemory()
+	kept := func() int {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return len(b.topics["offers"].messages)
+	}
+
+	// Messages wait for the first group of a topic
+	publish(t, b, "offers", "a", "b")
+	if got := kept(); got != 2 {
+		t.Fatalf("kept %d messages before any group subscribed, want 2", got)
+	}
+
+	backend := &recorder{}
+	status, cancel := subscribe(t, b, Subscription{Topic: "offers", Group: "backend"}, backend)
+	waitFor(t, time.Second, b.Drained)
+	if got := kept(); got != 0 {
+		t.Errorf("kept %d messages handled by every group, want 0", got)
+	}
+
+	// A group that is down keeps the messages it did not handle
+	cancel()
+	waitFor(t, time.Second, func() bool { return status.Check(context.Background()) != nil })
+	replay := &recorder{}
+	subscribe(t, b, Subscription{Topic: "offers", Group: "dlq-replay"}, replay)
+	publish(t, b, "offers", "c", "d")
+	waitFor(t, time.Second, b.Drained)
+	if got := kept(); got != 2 {
+		t.Errorf("kept %d messages while the backend group is down, want 2", got)
+	}
+
+	subscribe(t, b, Subscription{Topic: "offers", Group: "backend"}, backend)
+	waitFor(t, time.Second, b.Drained)
+	if got := kept(); got != 0 {
+		t.Errorf("kept %d messages handled by every group, want 0", got)
+	}
+	if got := backend.handled(); got != "a,b,c,d" {
+		t.Errorf("backend handled %s, want a,b,c,d", got)
+	}
+	if got := replay.handled(); got != "c,d" {
+		t.Errorf("dlq-replay handled %s, want c,d", got)
+	}
+
+	// Offsets keep counting from the dropped messages
+	msg := &Message{Topic: "offers", Value: []byte("e")}
+	if err := b.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Offset != 4 {
+		t.Errorf("offset = %d, want 4", msg.Offset)
+	}
+}
+
+func TestMemoryFromNewest(t *testing.T) {
+	b := NewMemory()
+	publish(t, b, "bot-responses", "a")
+
+	r := &recorder{}
+	subscribe(t, b, Subscription{Topic: "bot-responses", Group: "frontend", FromNewest: true}, r)
+	publish(t, b, "bot-responses", "b")
+	waitFor(t, time.Second, b.Drained)
+
+	if got := r.handled(); got != "b" {
+		t.Errorf("handled %s, want b", got)
+	}
+}
+
+func TestMemoryDrained(t *testing.T) {
+	b := NewMemory()
+	if !b.Drained() {
+		t.Error("Drained() = false for an empty bus")
+	}
+
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := b.Subscribe(ctx, Subscription{Topic: "offers", Group: "backend"}, func(ctx context.Context, msg *Message) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish(t, b, "offers", "a")
+
+	// A message being handled is not drained yet
+	time.Sleep(20 * time.Millisecond)
+	if b.Drained() {
+		t.Error("Drained() = true while the message is handled")
+	}
+
+	close(release)
+	waitFor(t, time.Second, b.Drained)
+}
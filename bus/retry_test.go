@@ -0,0 +1,120 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBackoffDoublesUpToTheMax(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second}
+
+	want := []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+		30 * time.Second, 30 * time.Second,
+	}
+	for i, w := range want {
+		if got := policy.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestBackoffStartsAboveTheMax(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Minute, MaxBackoff: 10 * time.Second}
+	if got := policy.Backoff(1); got != 10*time.Second {
+		t.Errorf("Backoff(1) = %s, want the max backoff", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	failure := errors.New("broker unavailable")
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"first attempt succeeds", 0, failure, 1, false},
+		{"succeeds after retries", 2, failure, 3, false},
+		{"attempts run out", 5, failure, 3, true},
+		{"permanent error is not retried", 5, Permanent(failure), 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			attempts, err := policy.Run(context.Background(), func() error {
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+
+			if attempts != tt.wantAttempts || calls != tt.wantAttempts {
+				t.Errorf("attempts = %d, calls = %d, want %d", attempts, calls, tt.wantAttempts)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, failure) {
+				t.Errorf("err = %v, want the handler error", err)
+			}
+		})
+	}
+}
+
+func TestRunWithoutAttemptsCallsOnce(t *testing.T) {
+	calls := 0
+	RetryPolicy{}.Run(context.Background(), func() error {
+		calls++
+		return errors.New("failed")
+	})
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRunStopsWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+
+	done := make(chan int)
+	go func() {
+		attempts, _ := policy.Run(ctx, func() error { return errors.New("failed") })
+		done <- attempts
+	}()
+	cancel()
+
+	select {
+	case attempts := <-done:
+		if attempts != 1 {
+			t.Errorf("attempts = %d, want 1", attempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run kept waiting for the backoff after the context was canceled")
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) != nil")
+	}
+
+	err := fmt.Errorf("failed to handle offer: %w", Permanent(errors.New("malformed")))
+	if !IsPermanent(err) {
+		t.Error("IsPermanent() = false for a wrapped permanent error")
+	}
+	if err.Error() != "failed to handle offer: malformed" {
+		t.Errorf("Error() = %q, want the wrapped message unchanged", err)
+	}
+	if IsPermanent(errors.New("timeout")) {
+		t.Error("IsPermanent() = true for a plain error")
+	}
+}
@@ -0,0 +1,200 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Sarama is the Kafka bus
+type Sarama struct {
+	brokers  []string
+	producer sarama.SyncProducer
+
+	mu     sync.Mutex
+	groups []sarama.ConsumerGroup
+}
+
+// NewSarama connects the producer to the brokers. Consumer groups are created
+// by Subscribe.
+func NewSarama(brokers []string) (*Sarama, error) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	return &Sarama{brokers: brokers, producer: producer}, nil
+}
+
+// Publish sends the messages to Kafka
+func (b *Sarama) Publish(ctx context.Context, msgs ...*Message) error {
+	producerMsgs := make([]*sarama.ProducerMessage, len(msgs))
+	for i, msg := range msgs {
+		producerMsgs[i] = toProducerMessage(msg)
+	}
+
+	var err error
+	if len(producerMsgs) == 1 {
+		_, _, err = b.producer.SendMessage(producerMsgs[0])
+	} else {
+		err = b.producer.SendMessages(producerMsgs)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, msg := range msgs {
+		msg.Partition = producerMsgs[i].Partition
+		msg.Offset = producerMsgs[i].Offset
+	}
+	return nil
+}
+
+// Subscribe joins the consumer group of the subscription. A failed message is
+// handed to its handler again after a second, keeping the session, and is only
+// committed once handled.
+func (b *Sarama) Subscribe(ctx context.Context, sub Subscription, handler Handler) (*Status, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_8_0_0
+	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	if sub.FromNewest {
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+
+	client, err := sarama.NewConsumerGroup(b.brokers, sub.Group, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer group %s: %w", sub.Group, err)
+	}
+
+	b.mu.Lock()
+	b.groups = append(b.groups, client)
+	b.mu.Unlock()
+
+	group := &groupHandler{status: &Status{}, handler: handler}
+
+	go func() {
+		for {
+			// `Consume` should be called inside an infinite loop, when a
+			// server-side rebalance happens, the consumer session will need to be
+			// recreated to get the new claims
+			if err := client.Consume(ctx, []string{sub.Topic}, group); err != nil {
+				log.Printf("Error from consumer: %v", err)
+				group.status.SetError(err)
+				time.Sleep(time.Second * 5) // Wait before retrying
+			}
+			// check if context was cancelled, signaling that the consumer should stop
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	// Readiness checks report the group as down until the first session starts
+	log.Printf("Sarama consumer group %s started for topic %s", sub.Group, sub.Topic)
+	return group.status, nil
+}
+
+// Close closes the producer and the consumer groups
+func (b *Sarama) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, group := range b.groups {
+		if err := group.Close(); err != nil {
+			log.Printf("Failed to close consumer group: %v", err)
+		}
+	}
+	b.groups = nil
+
+	return b.producer.Close()
+}
+
+// groupHandler implements sarama.ConsumerGroupHandler for a Handler
+type groupHandler struct {
+	status  *Status
+	handler Handler
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim
+func (g *groupHandler) Setup(sarama.ConsumerGroupSession) error {
+	g.status.SetActive(true)
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
+func (g *groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	g.status.SetActive(false)
+	return nil
+}
+
+// ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
+// A message is only marked once its handler succeeded. Failures are retried in
+// place: ending the session would rejoin the group at once and hand the same
+// message again in a hot loop.
+func (g *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+	for message := range claim.Messages() {
+		for {
+			err := g.handler(ctx, fromConsumerMessage(message))
+			if err == nil {
+				break
+			}
+			// Rebalance or shutdown, the message is consumed again
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Failed to handle message from %s at offset %d: %v", message.Topic, message.Offset, err)
+			g.status.SetError(err)
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(redeliveryDelay):
+			}
+		}
+
+		session.MarkMessage(message, "")
+	}
+	return nil
+}
+
+func toProducerMessage(msg *Message) *sarama.ProducerMessage {
+	producerMsg := &sarama.ProducerMessage{
+		Topic: msg.Topic,
+		Value: sarama.ByteEncoder(msg.Value),
+	}
+	if msg.Key != nil {
+		producerMsg.Key = sarama.ByteEncoder(msg.Key)
+	}
+	for k, v := range msg.Headers {
+		producerMsg.Headers = append(producerMsg.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	return producerMsg
+}
+
+func fromConsumerMessage(message *sarama.ConsumerMessage) *Message {
+	msg := &Message{
+		Topic:     message.Topic,
+		Key:       message.Key,
+		Value:     message.Value,
+		Headers:   make(map[string]string, len(message.Headers)),
+		Partition: message.Partition,
+		Offset:    message.Offset,
+	}
+	for _, h := range message.Headers {
+		if h != nil {
+			msg.Headers[string(h.Key)] = string(h.Value)
+		}
+	}
+	return msg
+}
@@ -0,0 +1,110 @@
+package bus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// fakeSession is a consumer group session keeping the marked offsets
+type fakeSession struct {
+	ctx context.Context
+
+	mu     sync.Mutex
+	marked []int64
+}
+
+func (s *fakeSession) Claims() map[string][]int32                                               { return nil }
+func (s *fakeSession) MemberID() string                                                         { return "member" }
+func (s *fakeSession) GenerationID() int32                                                      { return 1 }
+func (s *fakeSession) MarkOffset(topic string, partition int32, offset int64, metadata string)  {}
+func (s *fakeSession) Commit()                                                                  {}
+func (s *fakeSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {}
+func (s *fakeSession) Context() context.Context                                                 { return s.ctx }
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = append(s.marked, msg.Offset)
+}
+
+func (s *fakeSession) markedOffsets() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.marked...)
+}
+
+// fakeClaim is a claim of the offers topic handing the messages of a channel
+type fakeClaim struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string                            { return "offers" }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) InitialOffset() int64                     { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func newFakeClaim(values ...string) *fakeClaim {
+	claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, len(values))}
+	for i, value := range values {
+		claim.messages <- &sarama.ConsumerMessage{Topic: "offers", Offset: int64(i), Value: []byte(value)}
+	}
+	close(claim.messages)
+	return claim
+}
+
+func TestConsumeClaimRetriesFailedMessageInSession(t *testing.T) {
+	r := &recorder{fail: map[string]int{"a": 1}}
+	group := &groupHandler{status: &Status{}, handler: r.handle}
+	session := &fakeSession{ctx: context.Background()}
+
+	start := time.Now()
+	if err := group.ConsumeClaim(session, newFakeClaim("a", "b")); err != nil {
+		t.Fatalf("ConsumeClaim() error = %v, want the session kept", err)
+	}
+
+	if got := r.handled(); got != "a,a,b" {
+		t.Errorf("handled %s, want a,a,b", got)
+	}
+	if got := session.markedOffsets(); len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Errorf("marked offsets %v, want [0 1]", got)
+	}
+	if elapsed := time.Since(start); elapsed < redeliveryDelay {
+		t.Errorf("retried after %s, want at least %s", elapsed, redeliveryDelay)
+	}
+
+	// The error is reported once the session ends
+	group.status.SetActive(false)
+	if err := group.status.Check(context.Background()); err == nil || err.Error() != "no active session: database down" {
+		t.Errorf("Check() = %v, want the handler error", err)
+	}
+}
+
+func TestConsumeClaimStopsRetryingWhenSessionEnds(t *testing.T) {
+	r := &recorder{fail: map[string]int{"a": 100}}
+	group := &groupHandler{status: &Status{}, handler: r.handle}
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &fakeSession{ctx: ctx}
+
+	done := make(chan error, 1)
+	go func() { done <- group.ConsumeClaim(session, newFakeClaim("a", "b")) }()
+
+	waitFor(t, time.Second, func() bool { return r.handled() == "a" })
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ConsumeClaim() error = %v, want nil on rebalance", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ConsumeClaim() kept retrying after the session ended")
+	}
+	if got := session.markedOffsets(); len(got) != 0 {
+		t.Errorf("marked offsets %v, want none", got)
+	}
+}
@@ -0,0 +1,140 @@
+// Package tracing carries the trace of a message across the services: the
+// producer span writes its context to the message headers and the consumer
+// span continues it.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Init sets up the W3C trace context propagation and, when an OTLP endpoint is
+// configured (OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT),
+// exports the spans via OTLP/HTTP. Without an endpoint spans are not recorded
+// but the trace context received in messages is still passed on. The returned
+// function flushes and stops the exporter.
+func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		log.Println("Tracing export disabled: no OTLP endpoint configured")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.Merge(
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	log.Printf("Tracing enabled for %s", serviceName)
+	return provider.Shutdown, nil
+}
+
+// Tracer starts the spans of one instrumented package
+type Tracer struct {
+	instrumentationName string
+}
+
+// NewTracer creates a tracer whose spans are attributed to instrumentationName,
+// the import path of the package creating them
+func NewTracer(instrumentationName string) *Tracer {
+	return &Tracer{instrumentationName: instrumentationName}
+}
+
+// Start starts a span for work that is not a Kafka message, e.g. an HTTP
+// request or a scheduled job
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(t.instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartProducerSpan starts a span for publishing a message and writes its
+// trace context to the message headers, replacing the one of a message sent
+// again (e.g. to a dead-letter topic). End the span once the message is sent.
+func (t *Tracer) StartProducerSpan(ctx context.Context, msg *bus.Message) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(t.instrumentationName).Start(ctx, msg.Topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(msg.Topic),
+		),
+	)
+
+	if msg.Headers == nil {
+		msg.Headers = make(map[string]string)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(msg.Headers))
+	return ctx, span
+}
+
+// StartConsumerSpan starts a span for processing a message, continuing the
+// trace of the producer read from the message headers
+func (t *Tracer) StartConsumerSpan(ctx context.Context, message *bus.Message, groupID string) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(message.Headers))
+
+	return otel.Tracer(t.instrumentationName).Start(ctx, message.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationDeliver,
+			semconv.MessagingDestinationName(message.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(message.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(message.Offset)),
+			semconv.MessagingKafkaConsumerGroup(groupID),
+		),
+	)
+}
+
+// End records the error, if any, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of ctx as a map, to be stored with work
+// that is published later (e.g. outbox events)
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns ctx with the trace context stored by Inject
+func Extract(ctx context.Context, traceContext map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(traceContext))
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var recorder = tracetest.NewSpanRecorder()
+
+func TestMain(m *testing.M) {
+	os.Unsetenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	os.Unsetenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+
+	// Init sets the propagator; spans are recorded instead of exported
+	if _, err := Init(context.Background(), "tracing-test"); err != nil {
+		panic(err)
+	}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	os.Exit(m.Run())
+}
+
+func endedSpan(t *testing.T, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no ended span %q", name)
+	return nil
+}
+
+func TestTraceCrossesPublishAndConsume(t *testing.T) {
+	producer := NewTracer("github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/producer")
+	consumer := NewTracer("github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/consumer")
+	memory := bus.NewMemory()
+
+	// The command handled by the producer service
+	ctx, root := producer.Start(context.Background(), "handle command")
+
+	msg := &bus.Message{Topic: "bot-responses", Key: []byte("10"), Value: []byte("{}")}
+	_, publishSpan := producer.StartProducerSpan(ctx, msg)
+	err := memory.Publish(ctx, msg)
+	End(publishSpan, err)
+	root.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumed := make(chan trace.SpanContext, 1)
+	subCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = memory.Subscribe(subCtx, bus.Subscription{Topic: "bot-responses", Group: "frontend"}, func(ctx context.Context, message *bus.Message) error {
+		_, span := consumer.StartConsumerSpan(ctx, message, "frontend")
+		End(span, nil)
+		consumed <- span.SpanContext()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var consumeSpan trace.SpanContext
+	select {
+	case consumeSpan = <-consumed:
+	case <-time.After(time.Second):
+		t.Fatal("message was not consumed")
+	}
+
+	if consumeSpan.TraceID() != root.SpanContext().TraceID() {
+		t.Errorf("consumer trace %s, want the producer trace %s", consumeSpan.TraceID(), root.SpanContext().TraceID())
+	}
+
+	process := endedSpan(t, "bot-responses process")
+	if process.Parent().SpanID() != publishSpan.SpanContext().SpanID() {
+		t.Errorf("consumer span parent = %s, want the publish span %s", process.Parent().SpanID(), publishSpan.SpanContext().SpanID())
+	}
+	if process.SpanKind() != trace.SpanKindConsumer || process.InstrumentationScope().Name != "github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/consumer" {
+		t.Errorf("consumer span kind %s, scope %s", process.SpanKind(), process.InstrumentationScope().Name)
+	}
+
+	publish := endedSpan(t, "bot-responses publish")
+	if publish.SpanKind() != trace.SpanKindProducer || publish.InstrumentationScope().Name != "github.com/FlavioMalvestitiJunior/bf-offers/backend/internal/producer" {
+		t.Errorf("producer span kind %s, scope %s", publish.SpanKind(), publish.InstrumentationScope().Name)
+	}
+	if publish.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Error("publish span is not a child of the command span")
+	}
+}
+
+func TestProducerSpanReplacesTheTraceOfAResentMessage(t *testing.T) {
+	tracer := NewTracer("test")
+
+	ctx, first := tracer.Start(context.Background(), "first")
+	msg := &bus.Message{Topic: "offers"}
+	_, firstPublish := tracer.StartProducerSpan(ctx, msg)
+	firstPublish.End()
+	first.End()
+
+	ctx, second := tracer.Start(context.Background(), "dead-letter")
+	_, secondPublish := tracer.StartProducerSpan(ctx, msg)
+	secondPublish.End()
+	second.End()
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), msg.Headers))
+	if got.SpanID() != secondPublish.SpanContext().SpanID() {
+		t.Errorf("headers carry span %s, want the last publish span %s", got.SpanID(), secondPublish.SpanContext().SpanID())
+	}
+}
+
+func TestInjectExtractRoundTrip(t *testing.T) {
+	ctx, span := NewTracer("test").Start(context.Background(), "add wishlist")
+	defer span.End()
+
+	// Stored with an outbox event and published later
+	stored := Inject(ctx)
+	if stored["traceparent"] == "" {
+		t.Fatalf("Inject() = %v, want a traceparent", stored)
+	}
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), stored))
+	if !got.IsRemote() || got.TraceID() != span.SpanContext().TraceID() || got.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("Extract() = %v, want the remote span context %v", got, span.SpanContext())
+	}
+
+	if empty := trace.SpanContextFromContext(Extract(context.Background(), nil)); empty.IsValid() {
+		t.Errorf("Extract(nil) = %v, want no span context", empty)
+	}
+}
+
+func TestEndRecordsErrors(t *testing.T) {
+	_, span := NewTracer("test").Start(context.Background(), "failing")
+	End(span, errors.New("broker unavailable"))
+
+	ended := endedSpan(t, "failing")
+	if ended.Status().Code != codes.Error || ended.Status().Description != "broker unavailable" || len(ended.Events()) != 1 {
+		t.Errorf("status = %+v, events = %d, want the error recorded", ended.Status(), len(ended.Events()))
+	}
+}
@@ -0,0 +1,255 @@
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	scraper "github.com/yourusername/bf-offers/scraper/app"
+)
+
+func TestMain(m *testing.M) {
+	stop := SetupPostgres()
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// iPhoneOffer is an iPhone 15 offer found by the scraper on Promobit
+func iPhoneOffer(price float64) scraper.Offer {
+	return scraper.Offer{
+		ExternalID:    "101",
+		Seller:        "Loja Exemplo",
+		ProductName:   "Apple iPhone 15 128GB Preto",
+		Price:         price,
+		OriginalPrice: 4999,
+		URL:           "https://www.promobit.com.br/oferta/101",
+		Source:        "promobit-api",
+	}
+}
+
+// withOriginalPrice returns the offer claiming another original price
+func withOriginalPrice(offer scraper.Offer, originalPrice float64) scraper.Offer {
+	offer.OriginalPrice = originalPrice
+	return offer
+}
+
+// addWishlistItem registers the user and adds an item to their wishlist. It
+// returns the id of the item.
+func addWishlistItem(h *Harness, userID int64, command string) int {
+	h.Say(userID, "/start")
+	h.WaitForReply(userID, "Bem-vindo")
+	h.Settle()
+
+	h.Say(userID, command)
+	reply := h.WaitForReply(userID, "Produto adicionado")
+	h.Settle()
+
+	return h.WishlistID(reply)
+}
+
+// publishOnPastDays publishes the offer at each price, one day apart, ending
+// the day before today
+func publishOnPastDays(h *Harness, prices ...float64) {
+	for _, price := range prices {
+		h.PublishOffer(iPhoneOffer(price))
+		h.Settle()
+		h.PassDays(1)
+	}
+}
+
+func TestOfferBelowTargetPriceNotifiesOnce(t *testing.T) {
+	h := Start(t)
+	const user = 1001
+
+	addWishlistItem(h, user, "/add iPhone 15 R$4000")
+
+	// The scraper finds the offer, and again on its next run
+	h.PublishOffer(iPhoneOffer(3900))
+	h.PublishOffer(iPhoneOffer(3900))
+	h.Settle()
+
+	notifications := h.Notifications(user)
+	if len(notifications) != 1 {
+		t.Fatalf("Got %d notifications, want 1:%s", len(notifications), describe(notifications))
+	}
+	if text := notifications[0].Text; !strings.Contains(text, "R$ 3900.00") || !strings.Contains(text, "Atingiu seu preço desejado") {
+		t.Errorf("Unexpected notification: %q", text)
+	}
+	if markup := notifications[0].ReplyMarkup; !strings.Contains(markup, "https://www.promobit.com.br/oferta/101") {
+		t.Errorf("Notification has no link to the offer: %q", markup)
+	}
+}
+
+func TestOfferAboveTargetPriceDoesNotNotify(t *testing.T) {
+	h := Start(t)
+	const user = 1002
+
+	addWishlistItem(h, user, "/add iPhone 15 R$4000")
+
+	h.PublishOffer(iPhoneOffer(4500))
+	h.Settle()
+
+	if notifications := h.Notifications(user); len(notifications) != 0 {
+		t.Fatalf("Got %d notifications, want none:%s", len(notifications), describe(notifications))
+	}
+}
+
+func TestPriceDropAfterMissNotifies(t *testing.T) {
+	h := Start(t)
+	const user = 1003
+
+	addWishlistItem(h, user, "/add iPhone 15 R$4000")
+
+	h.PublishOffer(iPhoneOffer(4500))
+	h.Settle()
+	h.PublishOffer(iPhoneOffer(3800))
+	h.Settle()
+
+	notifications := h.Notifications(user)
+	if len(notifications) != 1 {
+		t.Fatalf("Got %d notifications, want 1:%s", len(notifications), describe(notifications))
+	}
+	if text := notifications[0].Text; !strings.Contains(text, "R$ 3800.00") {
+		t.Errorf("Notification is not about the new price: %q", text)
+	}
+}
+
+func TestOtherUsersAreNotNotified(t *testing.T) {
+	h := Start(t)
+	const user, other = 1004, 1005
+
+	addWishlistItem(h, user, "/add iPhone 15 R$4000")
+	addWishlistItem(h, other, "/add Samsung TV R$2000")
+
+	h.PublishOffer(iPhoneOffer(3900))
+	h.Settle()
+
+	if notifications := h.Notifications(user); len(notifications) != 1 {
+		t.Errorf("Got %d notifications for the iPhone wishlist, want 1:%s", len(notifications), describe(notifications))
+	}
+	if notifications := h.Notifications(other); len(notifications) != 0 {
+		t.Errorf("Got %d notifications for the TV wishlist, want none:%s", len(notifications), describe(notifications))
+	}
+}
+
+func TestPriceDropAfterNotificationNotifiesAgain(t *testing.T) {
+	h := Start(t)
+	const user = 1006
+
+	addWishlistItem(h, user, "/add iPhone 15 R$4000")
+
+	// The repeated offer is deduplicated, the lower price is news
+	h.PublishOffer(iPhoneOffer(3900))
+	h.Settle()
+	h.PublishOffer(iPhoneOffer(3900))
+	h.Settle()
+	h.PublishOffer(iPhoneOffer(3700))
+	h.Settle()
+
+	notifications := h.Notifications(user)
+	if len(notifications) != 2 {
+		t.Fatalf("Got %d notifications, want 2:%s", len(notifications), describe(notifications))
+	}
+	if !strings.Contains(notifications[0].Text, "R$ 3900.00") || !strings.Contains(notifications[1].Text, "R$ 3700.00") {
+		t.Errorf("Notifications are not about 3900 then 3700:%s", describe(notifications))
+	}
+}
+
+func TestAddReportsHowOftenTheTargetWasHit(t *testing.T) {
+	h := Start(t)
+	const user = 1007
+
+	publishOnPastDays(h, 3900, 4500, 3800, 4200)
+
+	addWishlistItem(h, user, "/add iPhone 15 R$4000")
+
+	summary := h.WaitForReply(user, "Histórico dos últimos 30 dias")
+	if !strings.Contains(summary.Text, "Condição atingida em 2 dia(s) (2 alerta(s))") || !strings.Contains(summary.Text, "Menor preço: R$ 3800.00") {
+		t.Errorf("Unexpected backtest summary: %q", summary.Text)
+	}
+
+	// Nothing is notified for past offers
+	if notifications := h.Notifications(user); len(notifications) != 0 {
+		t.Errorf("Got %d notifications, want none:%s", len(notifications), describe(notifications))
+	}
+}
+
+func TestInflatedOriginalPriceIsFlagged(t *testing.T) {
+	h := Start(t)
+	const user, hiding = 1008, 1009
+
+	// The iPhone sold for at most R$ 4400 in the last days
+	publishOnPastDays(h, 4300, 4400, 4200)
+
+	addWishlistItem(h, user, "/add iPhone 15 R$4000")
+	addWishlistItem(h, hiding, "/add iPhone 15 R$4000")
+	h.Say(hiding, "/suspeitas ocultar")
+	h.WaitForReply(hiding, "não serão mais enviadas")
+	h.Settle()
+
+	// "De R$ 7999 por R$ 3900": the original price was never charged
+	h.PublishOffer(withOriginalPrice(iPhoneOffer(3900), 7999))
+	h.Settle()
+
+	notifications := h.Notifications(user)
+	if len(notifications) != 1 {
+		t.Fatalf("Got %d notifications, want 1:%s", len(notifications), describe(notifications))
+	}
+	if text := notifications[0].Text; !strings.Contains(text, "Preço de referência suspeito") || !strings.Contains(text, "R$ 4400.00") {
+		t.Errorf("Notification is not flagged: %q", text)
+	}
+	if notifications := h.Notifications(hiding); len(notifications) != 0 {
+		t.Errorf("Got %d notifications for the user hiding suspicious offers, want none:%s", len(notifications), describe(notifications))
+	}
+}
+
+func TestHistoryShowsPastPrices(t *testing.T) {
+	h := Start(t)
+	const user = 1010
+
+	id := addWishlistItem(h, user, "/add iPhone 15 R$3000")
+
+	publishOnPastDays(h, 4200)
+	h.PublishOffer(iPhoneOffer(4000))
+	h.Settle()
+
+	h.Say(user, fmt.Sprintf("/historico %d", id))
+	reply := h.WaitForReply(user, "Histórico de preços")
+
+	if reply.Method != "sendPhoto" {
+		t.Errorf("History sent with %s, want a photo of the chart", reply.Method)
+	}
+	if !strings.Contains(reply.Text, "R$ 4000.00") || !strings.Contains(reply.Text, "mín R$ 4000.00 · média R$ 4100.00") {
+		t.Errorf("Unexpected price history: %q", reply.Text)
+	}
+}
+
+func TestDeletedItemIsNoLongerNotified(t *testing.T) {
+	h := Start(t)
+	const user = 1011
+
+	id := addWishlistItem(h, user, "/add iPhone 15 R$4000")
+
+	h.Say(user, fmt.Sprintf("/delete %d", id))
+	h.WaitForReply(user, "Produto removido")
+	// The backend index drops the item once the outbox publishes the event
+	h.Settle()
+
+	var published int
+	err := h.DB.QueryRow(`SELECT COUNT(*) FROM wishlist_outbox WHERE event_type = 'deleted' AND wishlist_id = $1 AND sent_at IS NOT NULL`, id).Scan(&published)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 1 {
+		t.Errorf("Got %d published delete events, want 1", published)
+	}
+
+	h.PublishOffer(iPhoneOffer(3900))
+	h.Settle()
+
+	if notifications := h.Notifications(user); len(notifications) != 0 {
+		t.Fatalf("Got %d notifications, want none:%s", len(notifications), describe(notifications))
+	}
+}
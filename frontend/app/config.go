@@ -0,0 +1,54 @@
+package app
+
+import (
+	"log"
+	"os"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Config holds application configuration
+type Config struct {
+	TelegramToken       string
+	TelegramAPIEndpoint string // Bot API endpoint, "<url>/bot%s/%s", e.g. of a fake server in tests
+	KafkaBrokers        string
+	KafkaCommandTopic   string
+	KafkaResponseTopic  string
+	KafkaGroupID        string
+	Port                string
+	CommandTimeout      time.Duration // How long to wait for the backend to answer a command
+}
+
+// LoadConfig loads configuration from environment variables
+func LoadConfig() Config {
+	return Config{
+		TelegramToken:       getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramAPIEndpoint: getEnv("TELEGRAM_API_ENDPOINT", tgbotapi.APIEndpoint),
+		KafkaBrokers:        getEnv("KAFKA_BROKERS", "kafka:9092"),
+		KafkaCommandTopic:   getEnv("KAFKA_COMMAND_TOPIC", "bot-commands"),
+		KafkaResponseTopic:  getEnv("KAFKA_RESPONSE_TOPIC", "bot-responses"),
+		KafkaGroupID:        getEnv("KAFKA_GROUP_ID", "telegram-bot-consumer"),
+		Port:                getEnv("FRONTEND_PORT", "8081"),
+		CommandTimeout:      getEnvDuration("COMMAND_TIMEOUT", 30*time.Second),
+	}
+}
+
+// getEnv gets an environment variable with a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "30s") with a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration for %s: %s, using %v", key, value, defaultValue)
+	}
+	return defaultValue
+}
@@ -0,0 +1,99 @@
+// Package app runs the frontend: it forwards the Telegram commands to the
+// backend through the message bus and sends the responses back to the users.
+// main runs it against Telegram and Kafka; the all-in-one command runs it in
+// process.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/health"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/bot"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/consumer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/metrics"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TelegramClient sends requests to the Telegram Bot API
+type TelegramClient = bot.TelegramClient
+
+// Frontend is a started frontend
+type Frontend struct {
+	// Checker holds the readiness check of the responses consumer. Callers add
+	// the checks of Telegram and of their message bus.
+	Checker *health.Checker
+
+	botHandler *bot.BotHandler
+}
+
+// Start starts the consumer of the backend responses. It stops when ctx is
+// cancelled. Updates received from Telegram are passed to HandleUpdate.
+func Start(ctx context.Context, config Config, telegram TelegramClient, messageBus bus.Bus) (*Frontend, error) {
+	// Initialize bot handler
+	botHandler := bot.NewBotHandler(telegram, messageBus, config.KafkaCommandTopic, config.CommandTimeout)
+
+	// Readiness checks of the dependencies, served on /health/ready
+	checker := health.NewChecker(5 * time.Second)
+
+	// Start Kafka consumer for receiving responses from backend
+	responsesGroup, err := consumer.StartConsumerGroup(
+		ctx,
+		messageBus,
+		config.KafkaResponseTopic,
+		config.KafkaGroupID,
+		bus.DefaultRetryPolicy,
+		bus.NewDeadLetterWriter(messageBus),
+		botHandler,
+	)
+	if err != nil {
+		log.Printf("Kafka consumer error: %v", err)
+		checker.Add("kafka_consumer_responses", func(context.Context) error {
+			return fmt.Errorf("failed to start: %w", err)
+		})
+	} else {
+		checker.Add("kafka_consumer_responses", responsesGroup.Check)
+	}
+
+	return &Frontend{Checker: checker, botHandler: botHandler}, nil
+}
+
+// HandleUpdate handles an update received from Telegram
+func (f *Frontend) HandleUpdate(update tgbotapi.Update) {
+	f.botHandler.HandleUpdate(update)
+}
+
+// ReceiveUpdates polls Telegram for updates and handles them until ctx is
+// cancelled
+func (f *Frontend) ReceiveUpdates(ctx context.Context, telegramBot *tgbotapi.BotAPI) {
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+
+	updates := telegramBot.GetUpdatesChan(u)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping Telegram bot...")
+			telegramBot.StopReceivingUpdates()
+			return
+		case update := <-updates:
+			go f.HandleUpdate(update)
+		}
+	}
+}
+
+// Handler serves /health/live for liveness and /health/ready for readiness
+// probes and the Prometheus metrics on /metrics
+func (f *Frontend) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", f.Checker.LiveHandler)
+	mux.HandleFunc("/health/ready", f.Checker.ReadyHandler)
+	mux.HandleFunc("/health", f.Checker.LiveHandler) // Kept for older probes
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
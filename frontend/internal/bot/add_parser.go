@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/models"
+)
+
+// parseAddArgs parses the arguments of the /add command into an add_wishlist command.
+//
+// Conditions are read from the end of the arguments:
+//   - "cashback 10%" - minimum cashback
+//   - "efetivo R$3000" - maximum price after cashback
+//   - one of "R$4000", "3000-4000" or "30%" - target price, price range or discount
+//
+// Conditions can be combined (all of them must be met). A bare number is only
+// read as a price when it is the last argument, so "iPhone 15 cashback 10%"
+// keeps the 15 in the product name. Words starting with "-" are excluded terms.
+// Errors are user-facing messages.
+func parseAddArgs(args string) (*models.Command, error) {
+	allParts := strings.Fields(args)
+	if len(allParts) < 2 {
+		return nil, errors.New("❌ Você precisa especificar o produto e o preço/desconto!\n\nExemplos:\n`/add iPhone 15 R$4000`\n`/add Samsung TV 30%`")
+	}
+
+	cmd := &models.Command{Type: "add_wishlist"}
+	parts := allParts
+	hasCondition := false
+	hasBase := false
+
+	for len(parts) > 1 {
+		n := len(parts)
+		atEnd := n == len(allParts)
+
+		if n > 2 {
+			ok, err := parseKeywordCondition(cmd, parts[n-2], parts[n-1], atEnd)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				hasCondition = true
+				parts = parts[:n-2]
+				continue
+			}
+		}
+
+		if hasBase || (!atEnd && !isExplicitCondition(parts[n-1])) {
+			break
+		}
+
+		if err := parseBaseCondition(cmd, parts[n-1]); err != nil {
+			if atEnd {
+				return nil, err
+			}
+			break
+		}
+		hasCondition = true
+		hasBase = true
+		parts = parts[:n-1]
+	}
+
+	if !hasCondition {
+		return nil, errors.New("❌ Você precisa especificar o preço, desconto ou cashback!\n\nExemplos:\n`/add iPhone 15 R$4000`\n`/add Notebook cashback 10%`")
+	}
+
+	// Words starting with "-" are terms to exclude from matching
+	var productWords []string
+	for _, part := range parts {
+		if len(part) > 1 && strings.HasPrefix(part, "-") {
+			cmd.ExcludedTerms = append(cmd.ExcludedTerms, strings.ToLower(strings.TrimPrefix(part, "-")))
+			continue
+		}
+		productWords = append(productWords, part)
+	}
+
+	if len(productWords) == 0 {
+		return nil, errors.New("❌ Você precisa especificar o produto!\n\nExemplo: `/add iPhone 15 -capa R$4000`")
+	}
+	cmd.ProductName = strings.Join(productWords, " ")
+
+	return cmd, nil
+}
+
+// parseEditArgs parses the arguments of the /editar command, the id of the
+// item followed by the arguments of /add, into an update_wishlist command.
+// Errors are user-facing messages.
+func parseEditArgs(args string) (*models.Command, error) {
+	idArg, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	id, err := strconv.Atoi(idArg)
+	if err != nil || id <= 0 {
+		return nil, errors.New("❌ Você precisa especificar o ID do produto e as novas condições!\n\nUse `/list` para ver os IDs.\n\nExemplo: `/editar 1 iPhone 15 R$3800`")
+	}
+
+	cmd, err := parseAddArgs(rest)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Type = "update_wishlist"
+	cmd.WishlistID = id
+
+	return cmd, nil
+}
+
+// parseKeywordCondition parses "cashback <n>%" and "efetivo <preço>" conditions.
+// It returns false when keyword is not a condition keyword.
+func parseKeywordCondition(cmd *models.Command, keyword, value string, atEnd bool) (bool, error) {
+	switch strings.ToLower(keyword) {
+	case "cashback":
+		percent, err := parsePercent(value)
+		if err != nil {
+			if atEnd {
+				return false, errors.New("❌ Cashback inválido! Use um número entre 1 e 100.\n\nExemplo: `/add Notebook cashback 10%`")
+			}
+			return false, nil
+		}
+		cmd.MinCashback = &percent
+		return true, nil
+	case "efetivo":
+		price, err := parsePrice(value)
+		if err != nil || price <= 0 {
+			if atEnd {
+				return false, errors.New("❌ Preço efetivo inválido!\n\nExemplo: `/add Notebook efetivo R$3000`")
+			}
+			return false, nil
+		}
+		cmd.MaxEffectivePrice = &price
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// parseBaseCondition parses a target price, price range or discount
+func parseBaseCondition(cmd *models.Command, value string) error {
+	// Check if it's a percentage
+	if strings.HasSuffix(value, "%") {
+		percent, err := parsePercent(value)
+		if err != nil {
+			return errors.New("❌ Desconto inválido! Use um número entre 1 e 100.\n\nExemplo: `/add Samsung TV 30%`")
+		}
+		cmd.DiscountPercentage = &percent
+		return nil
+	}
+
+	// Check if it's a price range (min-max)
+	if minStr, maxStr, isRange := strings.Cut(value, "-"); isRange {
+		rangeMin, minErr := parsePrice(minStr)
+		rangeMax, maxErr := parsePrice(maxStr)
+		if minErr != nil || maxErr != nil || rangeMin <= 0 || rangeMax < rangeMin {
+			return errors.New("❌ Faixa de preço inválida!\n\nExemplo: `/add iPhone 15 3000-4000`")
+		}
+		cmd.MinPrice = &rangeMin
+		cmd.TargetPrice = &rangeMax
+		return nil
+	}
+
+	price, err := parsePrice(value)
+	if err != nil || price <= 0 {
+		return errors.New("❌ Preço inválido!\n\nExemplo: `/add iPhone 15 R$4000` ou `/add iPhone 15 4000`")
+	}
+	cmd.TargetPrice = &price
+	return nil
+}
+
+// isExplicitCondition checks if a word can only be a condition, never part of a product name
+func isExplicitCondition(value string) bool {
+	if strings.HasPrefix(value, "R$") || strings.HasSuffix(value, "%") {
+		return true
+	}
+
+	minStr, maxStr, isRange := strings.Cut(value, "-")
+	if !isRange {
+		return false
+	}
+	_, minErr := parsePrice(minStr)
+	_, maxErr := parsePrice(maxStr)
+	return minErr == nil && maxErr == nil
+}
+
+// parsePrice parses a price like "R$4000", "4000" or "3999,90". NaN and
+// infinities are rejected.
+func parsePrice(s string) (float64, error) {
+	priceStr := strings.ReplaceAll(s, "R$", "")
+	priceStr = strings.ReplaceAll(priceStr, ",", ".")
+	price, err := strconv.ParseFloat(priceStr, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, fmt.Errorf("invalid price: %s", s)
+	}
+	return price, nil
+}
+
+// parsePercent parses a percentage like "30%" between 1 and 100
+func parsePercent(s string) (int, error) {
+	percent, err := strconv.Atoi(strings.TrimSuffix(s, "%"))
+	if err != nil {
+		return 0, err
+	}
+	if percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("percentage out of range: %d", percent)
+	}
+	return percent, nil
+}
+
+// formatConditions describes the conditions of a wishlist item, one per line
+func formatConditions(targetPrice, minPrice *float64, discountPercentage, minCashback *int, maxEffectivePrice *float64, indent string) string {
+	var text strings.Builder
+
+	if minPrice != nil && targetPrice != nil {
+		text.WriteString(fmt.Sprintf("%s💰 Faixa de preço: R$ %.2f - R$ %.2f\n", indent, *minPrice, *targetPrice))
+	} else if targetPrice != nil {
+		text.WriteString(fmt.Sprintf("%s💰 Preço desejado: R$ %.2f\n", indent, *targetPrice))
+	}
+	if discountPercentage != nil {
+		text.WriteString(fmt.Sprintf("%s🔥 Desconto mínimo: %d%%\n", indent, *discountPercentage))
+	}
+	if minCashback != nil {
+		text.WriteString(fmt.Sprintf("%s💸 Cashback mínimo: %d%%\n", indent, *minCashback))
+	}
+	if maxEffectivePrice != nil {
+		text.WriteString(fmt.Sprintf("%s💳 Preço efetivo (com cashback): R$ %.2f\n", indent, *maxEffectivePrice))
+	}
+
+	return text.String()
+}
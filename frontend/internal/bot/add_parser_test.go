@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/models"
+)
+
+// describe lists the fields of an add command set by the parser
+func describe(cmd *models.Command) string {
+	fields := []string{"produto=" + cmd.ProductName}
+	if cmd.MinPrice != nil {
+		fields = append(fields, fmt.Sprintf("min=%g", *cmd.MinPrice))
+	}
+	if cmd.TargetPrice != nil {
+		fields = append(fields, fmt.Sprintf("alvo=%g", *cmd.TargetPrice))
+	}
+	if cmd.DiscountPercentage != nil {
+		fields = append(fields, fmt.Sprintf("desconto=%d", *cmd.DiscountPercentage))
+	}
+	if cmd.MinCashback != nil {
+		fields = append(fields, fmt.Sprintf("cashback=%d", *cmd.MinCashback))
+	}
+	if cmd.MaxEffectivePrice != nil {
+		fields = append(fields, fmt.Sprintf("efetivo=%g", *cmd.MaxEffectivePrice))
+	}
+	if len(cmd.ExcludedTerms) > 0 {
+		fields = append(fields, "sem="+strings.Join(cmd.ExcludedTerms, ","))
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestParseAddArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"iPhone 15 R$4000", "produto=iPhone 15 alvo=4000"},
+		{"iPhone 15 4000", "produto=iPhone 15 alvo=4000"},
+		{"iPhone 15", "produto=iPhone alvo=15"},
+		{"Notebook R$3999,90", "produto=Notebook alvo=3999.9"},
+		{"Samsung TV 30%", "produto=Samsung TV desconto=30"},
+		{"iPhone 15 3000-4000", "produto=iPhone 15 min=3000 alvo=4000"},
+		{"iPhone 15 R$3000-R$4000", "produto=iPhone 15 min=3000 alvo=4000"},
+		{"Notebook cashback 10%", "produto=Notebook cashback=10"},
+		{"Notebook CASHBACK 10", "produto=Notebook cashback=10"},
+		{"Notebook efetivo R$3000", "produto=Notebook efetivo=3000"},
+		{"iPhone 15 cashback 10%", "produto=iPhone 15 cashback=10"},
+		{"iPhone 15 R$4000 cashback 10%", "produto=iPhone 15 alvo=4000 cashback=10"},
+		{"Notebook 20% cashback 5% efetivo 3500", "produto=Notebook desconto=20 cashback=5 efetivo=3500"},
+		{"iPhone 15 -capa -pelicula R$4000", "produto=iPhone 15 alvo=4000 sem=capa,pelicula"},
+		{"Air Fryer -Mondial 400", "produto=Air Fryer alvo=400 sem=mondial"},
+		{"Cabo USB-C 50", "produto=Cabo USB-C alvo=50"},
+		{"Fone - R$100", "produto=Fone - alvo=100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			cmd, err := parseAddArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseAddArgs(%q) error = %v", tt.args, err)
+			}
+			if cmd.Type != "add_wishlist" {
+				t.Errorf("Type = %q, want add_wishlist", cmd.Type)
+			}
+			if got := describe(cmd); got != tt.want {
+				t.Errorf("parseAddArgs(%q) = %s, want %s", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAddArgsErrors(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "especificar o produto e o preço"},
+		{"iPhone", "especificar o produto e o preço"},
+		{"iPhone R$abc", "Preço inválido"},
+		{"iPhone R$0", "Preço inválido"},
+		{"iPhone -100", "Faixa de preço inválida"},
+		{"iPhone 4000-3000", "Faixa de preço inválida"},
+		{"iPhone 0-3000", "Faixa de preço inválida"},
+		{"TV 0%", "Desconto inválido"},
+		{"TV 101%", "Desconto inválido"},
+		{"Notebook cashback 0%", "Cashback inválido"},
+		{"Notebook cashback muito", "Cashback inválido"},
+		{"Notebook efetivo 0", "Preço efetivo inválido"},
+		{"-capa R$4000", "especificar o produto!"},
+		{"iPhone NaN", "Preço inválido"},
+		{"iPhone R$Inf", "Preço inválido"},
+		{"iPhone -Infinity", "Faixa de preço inválida"},
+		{"iPhone 1e400", "Preço inválido"},
+		{"iPhone NaN-Inf", "Faixa de preço inválida"},
+		{"iPhone 100-Inf", "Faixa de preço inválida"},
+		{"Notebook efetivo NaN", "Preço efetivo inválido"},
+		{"Notebook efetivo +Inf", "Preço efetivo inválido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			cmd, err := parseAddArgs(tt.args)
+			if err == nil {
+				t.Fatalf("parseAddArgs(%q) = %s, want error %q", tt.args, describe(cmd), tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseAddArgs(%q) error = %q, want %q", tt.args, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEditArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+		err  string
+	}{
+		{"3 iPhone 15 R$3800", "3 produto=iPhone 15 alvo=3800", ""},
+		{" 12  Notebook -usado cashback 10%", "12 produto=Notebook cashback=10 sem=usado", ""},
+		{"", "", "especificar o ID do produto"},
+		{"iPhone 15 R$3800", "", "especificar o ID do produto"},
+		{"0 iPhone R$3800", "", "especificar o ID do produto"},
+		{"3", "", "especificar o produto e o preço"},
+		{"3 iPhone R$abc", "", "Preço inválido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			cmd, err := parseEditArgs(tt.args)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("parseEditArgs(%q) error = %v, want %q", tt.args, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEditArgs(%q) error = %v", tt.args, err)
+			}
+			if cmd.Type != "update_wishlist" {
+				t.Errorf("parseEditArgs(%q) type = %s, want update_wishlist", tt.args, cmd.Type)
+			}
+			if got := fmt.Sprintf("%d %s", cmd.WishlistID, describe(cmd)); got != tt.want {
+				t.Errorf("parseEditArgs(%q) = %s, want %s", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"4000", 4000, false},
+		{"R$4000", 4000, false},
+		{"3999,90", 3999.9, false},
+		{"R$0,99", 0.99, false},
+		{"", 0, true},
+		{"R$", 0, true},
+		{"quatro", 0, true},
+		{"NaN", 0, true},
+		{"nan", 0, true},
+		{"Inf", 0, true},
+		{"-Inf", 0, true},
+		{"+Infinity", 0, true},
+		{"1e400", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePrice(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parsePrice(%q) = %g, %v, want %g, error %t", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
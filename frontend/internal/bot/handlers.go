@@ -1,334 +1,674 @@
-package bot
-
-import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"strconv"
-	"strings"
-	"time"
-
-	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/models"
-	"github.com/IBM/sarama"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-)
-
-type BotHandler struct {
-	bot           *tgbotapi.BotAPI
-	kafkaProducer sarama.SyncProducer
-	commandTopic  string
-}
-
-func NewBotHandler(bot *tgbotapi.BotAPI, kafkaProducer sarama.SyncProducer, commandTopic string) *BotHandler {
-	return &BotHandler{
-		bot:           bot,
-		kafkaProducer: kafkaProducer,
-		commandTopic:  commandTopic,
-	}
-}
-
-// HandleUpdate handles incoming Telegram updates
-func (h *BotHandler) HandleUpdate(update tgbotapi.Update) {
-	if update.Message == nil {
-		return
-	}
-
-	// Handle commands
-	if update.Message.IsCommand() {
-		h.handleCommand(update.Message)
-		return
-	}
-
-	// Handle regular messages
-	h.sendMessage(update.Message.Chat.ID, "Use /help para ver os comandos disponíveis.")
-}
-
-// handleCommand handles bot commands
-func (h *BotHandler) handleCommand(message *tgbotapi.Message) {
-	switch message.Command() {
-	case "start":
-		h.handleStart(message)
-	case "help":
-		h.handleHelp(message)
-	case "add":
-		h.handleAdd(message)
-	case "list":
-		h.handleList(message)
-	case "delete", "del":
-		h.handleDelete(message)
-	default:
-		h.sendMessage(message.Chat.ID, "Comando não reconhecido. Use /help para ver os comandos disponíveis.")
-	}
-}
-
-// handleStart handles the /start command
-func (h *BotHandler) handleStart(message *tgbotapi.Message) {
-	text := `🎉 *Bem-vindo ao Bot de Ofertas!*
-
-Eu vou te ajudar a monitorar ofertas e cashbacks de produtos!
-
-*Como funciona:*
-1️⃣ Adicione produtos à sua lista de desejos
-2️⃣ Defina um preço desejado ou desconto mínimo
-3️⃣ Receba notificações quando encontrarmos ofertas!
-
-*Comandos disponíveis:*
-/add - Adicionar produto à lista
-/list - Ver sua lista de desejos
-/delete - Remover produto da lista
-/help - Ver esta mensagem
-
-*Exemplos:*
-` + "`/add iPhone 15 R$4000`" + `
-` + "`/add Samsung TV 30%`" + `
-
-Vamos começar? Use /add para adicionar seu primeiro produto! 🚀`
-
-	h.sendMessage(message.Chat.ID, text)
-
-	// Send user registration command to backend via Kafka
-	h.sendCommandToBackend(models.Command{
-		Type:       "register_user",
-		TelegramID: message.From.ID,
-		Username:   message.From.UserName,
-		FirstName:  message.From.FirstName,
-		LastName:   message.From.LastName,
-	})
-}
-
-// handleHelp handles the /help command
-func (h *BotHandler) handleHelp(message *tgbotapi.Message) {
-	text := `📚 *Ajuda - Comandos Disponíveis*
-
-*Adicionar produto:*
-` + "`/add <produto> <preço|desconto%>`" + `
-
-Exemplos:
-` + "`/add iPhone 15 R$4000`" + ` - Notifica quando preço ≤ R$4000
-` + "`/add Samsung TV 30%`" + ` - Notifica quando desconto ≥ 30%
-` + "`/add Notebook Gamer 25%`" + ` - Notifica quando desconto ≥ 25%
-
-*Listar produtos:*
-` + "`/list`" + ` - Mostra todos os produtos na sua lista
-
-*Remover produto:*
-` + "`/delete <id>`" + ` - Remove produto pelo ID (veja o ID com /list)
-
-Exemplo:
-` + "`/delete 1`" + ` - Remove o produto com ID 1
-
-*Dicas:*
-• Você pode adicionar quantos produtos quiser
-• Use nomes descritivos para facilitar a busca
-• O bot monitora ofertas 24/7! 🔍`
-
-	h.sendMessage(message.Chat.ID, text)
-}
-
-// handleAdd handles the /add command
-func (h *BotHandler) handleAdd(message *tgbotapi.Message) {
-	args := message.CommandArguments()
-	if args == "" {
-		h.sendMessage(message.Chat.ID, "❌ Uso incorreto!\n\nExemplos:\n`/add iPhone 15 R$4000`\n`/add Samsung TV 30%`")
-		return
-	}
-
-	// Parse arguments
-	parts := strings.Fields(args)
-	if len(parts) < 2 {
-		h.sendMessage(message.Chat.ID, "❌ Você precisa especificar o produto e o preço/desconto!\n\nExemplos:\n`/add iPhone 15 R$4000`\n`/add Samsung TV 30%`")
-		return
-	}
-
-	// Get the last part (price or discount)
-	lastPart := parts[len(parts)-1]
-	productName := strings.Join(parts[:len(parts)-1], " ")
-
-	var targetPrice *float64
-	var discountPercentage *int
-
-	// Check if it's a percentage
-	if strings.HasSuffix(lastPart, "%") {
-		percentStr := strings.TrimSuffix(lastPart, "%")
-		percent, err := strconv.Atoi(percentStr)
-		if err != nil || percent <= 0 || percent > 100 {
-			h.sendMessage(message.Chat.ID, "❌ Desconto inválido! Use um número entre 1 e 100.\n\nExemplo: `/add Samsung TV 30%`")
-			return
-		}
-		discountPercentage = &percent
-	} else {
-		// Parse price
-		priceStr := strings.ReplaceAll(lastPart, "R$", "")
-		priceStr = strings.ReplaceAll(priceStr, ",", ".")
-		price, err := strconv.ParseFloat(priceStr, 64)
-		if err != nil || price <= 0 {
-			h.sendMessage(message.Chat.ID, "❌ Preço inválido!\n\nExemplo: `/add iPhone 15 R$4000` ou `/add iPhone 15 4000`")
-			return
-		}
-		targetPrice = &price
-	}
-
-	// Send add command to backend via Kafka
-	h.sendCommandToBackend(models.Command{
-		Type:               "add_wishlist",
-		TelegramID:         message.From.ID,
-		ProductName:        productName,
-		TargetPrice:        targetPrice,
-		DiscountPercentage: discountPercentage,
-	})
-
-	// Send confirmation
-	var confirmText string
-	if targetPrice != nil {
-		confirmText = fmt.Sprintf("✅ *Produto adicionado!*\n\n📦 %s\n💰 Preço desejado: R$ %.2f\n\nVou te avisar quando encontrar uma oferta! 🔔",
-			productName, *targetPrice)
-	} else {
-		confirmText = fmt.Sprintf("✅ *Produto adicionado!*\n\n📦 %s\n🔥 Desconto mínimo: %d%%\n\nVou te avisar quando encontrar uma oferta! 🔔",
-			productName, *discountPercentage)
-	}
-
-	h.sendMessage(message.Chat.ID, confirmText)
-}
-
-// handleList handles the /list command
-func (h *BotHandler) handleList(message *tgbotapi.Message) {
-	// Send list request to backend via Kafka
-	h.sendCommandToBackend(models.Command{
-		Type:       "list_wishlist",
-		TelegramID: message.From.ID,
-		ChatID:     message.Chat.ID,
-	})
-
-	// Backend will respond via Kafka with the list
-	h.sendMessage(message.Chat.ID, "🔍 Buscando sua lista...")
-}
-
-// handleDelete handles the /delete command
-func (h *BotHandler) handleDelete(message *tgbotapi.Message) {
-	args := message.CommandArguments()
-	if args == "" {
-		h.sendMessage(message.Chat.ID, "❌ Você precisa especificar o ID do produto!\n\nUse `/list` para ver os IDs.\n\nExemplo: `/delete 1`")
-		return
-	}
-
-	id, err := strconv.Atoi(args)
-	if err != nil {
-		h.sendMessage(message.Chat.ID, "❌ ID inválido! Use um número.\n\nExemplo: `/delete 1`")
-		return
-	}
-
-	// Send delete command to backend via Kafka
-	h.sendCommandToBackend(models.Command{
-		Type:       "delete_wishlist",
-		TelegramID: message.From.ID,
-		WishlistID: id,
-		ChatID:     message.Chat.ID,
-	})
-
-	h.sendMessage(message.Chat.ID, "🗑️ Removendo produto...")
-}
-
-// SendNotification sends a notification to a user
-func (h *BotHandler) SendNotification(notification *models.OfferNotification) error {
-	var msg strings.Builder
-
-	msg.WriteString("🎉 *Oferta Encontrada!*\n\n")
-	msg.WriteString(fmt.Sprintf("📦 *Produto:* %s\n", notification.ProductName))
-
-	if notification.Price > 0 {
-		msg.WriteString(fmt.Sprintf("💰 *Preço:* R$ %.2f\n", notification.Price))
-	}
-
-	if notification.OriginalPrice > 0 && notification.OriginalPrice > notification.Price {
-		msg.WriteString(fmt.Sprintf("~~R$ %.2f~~\n", notification.OriginalPrice))
-	}
-
-	if notification.DiscountPercentage > 0 {
-		msg.WriteString(fmt.Sprintf("🔥 *Desconto:* %d%%\n", notification.DiscountPercentage))
-	}
-
-	if notification.CashbackPercentage > 0 {
-		msg.WriteString(fmt.Sprintf("💸 *Cashback:* %d%%\n", notification.CashbackPercentage))
-	}
-
-	if notification.MatchType == "price" {
-		msg.WriteString("\n✅ *Atingiu seu preço desejado!*")
-	} else if notification.MatchType == "discount" {
-		msg.WriteString("\n✅ *Atingiu o desconto desejado!*")
-	}
-
-	return h.sendMessage(notification.TelegramID, msg.String())
-}
-
-// SendWishlistResponse sends wishlist data back to user
-func (h *BotHandler) SendWishlistResponse(response *models.WishlistResponse) error {
-	if len(response.Items) == 0 {
-		return h.sendMessage(response.ChatID, "📭 Sua lista está vazia!\n\nUse `/add` para adicionar produtos.\n\nExemplo: `/add iPhone 15 R$4000`")
-	}
-
-	var text strings.Builder
-	text.WriteString("📋 *Sua Lista de Desejos*\n\n")
-
-	for i, w := range response.Items {
-		text.WriteString(fmt.Sprintf("*%d.* %s\n", i+1, w.ProductName))
-		if w.TargetPrice != nil {
-			text.WriteString(fmt.Sprintf("   💰 Preço: R$ %.2f\n", *w.TargetPrice))
-		}
-		if w.DiscountPercentage != nil {
-			text.WriteString(fmt.Sprintf("   🔥 Desconto: %d%%\n", *w.DiscountPercentage))
-		}
-		text.WriteString(fmt.Sprintf("   🆔 ID: `%d`\n\n", w.ID))
-	}
-
-	text.WriteString(fmt.Sprintf("Total: %d produto(s)\n\n", len(response.Items)))
-	text.WriteString("Para remover: `/delete <id>`")
-
-	return h.sendMessage(response.ChatID, text.String())
-}
-
-// SendDeleteResponse sends delete confirmation
-func (h *BotHandler) SendDeleteResponse(response *models.DeleteResponse) error {
-	if response.Success {
-		return h.sendMessage(response.ChatID, "✅ Produto removido da lista!")
-	}
-	return h.sendMessage(response.ChatID, "❌ Produto não encontrado!\n\nUse `/list` para ver os IDs disponíveis.")
-}
-
-// sendCommandToBackend sends a command to the backend via Kafka
-func (h *BotHandler) sendCommandToBackend(cmd models.Command) error {
-	cmd.Timestamp = time.Now()
-
-	data, err := json.Marshal(cmd)
-	if err != nil {
-		log.Printf("Error marshaling command: %v", err)
-		return err
-	}
-
-	msg := &sarama.ProducerMessage{
-		Topic: h.commandTopic,
-		Key:   sarama.StringEncoder(fmt.Sprintf("%d", cmd.TelegramID)),
-		Value: sarama.ByteEncoder(data),
-	}
-
-	_, _, err = h.kafkaProducer.SendMessage(msg)
-	if err != nil {
-		log.Printf("Error sending command to backend: %v", err)
-		return err
-	}
-
-	return nil
-}
-
-// sendMessage sends a message to a chat
-func (h *BotHandler) sendMessage(chatID int64, text string) error {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-
-	_, err := h.bot.Send(msg)
-	if err != nil {
-		log.Printf("Error sending message: %v", err)
-		return err
-	}
-
-	return nil
-}
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/metrics"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracer starts the spans of this package
+var tracer = tracing.NewTracer("github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/bot")
+
+// TelegramClient sends requests to the Telegram Bot API. *tgbotapi.BotAPI
+// implements it; the all-in-one command replaces it with a terminal client.
+type TelegramClient interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+}
+
+type BotHandler struct {
+	bot          TelegramClient
+	publisher    bus.Publisher
+	commandTopic string
+	pending      *pendingRequests
+}
+
+// NewBotHandler creates the bot handler. Commands not answered by the backend
+// within commandTimeout are reported to the user as failed.
+func NewBotHandler(bot TelegramClient, publisher bus.Publisher, commandTopic string, commandTimeout time.Duration) *BotHandler {
+	h := &BotHandler{
+		bot:          bot,
+		publisher:    publisher,
+		commandTopic: commandTopic,
+	}
+	h.pending = newPendingRequests(commandTimeout, h.handleTimeout)
+	return h
+}
+
+// HandleUpdate handles incoming Telegram updates
+func (h *BotHandler) HandleUpdate(update tgbotapi.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	// Handle commands. Each command starts a trace followed by the commands
+	// sent to the backend and the messages they lead to.
+	if update.Message.IsCommand() {
+		ctx, span := tracer.Start(context.Background(), "telegram /"+update.Message.Command(),
+			attribute.Int64("telegram.user_id", update.Message.From.ID),
+			attribute.Int64("telegram.chat_id", update.Message.Chat.ID),
+		)
+		defer span.End()
+
+		h.handleCommand(ctx, update.Message)
+		return
+	}
+
+	// Handle regular messages
+	h.sendMessage(update.Message.Chat.ID, "Use /help para ver os comandos disponíveis.")
+}
+
+// handleCommand handles bot commands
+func (h *BotHandler) handleCommand(ctx context.Context, message *tgbotapi.Message) {
+	switch message.Command() {
+	case "start":
+		h.handleStart(ctx, message)
+	case "help":
+		h.handleHelp(message)
+	case "add":
+		h.handleAdd(ctx, message)
+	case "list":
+		h.handleList(ctx, message)
+	case "editar", "edit":
+		h.handleEdit(ctx, message)
+	case "delete", "del":
+		h.handleDelete(ctx, message)
+	case "historico", "history":
+		h.handleHistory(ctx, message)
+	case "suspeitas":
+		h.handleSuspicious(ctx, message)
+	default:
+		h.sendMessage(message.Chat.ID, "Comando não reconhecido. Use /help para ver os comandos disponíveis.")
+	}
+}
+
+// handleStart handles the /start command
+func (h *BotHandler) handleStart(ctx context.Context, message *tgbotapi.Message) {
+	text := `🎉 *Bem-vindo ao Bot de Ofertas!*
+
+Eu vou te ajudar a monitorar ofertas e cashbacks de produtos!
+
+*Como funciona:*
+1️⃣ Adicione produtos à sua lista de desejos
+2️⃣ Defina um preço desejado ou desconto mínimo
+3️⃣ Receba notificações quando encontrarmos ofertas!
+
+*Comandos disponíveis:*
+/add - Adicionar produto à lista
+/list - Ver sua lista de desejos
+/editar - Alterar produto ou condições
+/delete - Remover produto da lista
+/historico - Ver histórico de preços de um produto
+/suspeitas - Mostrar ou ocultar ofertas com desconto suspeito
+/help - Ver esta mensagem
+
+*Exemplos:*
+` + "`/add iPhone 15 R$4000`" + `
+` + "`/add Samsung TV 30%`" + `
+` + "`/add iPhone 15 -capa -película 3000-4000`" + `
+
+Vamos começar? Use /add para adicionar seu primeiro produto! 🚀`
+
+	h.sendMessage(message.Chat.ID, text)
+
+	// Send user registration command to backend via Kafka
+	h.sendCommandToBackend(ctx, models.Command{
+		Type:       "register_user",
+		TelegramID: message.From.ID,
+		Username:   message.From.UserName,
+		FirstName:  message.From.FirstName,
+		LastName:   message.From.LastName,
+	})
+}
+
+// handleHelp handles the /help command
+func (h *BotHandler) handleHelp(message *tgbotapi.Message) {
+	text := `📚 *Ajuda - Comandos Disponíveis*
+
+*Adicionar produto:*
+` + "`/add <produto> [-termo] [cashback N%] [efetivo preço] <preço|mín-máx|desconto%>`" + `
+
+Exemplos:
+` + "`/add iPhone 15 R$4000`" + ` - Notifica quando preço ≤ R$4000
+` + "`/add Samsung TV 30%`" + ` - Notifica quando desconto ≥ 30%
+` + "`/add Notebook Gamer 25%`" + ` - Notifica quando desconto ≥ 25%
+` + "`/add iPhone 15 3000-4000`" + ` - Notifica quando R$3000 ≤ preço ≤ R$4000
+` + "`/add iPhone 15 -capa -película R$4000`" + ` - Ignora ofertas com "capa" ou "película"
+` + "`/add Notebook cashback 10%`" + ` - Notifica quando cashback ≥ 10%
+` + "`/add Notebook efetivo R$3000`" + ` - Notifica quando preço com cashback ≤ R$3000
+` + "`/add Notebook R$3500 cashback 10%`" + ` - Combina condições (todas precisam ser atendidas)
+
+*Listar produtos:*
+` + "`/list`" + ` - Mostra todos os produtos na sua lista
+
+*Alterar produto:*
+` + "`/editar <id> <produto> <condições>`" + ` - Substitui o produto e as condições, como no /add
+
+Exemplo:
+` + "`/editar 1 iPhone 15 R$3800`" + ` - O produto com ID 1 passa a notificar com preço ≤ R$3800
+
+*Remover produto:*
+` + "`/delete <id>`" + ` - Remove produto pelo ID (veja o ID com /list)
+
+Exemplo:
+` + "`/delete 1`" + ` - Remove o produto com ID 1
+
+*Histórico de preços:*
+` + "`/historico <id>`" + ` - Menor, médio e atual preço em 7, 30 e 90 dias, com gráfico
+
+*Descontos suspeitos:*
+Ofertas com preço "de" acima do que já vimos para o produto vêm marcadas com ⚠️
+` + "`/suspeitas ocultar`" + ` - Não receber essas ofertas
+` + "`/suspeitas mostrar`" + ` - Voltar a receber essas ofertas
+
+*Dicas:*
+• Você pode adicionar quantos produtos quiser
+• Use nomes descritivos para facilitar a busca
+• O bot monitora ofertas 24/7! 🔍`
+
+	h.sendMessage(message.Chat.ID, text)
+}
+
+// handleAdd handles the /add command
+func (h *BotHandler) handleAdd(ctx context.Context, message *tgbotapi.Message) {
+	args := message.CommandArguments()
+	if args == "" {
+		h.sendMessage(message.Chat.ID, "❌ Uso incorreto!\n\nExemplos:\n`/add iPhone 15 R$4000`\n`/add Samsung TV 30%`")
+		return
+	}
+
+	cmd, err := parseAddArgs(args)
+	if err != nil {
+		h.sendMessage(message.Chat.ID, err.Error())
+		return
+	}
+	cmd.TelegramID = message.From.ID
+	cmd.ChatID = message.Chat.ID
+
+	// Backend confirms with the saved item
+	h.sendRequest(ctx, *cmd, "⏳ Adicionando produto...")
+}
+
+// handleList handles the /list command
+func (h *BotHandler) handleList(ctx context.Context, message *tgbotapi.Message) {
+	// Backend will respond via Kafka with the list
+	h.sendRequest(ctx, models.Command{
+		Type:       "list_wishlist",
+		TelegramID: message.From.ID,
+		ChatID:     message.Chat.ID,
+	}, "🔍 Buscando sua lista...")
+}
+
+// handleEdit handles the /editar command
+func (h *BotHandler) handleEdit(ctx context.Context, message *tgbotapi.Message) {
+	cmd, err := parseEditArgs(message.CommandArguments())
+	if err != nil {
+		h.sendMessage(message.Chat.ID, err.Error())
+		return
+	}
+	cmd.TelegramID = message.From.ID
+	cmd.ChatID = message.Chat.ID
+
+	// Backend confirms with the saved item
+	h.sendRequest(ctx, *cmd, "⏳ Alterando produto...")
+}
+
+// handleDelete handles the /delete command
+func (h *BotHandler) handleDelete(ctx context.Context, message *tgbotapi.Message) {
+	args := message.CommandArguments()
+	if args == "" {
+		h.sendMessage(message.Chat.ID, "❌ Você precisa especificar o ID do produto!\n\nUse `/list` para ver os IDs.\n\nExemplo: `/delete 1`")
+		return
+	}
+
+	id, err := strconv.Atoi(args)
+	if err != nil {
+		h.sendMessage(message.Chat.ID, "❌ ID inválido! Use um número.\n\nExemplo: `/delete 1`")
+		return
+	}
+
+	h.sendRequest(ctx, models.Command{
+		Type:       "delete_wishlist",
+		TelegramID: message.From.ID,
+		WishlistID: id,
+		ChatID:     message.Chat.ID,
+	}, "🗑️ Removendo produto...")
+}
+
+// handleHistory handles the /historico command
+func (h *BotHandler) handleHistory(ctx context.Context, message *tgbotapi.Message) {
+	args := message.CommandArguments()
+	if args == "" {
+		h.sendMessage(message.Chat.ID, "❌ Você precisa especificar o ID do produto!\n\nUse `/list` para ver os IDs.\n\nExemplo: `/historico 1`")
+		return
+	}
+
+	id, err := strconv.Atoi(args)
+	if err != nil {
+		h.sendMessage(message.Chat.ID, "❌ ID inválido! Use um número.\n\nExemplo: `/historico 1`")
+		return
+	}
+
+	h.sendRequest(ctx, models.Command{
+		Type:       "price_history",
+		TelegramID: message.From.ID,
+		WishlistID: id,
+		ChatID:     message.Chat.ID,
+	}, "📊 Buscando histórico de preços...")
+}
+
+// handleSuspicious handles the /suspeitas command
+func (h *BotHandler) handleSuspicious(ctx context.Context, message *tgbotapi.Message) {
+	var hide bool
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "ocultar":
+		hide = true
+	case "mostrar":
+		hide = false
+	default:
+		h.sendMessage(message.Chat.ID, "❌ Uso incorreto!\n\nExemplos:\n`/suspeitas ocultar` - Não receber ofertas com preço de referência suspeito\n`/suspeitas mostrar` - Receber essas ofertas com o aviso ⚠️")
+		return
+	}
+
+	h.sendRequest(ctx, models.Command{
+		Type:           "set_hide_suspicious",
+		TelegramID:     message.From.ID,
+		ChatID:         message.Chat.ID,
+		HideSuspicious: &hide,
+	}, "⏳ Salvando preferência...")
+}
+
+// SendNotification sends a notification to a user
+func (h *BotHandler) SendNotification(notification *models.OfferNotification) error {
+	var msg strings.Builder
+
+	msg.WriteString("🎉 *Oferta Encontrada!*\n\n")
+	msg.WriteString(fmt.Sprintf("📦 *Produto:* %s\n", notification.ProductName))
+
+	if notification.Price > 0 {
+		msg.WriteString(fmt.Sprintf("💰 *Preço:* R$ %.2f\n", notification.Price))
+	}
+
+	if notification.OriginalPrice > 0 && notification.OriginalPrice > notification.Price {
+		msg.WriteString(fmt.Sprintf("~~R$ %.2f~~\n", notification.OriginalPrice))
+	}
+
+	if notification.DiscountPercentage > 0 {
+		msg.WriteString(fmt.Sprintf("🔥 *Desconto:* %d%%\n", notification.DiscountPercentage))
+	}
+
+	if notification.CashbackPercentage > 0 {
+		msg.WriteString(fmt.Sprintf("💸 *Cashback:* %d%%\n", notification.CashbackPercentage))
+	}
+
+	if notification.MatchType == "effective_price" && notification.EffectivePrice > 0 {
+		msg.WriteString(fmt.Sprintf("💳 *Preço com cashback:* R$ %.2f\n", notification.EffectivePrice))
+	}
+
+	if notification.SuspiciousReference {
+		msg.WriteString(fmt.Sprintf("\n⚠️ *Preço de referência suspeito:* o maior preço que vimos nos últimos 90 dias foi R$ %.2f\n", notification.HighestPrice))
+	}
+
+	switch notification.MatchType {
+	case "price":
+		msg.WriteString("\n✅ *Atingiu seu preço desejado!*")
+	case "discount":
+		msg.WriteString("\n✅ *Atingiu o desconto desejado!*")
+	case "cashback":
+		msg.WriteString("\n✅ *Atingiu o cashback desejado!*")
+	case "effective_price":
+		msg.WriteString("\n✅ *Com o cashback, atingiu seu preço desejado!*")
+	}
+
+	var markup *tgbotapi.InlineKeyboardMarkup
+	if notification.URL != "" {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonURL("🛒 Ver oferta", notification.URL),
+			),
+		)
+		markup = &keyboard
+	}
+
+	if notification.ImageURL != "" {
+		photo := tgbotapi.NewPhoto(notification.TelegramID, tgbotapi.FileURL(notification.ImageURL))
+		photo.Caption = msg.String()
+		photo.ParseMode = "Markdown"
+		if markup != nil {
+			photo.ReplyMarkup = markup
+		}
+
+		_, err := h.send("sendPhoto", photo)
+		if err == nil {
+			metrics.Notifications.WithLabelValues("sent").Inc()
+			return nil
+		}
+		// Telegram could not fetch the image, send the text alone
+		log.Printf("Error sending photo, falling back to text: %v", err)
+	}
+
+	text := tgbotapi.NewMessage(notification.TelegramID, msg.String())
+	text.ParseMode = "Markdown"
+	if markup != nil {
+		text.ReplyMarkup = markup
+	}
+
+	if _, err := h.send("sendMessage", text); err != nil {
+		log.Printf("Error sending message: %v", err)
+		metrics.Notifications.WithLabelValues("failed").Inc()
+		return err
+	}
+
+	metrics.Notifications.WithLabelValues("sent").Inc()
+	return nil
+}
+
+// SendWishlistResponse sends wishlist data back to user
+func (h *BotHandler) SendWishlistResponse(correlationID string, response *models.WishlistResponse) error {
+	if len(response.Items) == 0 {
+		return h.reply(correlationID, response.ChatID, "📭 Sua lista está vazia!\n\nUse `/add` para adicionar produtos.\n\nExemplo: `/add iPhone 15 R$4000`")
+	}
+
+	var text strings.Builder
+	text.WriteString("📋 *Sua Lista de Desejos*\n\n")
+
+	for i, w := range response.Items {
+		text.WriteString(fmt.Sprintf("*%d.* %s\n", i+1, w.ProductName))
+		text.WriteString(formatConditions(w.TargetPrice, w.MinPrice, w.DiscountPercentage, w.MinCashback, w.MaxEffectivePrice, "   "))
+		if len(w.ExcludedTerms) > 0 {
+			text.WriteString(fmt.Sprintf("   🚫 Ignorando: %s\n", strings.Join(w.ExcludedTerms, ", ")))
+		}
+		text.WriteString(fmt.Sprintf("   🆔 ID: `%d`\n\n", w.ID))
+	}
+
+	text.WriteString(fmt.Sprintf("Total: %d produto(s)\n\n", len(response.Items)))
+	text.WriteString("Para alterar: `/editar <id> <produto> <condições>`\n")
+	text.WriteString("Para remover: `/delete <id>`")
+
+	return h.reply(correlationID, response.ChatID, text.String())
+}
+
+// SendWishlistAddedResponse confirms a wishlist item was saved
+func (h *BotHandler) SendWishlistAddedResponse(correlationID string, response *models.WishlistAddedResponse) error {
+	item := response.Item
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("✅ *Produto adicionado!*\n\n📦 %s\n", item.ProductName))
+	text.WriteString(formatConditions(item.TargetPrice, item.MinPrice, item.DiscountPercentage, item.MinCashback, item.MaxEffectivePrice, ""))
+	if len(item.ExcludedTerms) > 0 {
+		text.WriteString(fmt.Sprintf("🚫 Ignorando: %s\n", strings.Join(item.ExcludedTerms, ", ")))
+	}
+	text.WriteString(fmt.Sprintf("🆔 ID: `%d`\n", item.ID))
+	text.WriteString("\nVou te avisar quando encontrar uma oferta! 🔔")
+
+	return h.reply(correlationID, response.ChatID, text.String())
+}
+
+// SendWishlistUpdatedResponse confirms a wishlist item was changed
+func (h *BotHandler) SendWishlistUpdatedResponse(correlationID string, response *models.WishlistUpdatedResponse) error {
+	if !response.Success || response.Item == nil {
+		return h.reply(correlationID, response.ChatID, "❌ Produto não encontrado!\n\nUse `/list` para ver os IDs disponíveis.")
+	}
+	item := response.Item
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("✅ *Produto alterado!*\n\n📦 %s\n", item.ProductName))
+	text.WriteString(formatConditions(item.TargetPrice, item.MinPrice, item.DiscountPercentage, item.MinCashback, item.MaxEffectivePrice, ""))
+	if len(item.ExcludedTerms) > 0 {
+		text.WriteString(fmt.Sprintf("🚫 Ignorando: %s\n", strings.Join(item.ExcludedTerms, ", ")))
+	}
+	text.WriteString(fmt.Sprintf("🆔 ID: `%d`\n", item.ID))
+
+	return h.reply(correlationID, response.ChatID, text.String())
+}
+
+// SendDeleteResponse sends delete confirmation
+func (h *BotHandler) SendDeleteResponse(correlationID string, response *models.DeleteResponse) error {
+	if response.Success {
+		return h.reply(correlationID, response.ChatID, "✅ Produto removido da lista!")
+	}
+	return h.reply(correlationID, response.ChatID, "❌ Produto não encontrado!\n\nUse `/list` para ver os IDs disponíveis.")
+}
+
+// SendPreferencesResponse confirms the suspicious offers preference was saved
+func (h *BotHandler) SendPreferencesResponse(correlationID string, response *models.PreferencesResponse) error {
+	if response.HideSuspicious {
+		return h.reply(correlationID, response.ChatID, "✅ Ofertas com preço de referência suspeito não serão mais enviadas.")
+	}
+	return h.reply(correlationID, response.ChatID, "✅ Ofertas com preço de referência suspeito serão enviadas com o aviso ⚠️")
+}
+
+// SendCommandFailedResponse tells the user the backend rejected a command
+func (h *BotHandler) SendCommandFailedResponse(correlationID string, response *models.CommandFailedResponse) error {
+	if response.Reason == models.FailureUserNotRegistered {
+		return h.reply(correlationID, response.ChatID, "❌ Você ainda não está cadastrado!\n\nUse /start para se cadastrar e tente novamente.")
+	}
+	return h.reply(correlationID, response.ChatID, "❌ Não foi possível salvar: valores inválidos.\n\nUse /help para ver o formato dos comandos.")
+}
+
+// SendBacktestResponse tells the user how often a new wishlist item would have been notified
+func (h *BotHandler) SendBacktestResponse(response *models.BacktestResponse) error {
+	backtest := response.Backtest
+
+	// Nothing to compare against yet
+	if backtest.OffersScanned == 0 {
+		return nil
+	}
+
+	if backtest.HitCount == 0 {
+		return h.sendMessage(response.ChatID, fmt.Sprintf(
+			"📉 Nos últimos %d dias nenhuma oferta atingiu essa condição.\n\nTalvez valha ajustar o preço ou desconto desejado.",
+			backtest.Days))
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📈 *Histórico dos últimos %d dias*\n\n", backtest.Days))
+	text.WriteString(fmt.Sprintf("✅ Condição atingida em %d dia(s) (%d alerta(s))\n", backtest.DaysWithHits, backtest.HitCount))
+	if backtest.LowestPrice > 0 {
+		text.WriteString(fmt.Sprintf("💰 Menor preço: R$ %.2f\n", backtest.LowestPrice))
+	}
+
+	return h.sendMessage(response.ChatID, text.String())
+}
+
+// SendHistoryResponse sends the price history of a wishlist item, with the
+// chart as a photo when there is one
+func (h *BotHandler) SendHistoryResponse(correlationID string, response *models.HistoryResponse) error {
+	history := response.History
+	if !history.Found {
+		return h.reply(correlationID, response.ChatID, "❌ Produto não encontrado!\n\nUse `/list` para ver os IDs disponíveis.")
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📊 *Histórico de preços*\n📦 %s\n\n", history.ProductName))
+
+	if history.CurrentDay == nil {
+		text.WriteString("Ainda não vimos ofertas desse produto. Volte em alguns dias! 🔍")
+		return h.reply(correlationID, response.ChatID, text.String())
+	}
+
+	text.WriteString(fmt.Sprintf("💰 *Atual:* R$ %.2f (%s)\n\n", history.CurrentPrice, history.CurrentDay.Format("02/01")))
+	for _, period := range history.Periods {
+		if period.DaysWithPrices == 0 {
+			text.WriteString(fmt.Sprintf("*%d dias:* sem ofertas\n", period.Days))
+			continue
+		}
+		text.WriteString(fmt.Sprintf("*%d dias:* mín R$ %.2f · média R$ %.2f\n", period.Days, period.MinPrice, period.AvgPrice))
+	}
+
+	if len(history.Chart) == 0 {
+		return h.reply(correlationID, response.ChatID, text.String())
+	}
+
+	// A text message cannot be edited into a photo, replace the placeholder
+	if req := h.pending.resolve(correlationID); req != nil {
+		h.deleteMessage(req.chatID, req.messageID)
+	}
+
+	photo := tgbotapi.NewPhoto(response.ChatID, tgbotapi.FileBytes{Name: "historico.png", Bytes: history.Chart})
+	photo.Caption = text.String()
+	photo.ParseMode = "Markdown"
+
+	if _, err := h.send("sendPhoto", photo); err != nil {
+		log.Printf("Error sending photo, falling back to text: %v", err)
+		return h.sendMessage(response.ChatID, text.String())
+	}
+
+	return nil
+}
+
+// sendRequest sends a placeholder message and a command the backend answers.
+// The placeholder is edited with the response, or with an error if the
+// command cannot be sent or the backend does not answer in time.
+func (h *BotHandler) sendRequest(ctx context.Context, cmd models.Command, placeholder string) {
+	cmd.CorrelationID = newRequestID()
+
+	// messageID is 0 if the placeholder could not be sent, the response then
+	// goes in a new message
+	messageID, _ := h.sendPlaceholder(cmd.ChatID, placeholder)
+
+	// Track before sending so a fast response finds the request
+	h.pending.add(&pendingRequest{
+		correlationID: cmd.CorrelationID,
+		chatID:        cmd.ChatID,
+		messageID:     messageID,
+		command:       cmd.Type,
+	})
+
+	if err := h.sendCommandToBackend(ctx, cmd); err != nil {
+		h.reply(cmd.CorrelationID, cmd.ChatID, "❌ Não foi possível enviar seu pedido. Tente novamente em instantes.")
+	}
+}
+
+// handleTimeout tells the user the backend did not answer a command
+func (h *BotHandler) handleTimeout(req *pendingRequest) {
+	log.Printf("Command %s %s timed out for chat %d", req.command, req.correlationID, req.chatID)
+	metrics.CommandTimeouts.WithLabelValues(req.command).Inc()
+
+	text := "⌛ O servidor não respondeu a tempo. Tente novamente em instantes."
+	if req.messageID == 0 {
+		h.sendMessage(req.chatID, text)
+		return
+	}
+	h.editMessage(req.chatID, req.messageID, text)
+}
+
+// reply answers a command by editing its placeholder message, or with a new
+// message when the command is not pending (timed out or sent by an older
+// frontend)
+func (h *BotHandler) reply(correlationID string, chatID int64, text string) error {
+	req := h.pending.resolve(correlationID)
+	if req == nil || req.messageID == 0 {
+		return h.sendMessage(chatID, text)
+	}
+
+	if err := h.editMessage(req.chatID, req.messageID, text); err != nil {
+		return h.sendMessage(chatID, text)
+	}
+
+	return nil
+}
+
+// sendCommandToBackend sends a command to the backend via the message bus
+func (h *BotHandler) sendCommandToBackend(ctx context.Context, cmd models.Command) error {
+	// The backend skips commands with an id it already applied, so the
+	// producer retries and Kafka redeliveries apply the command once
+	cmd.CommandID = newRequestID()
+	cmd.Timestamp = time.Now()
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		log.Printf("Error marshaling command: %v", err)
+		metrics.Commands.WithLabelValues(cmd.Type, "failed").Inc()
+		return err
+	}
+
+	msg := &bus.Message{
+		Topic: h.commandTopic,
+		Key:   []byte(fmt.Sprintf("%d", cmd.TelegramID)),
+		Value: data,
+	}
+
+	_, span := tracer.StartProducerSpan(ctx, msg)
+	err = h.publisher.Publish(ctx, msg)
+	tracing.End(span, err)
+	if err != nil {
+		log.Printf("Error sending command to backend: %v", err)
+		metrics.Commands.WithLabelValues(cmd.Type, "failed").Inc()
+		return err
+	}
+
+	metrics.Commands.WithLabelValues(cmd.Type, "sent").Inc()
+	return nil
+}
+
+// sendPlaceholder sends a message to be edited later and returns its id
+func (h *BotHandler) sendPlaceholder(chatID int64, text string) (int, error) {
+	sent, err := h.send("sendMessage", tgbotapi.NewMessage(chatID, text))
+	if err != nil {
+		log.Printf("Error sending message: %v", err)
+		return 0, err
+	}
+
+	return sent.MessageID, nil
+}
+
+// editMessage replaces the text of a message
+func (h *BotHandler) editMessage(chatID int64, messageID int, text string) error {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = "Markdown"
+
+	if _, err := h.send("editMessageText", edit); err != nil {
+		log.Printf("Error editing message: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// deleteMessage deletes a message, logging failures
+func (h *BotHandler) deleteMessage(chatID int64, messageID int) {
+	if messageID == 0 {
+		return
+	}
+
+	if _, err := h.bot.Request(tgbotapi.NewDeleteMessage(chatID, messageID)); err != nil {
+		log.Printf("Error deleting message: %v", err)
+		metrics.TelegramSendErrors.WithLabelValues("deleteMessage").Inc()
+	}
+}
+
+// sendMessage sends a message to a chat
+func (h *BotHandler) sendMessage(chatID int64, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+
+	_, err := h.send("sendMessage", msg)
+	if err != nil {
+		log.Printf("Error sending message: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// send sends a message through the Telegram Bot API, counting failures by
+// API method
+func (h *BotHandler) send(method string, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	sent, err := h.bot.Send(c)
+	if err != nil {
+		metrics.TelegramSendErrors.WithLabelValues(method).Inc()
+	}
+	return sent, err
+}
@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// recordingClient records what the bot sends instead of calling Telegram
+type recordingClient struct {
+	sent []tgbotapi.Chattable
+}
+
+func (c *recordingClient) Send(chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+	c.sent = append(c.sent, chattable)
+	return tgbotapi.Message{MessageID: len(c.sent)}, nil
+}
+
+func (c *recordingClient) Request(chattable tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	c.sent = append(c.sent, chattable)
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func TestSendNotificationWarnsAboutSuspiciousReference(t *testing.T) {
+	tests := []struct {
+		name       string
+		suspicious bool
+		want       string
+	}{
+		{"flagged", true, "⚠️ *Preço de referência suspeito:* o maior preço que vimos nos últimos 90 dias foi R$ 2500.00"},
+		{"not flagged", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &recordingClient{}
+			handler := NewBotHandler(client, bus.NewMemory(), "bot-commands", time.Minute)
+
+			err := handler.SendNotification(&models.OfferNotification{
+				TelegramID:          10,
+				ProductName:         "Geladeira Consul 300L",
+				Price:               2000,
+				OriginalPrice:       4000,
+				DiscountPercentage:  50,
+				MatchType:           "price",
+				SuspiciousReference: tt.suspicious,
+				HighestPrice:        2500,
+			})
+			if err != nil {
+				t.Fatalf("SendNotification() error = %v", err)
+			}
+			if len(client.sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(client.sent))
+			}
+
+			text := client.sent[0].(tgbotapi.MessageConfig).Text
+			if tt.want != "" && !strings.Contains(text, tt.want) {
+				t.Errorf("notification = %q, want the warning %q", text, tt.want)
+			}
+			if tt.want == "" && strings.Contains(text, "⚠️") {
+				t.Errorf("notification = %q, want no warning", text)
+			}
+		})
+	}
+}
+
+func TestSendCommandFailedResponse(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   string
+	}{
+		{models.FailureUserNotRegistered, "Use /start para se cadastrar"},
+		{models.FailureInvalidValues, "valores inválidos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			client := &recordingClient{}
+			handler := NewBotHandler(client, bus.NewMemory(), "bot-commands", time.Minute)
+
+			err := handler.SendCommandFailedResponse("", &models.CommandFailedResponse{ChatID: 99, Command: "add_wishlist", Reason: tt.reason})
+			if err != nil {
+				t.Fatalf("SendCommandFailedResponse() error = %v", err)
+			}
+			if len(client.sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(client.sent))
+			}
+
+			message := client.sent[0].(tgbotapi.MessageConfig)
+			if message.ChatID != 99 || !strings.Contains(message.Text, tt.want) {
+				t.Errorf("message to chat %d = %q, want %q", message.ChatID, message.Text, tt.want)
+			}
+		})
+	}
+}
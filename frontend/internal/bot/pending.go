@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// pendingRequest is a command waiting for the backend response. MessageID is
+// the placeholder message edited with the response, 0 when it was not sent.
+type pendingRequest struct {
+	correlationID string
+	chatID        int64
+	messageID     int
+	command       string
+	timer         *time.Timer
+}
+
+// pendingRequests tracks the commands sent to the backend until they are
+// answered or time out
+type pendingRequests struct {
+	mu        sync.Mutex
+	requests  map[string]*pendingRequest
+	timeout   time.Duration
+	onTimeout func(*pendingRequest)
+}
+
+func newPendingRequests(timeout time.Duration, onTimeout func(*pendingRequest)) *pendingRequests {
+	return &pendingRequests{
+		requests:  make(map[string]*pendingRequest),
+		timeout:   timeout,
+		onTimeout: onTimeout,
+	}
+}
+
+// add starts tracking a request. onTimeout is called if it is not resolved
+// within the timeout.
+func (p *pendingRequests) add(req *pendingRequest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests[req.correlationID] = req
+	req.timer = time.AfterFunc(p.timeout, func() {
+		if expired := p.resolve(req.correlationID); expired != nil {
+			p.onTimeout(expired)
+		}
+	})
+}
+
+// resolve stops tracking a request and returns it, or nil when there is no
+// request with the correlation id (unknown, already answered or timed out)
+func (p *pendingRequests) resolve(correlationID string) *pendingRequest {
+	if correlationID == "" {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	req, ok := p.requests[correlationID]
+	if !ok {
+		return nil
+	}
+
+	delete(p.requests, correlationID)
+	req.timer.Stop()
+	return req
+}
+
+// newRequestID returns a random id for the correlation and command ids of a command
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// Fall back to the clock, ids only need to be unique among recent commands
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,107 +1,205 @@
-package consumer
-
-import (
-	"context"
-	"encoding/json"
-	"log"
-	"time"
-
-	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/bot"
-	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/models"
-	"github.com/IBM/sarama"
-)
-
-type KafkaConsumer struct {
-	ready      chan bool
-	botHandler *bot.BotHandler
-}
-
-func NewKafkaConsumer(botHandler *bot.BotHandler) *KafkaConsumer {
-	return &KafkaConsumer{
-		ready:      make(chan bool),
-		botHandler: botHandler,
-	}
-}
-
-// Setup is run at the beginning of a new session, before ConsumeClaim
-func (consumer *KafkaConsumer) Setup(sarama.ConsumerGroupSession) error {
-	close(consumer.ready)
-	return nil
-}
-
-// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
-func (consumer *KafkaConsumer) Cleanup(sarama.ConsumerGroupSession) error {
-	return nil
-}
-
-// ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages()
-func (consumer *KafkaConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for message := range claim.Messages() {
-		if err := consumer.processMessage(message.Value); err != nil {
-			log.Printf("Error processing message: %v", err)
-		}
-		session.MarkMessage(message, "")
-	}
-	return nil
-}
-
-// processMessage processes a Kafka message
-func (c *KafkaConsumer) processMessage(data []byte) error {
-	// Try OfferNotification
-	var offerNotification models.OfferNotification
-	if err := json.Unmarshal(data, &offerNotification); err == nil && offerNotification.TelegramID != 0 {
-		if offerNotification.ProductName != "" {
-			log.Printf("Received offer notification for user %d: %s", offerNotification.TelegramID, offerNotification.ProductName)
-			return c.botHandler.SendNotification(&offerNotification)
-		}
-	}
-
-	// Try WishlistResponse
-	var wishlistResponse models.WishlistResponse
-	if err := json.Unmarshal(data, &wishlistResponse); err == nil && wishlistResponse.ChatID != 0 {
-		log.Printf("Received wishlist response for chat %d", wishlistResponse.ChatID)
-		return c.botHandler.SendWishlistResponse(&wishlistResponse)
-	}
-
-	// Try DeleteResponse
-	var deleteResponse models.DeleteResponse
-	if err := json.Unmarshal(data, &deleteResponse); err == nil && deleteResponse.ChatID != 0 {
-		log.Printf("Received delete response for chat %d", deleteResponse.ChatID)
-		return c.botHandler.SendDeleteResponse(&deleteResponse)
-	}
-
-	log.Printf("Unknown message type received: %s", string(data))
-	return nil
-}
-
-// StartConsumerGroup starts the consumer group
-func StartConsumerGroup(ctx context.Context, brokers []string, topic, groupID string, botHandler *bot.BotHandler) error {
-	config := sarama.NewConfig()
-	config.Version = sarama.V2_8_0_0
-	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetOldest
-
-	client, err := sarama.NewConsumerGroup(brokers, groupID, config)
-	if err != nil {
-		return err
-	}
-
-	consumer := NewKafkaConsumer(botHandler)
-
-	go func() {
-		for {
-			if err := client.Consume(ctx, []string{topic}, consumer); err != nil {
-				log.Printf("Error from consumer: %v", err)
-				time.Sleep(time.Second * 5)
-			}
-			if ctx.Err() != nil {
-				return
-			}
-			consumer.ready = make(chan bool)
-		}
-	}()
-
-	<-consumer.ready
-	log.Println("Sarama consumer up and running!...")
-	return nil
-}
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/bot"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/models"
+)
+
+// tracer starts the spans of this package
+var tracer = tracing.NewTracer("github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/consumer")
+
+// KafkaConsumer handles the messages of the bot-responses topic with retries,
+// moving messages that keep failing to the dead-letter topic
+type KafkaConsumer struct {
+	botHandler  *bot.BotHandler
+	groupID     string
+	retry       bus.RetryPolicy
+	deadLetters *bus.DeadLetterWriter
+}
+
+func NewKafkaConsumer(botHandler *bot.BotHandler, groupID string, retry bus.RetryPolicy, deadLetters *bus.DeadLetterWriter) *KafkaConsumer {
+	return &KafkaConsumer{
+		botHandler:  botHandler,
+		groupID:     groupID,
+		retry:       retry,
+		deadLetters: deadLetters,
+	}
+}
+
+// Handle is the bus handler of the consumer. It returns nil once the message
+// was handled or sent to the dead-letter topic, so only then is it marked.
+// Messages are processed in a span continuing the trace of the backend.
+func (consumer *KafkaConsumer) Handle(ctx context.Context, message *bus.Message) error {
+	_, span := tracer.StartConsumerSpan(ctx, message, consumer.groupID)
+	attempts, err := consumer.retry.Run(ctx, func() error {
+		return consumer.processMessage(message.Value)
+	})
+	tracing.End(span, err)
+
+	if err == nil {
+		return nil
+	}
+
+	// Rebalance or shutdown while retrying, the message is consumed again
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	log.Printf("Error processing message at offset %d after %d attempt(s): %v", message.Offset, attempts, err)
+
+	if dlqErr := consumer.deadLetters.Send(ctx, message, consumer.groupID, err, attempts); dlqErr != nil {
+		// Leave the message unmarked so it is consumed again
+		return fmt.Errorf("failed to dead-letter message: %w", dlqErr)
+	}
+	log.Printf("Sent message at offset %d to %s", message.Offset, bus.DeadLetterTopic(message.Topic))
+	return nil
+}
+
+// processMessage routes a message of the bot-responses topic by its envelope type
+func (c *KafkaConsumer) processMessage(data []byte) error {
+	envelope, ok := models.ParseEnvelope(data)
+	if !ok {
+		return c.processRawMessage(data)
+	}
+
+	// Newer messages go to the dead-letter topic, to be replayed once the
+	// frontend understands them
+	if envelope.Version > models.EnvelopeVersion {
+		return bus.Permanent(fmt.Errorf("unsupported %s message version %d", envelope.Type, envelope.Version))
+	}
+
+	switch envelope.Type {
+	case models.MessageTypeOfferNotification:
+		var notification models.OfferNotification
+		if err := json.Unmarshal(envelope.Payload, &notification); err != nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %w", envelope.Type, err))
+		}
+		log.Printf("Received offer notification for user %d: %s", notification.TelegramID, notification.ProductName)
+		return c.botHandler.SendNotification(&notification)
+
+	case models.MessageTypeWishlistList:
+		var response models.WishlistResponse
+		if err := json.Unmarshal(envelope.Payload, &response); err != nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %w", envelope.Type, err))
+		}
+		log.Printf("Received wishlist response for chat %d", response.ChatID)
+		return c.botHandler.SendWishlistResponse(envelope.CorrelationID, &response)
+
+	case models.MessageTypeWishlistAdded:
+		var response models.WishlistAddedResponse
+		if err := json.Unmarshal(envelope.Payload, &response); err != nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %w", envelope.Type, err))
+		}
+		log.Printf("Received add confirmation for chat %d", response.ChatID)
+		return c.botHandler.SendWishlistAddedResponse(envelope.CorrelationID, &response)
+
+	case models.MessageTypeWishlistUpdated:
+		var response models.WishlistUpdatedResponse
+		if err := json.Unmarshal(envelope.Payload, &response); err != nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %w", envelope.Type, err))
+		}
+		log.Printf("Received update confirmation for chat %d", response.ChatID)
+		return c.botHandler.SendWishlistUpdatedResponse(envelope.CorrelationID, &response)
+
+	case models.MessageTypeWishlistDeleted:
+		var response models.DeleteResponse
+		if err := json.Unmarshal(envelope.Payload, &response); err != nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %w", envelope.Type, err))
+		}
+		log.Printf("Received delete response for chat %d", response.ChatID)
+		return c.botHandler.SendDeleteResponse(envelope.CorrelationID, &response)
+
+	case models.MessageTypeBacktestSummary:
+		var response models.BacktestResponse
+		if err := json.Unmarshal(envelope.Payload, &response); err != nil || response.Backtest == nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %v", envelope.Type, err))
+		}
+		log.Printf("Received backtest response for chat %d", response.ChatID)
+		return c.botHandler.SendBacktestResponse(&response)
+
+	case models.MessageTypePriceHistory:
+		var response models.HistoryResponse
+		if err := json.Unmarshal(envelope.Payload, &response); err != nil || response.History == nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %v", envelope.Type, err))
+		}
+		log.Printf("Received price history response for chat %d", response.ChatID)
+		return c.botHandler.SendHistoryResponse(envelope.CorrelationID, &response)
+
+	case models.MessageTypePreferences:
+		var response models.PreferencesResponse
+		if err := json.Unmarshal(envelope.Payload, &response); err != nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %w", envelope.Type, err))
+		}
+		log.Printf("Received preferences confirmation for chat %d", response.ChatID)
+		return c.botHandler.SendPreferencesResponse(envelope.CorrelationID, &response)
+
+	case models.MessageTypeCommandFailed:
+		var response models.CommandFailedResponse
+		if err := json.Unmarshal(envelope.Payload, &response); err != nil {
+			return bus.Permanent(fmt.Errorf("invalid %s payload: %w", envelope.Type, err))
+		}
+		log.Printf("Received %s failure for chat %d: %s", response.Command, response.ChatID, response.Reason)
+		return c.botHandler.SendCommandFailedResponse(envelope.CorrelationID, &response)
+	}
+
+	return bus.Permanent(fmt.Errorf("unknown message type %q", envelope.Type))
+}
+
+// processRawMessage guesses the type of a message written before the envelope
+// existed. Kept while older backends are still running.
+func (c *KafkaConsumer) processRawMessage(data []byte) error {
+	// Try OfferNotification
+	var offerNotification models.OfferNotification
+	if err := json.Unmarshal(data, &offerNotification); err == nil && offerNotification.TelegramID != 0 {
+		if offerNotification.ProductName != "" {
+			log.Printf("Received offer notification for user %d: %s", offerNotification.TelegramID, offerNotification.ProductName)
+			return c.botHandler.SendNotification(&offerNotification)
+		}
+	}
+
+	// Try BacktestResponse
+	var backtestResponse models.BacktestResponse
+	if err := json.Unmarshal(data, &backtestResponse); err == nil && backtestResponse.ChatID != 0 && backtestResponse.Backtest != nil {
+		log.Printf("Received backtest response for chat %d", backtestResponse.ChatID)
+		return c.botHandler.SendBacktestResponse(&backtestResponse)
+	}
+
+	// Try HistoryResponse
+	var historyResponse models.HistoryResponse
+	if err := json.Unmarshal(data, &historyResponse); err == nil && historyResponse.ChatID != 0 && historyResponse.History != nil {
+		log.Printf("Received price history response for chat %d", historyResponse.ChatID)
+		return c.botHandler.SendHistoryResponse("", &historyResponse)
+	}
+
+	// Try WishlistResponse (delete responses have no items)
+	var wishlistResponse models.WishlistResponse
+	if err := json.Unmarshal(data, &wishlistResponse); err == nil && wishlistResponse.ChatID != 0 && wishlistResponse.Items != nil {
+		log.Printf("Received wishlist response for chat %d", wishlistResponse.ChatID)
+		return c.botHandler.SendWishlistResponse("", &wishlistResponse)
+	}
+
+	// Try DeleteResponse
+	var deleteResponse models.DeleteResponse
+	if err := json.Unmarshal(data, &deleteResponse); err == nil && deleteResponse.ChatID != 0 {
+		log.Printf("Received delete response for chat %d", deleteResponse.ChatID)
+		return c.botHandler.SendDeleteResponse("", &deleteResponse)
+	}
+
+	log.Printf("Unknown message type received: %s", string(data))
+	return nil
+}
+
+// StartConsumerGroup subscribes the bot handler to the topic. Handler errors
+// are retried as set by retry, then the message is sent to its dead-letter
+// topic. The returned status reports whether the member has joined the group.
+func StartConsumerGroup(ctx context.Context, subscriber bus.Subscriber, topic, groupID string, retry bus.RetryPolicy, deadLetters *bus.DeadLetterWriter, botHandler *bot.BotHandler) (*bus.Status, error) {
+	return subscriber.Subscribe(ctx, bus.Subscription{Topic: topic, Group: groupID},
+		NewKafkaConsumer(botHandler, groupID, retry, deadLetters).Handle)
+}
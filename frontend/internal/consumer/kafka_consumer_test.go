@@ -0,0 +1,287 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/bot"
+	"github.com/FlavioMalvestitiJunior/bf-offers/frontend/internal/models"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// recordingClient records what the bot sends instead of calling Telegram
+type recordingClient struct {
+	mu   sync.Mutex
+	sent []tgbotapi.Chattable
+}
+
+func (c *recordingClient) Send(chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent = append(c.sent, chattable)
+	return tgbotapi.Message{MessageID: len(c.sent)}, nil
+}
+
+func (c *recordingClient) Request(chattable tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent = append(c.sent, chattable)
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+// sentMessage is a message sent or edited by the bot
+type sentMessage struct {
+	method    string
+	chatID    int64
+	messageID int
+	text      string
+}
+
+func (c *recordingClient) messages() []sentMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var messages []sentMessage
+	for _, chattable := range c.sent {
+		switch m := chattable.(type) {
+		case tgbotapi.MessageConfig:
+			messages = append(messages, sentMessage{"sendMessage", m.ChatID, 0, m.Text})
+		case tgbotapi.EditMessageTextConfig:
+			messages = append(messages, sentMessage{"editMessageText", m.ChatID, m.MessageID, m.Text})
+		case tgbotapi.PhotoConfig:
+			messages = append(messages, sentMessage{"sendPhoto", m.ChatID, 0, m.Caption})
+		default:
+			messages = append(messages, sentMessage{method: fmt.Sprintf("%T", m)})
+		}
+	}
+	return messages
+}
+
+func newTestConsumer(client *recordingClient, publisher bus.Publisher) *KafkaConsumer {
+	handler := bot.NewBotHandler(client, publisher, "bot-commands", time.Minute)
+	return NewKafkaConsumer(handler, "frontend", bus.RetryPolicy{}, nil)
+}
+
+// envelope returns an enveloped message of the bot-responses topic
+func envelope(t *testing.T, messageType string, version int, correlationID string, payload interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := json.Marshal(models.Envelope{Type: messageType, Version: version, CorrelationID: correlationID, Payload: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestProcessMessageRoutesEnvelopesByType(t *testing.T) {
+	targetPrice := 3000.0
+	item := models.WishlistItem{ID: 5, ProductName: "geladeira", TargetPrice: &targetPrice}
+
+	tests := []struct {
+		name        string
+		messageType string
+		payload     interface{}
+		wantChat    int64
+		wantText    string
+	}{
+		{"offer notification", models.MessageTypeOfferNotification,
+			models.OfferNotification{TelegramID: 10, ProductName: "Geladeira Consul", Price: 2500, MatchType: "price"},
+			10, "Oferta Encontrada"},
+		{"wishlist list", models.MessageTypeWishlistList,
+			models.WishlistResponse{ChatID: 99, Items: []models.WishlistItem{item}},
+			99, "geladeira"},
+		{"empty wishlist list", models.MessageTypeWishlistList,
+			models.WishlistResponse{ChatID: 99, Items: []models.WishlistItem{}},
+			99, "Sua lista está vazia"},
+		{"wishlist added", models.MessageTypeWishlistAdded,
+			models.WishlistAddedResponse{ChatID: 99, Item: item},
+			99, "Produto adicionado"},
+		{"wishlist updated", models.MessageTypeWishlistUpdated,
+			models.WishlistUpdatedResponse{ChatID: 99, Success: true, Item: &item},
+			99, "Produto alterado"},
+		{"wishlist deleted", models.MessageTypeWishlistDeleted,
+			models.DeleteResponse{ChatID: 99, Success: true},
+			99, "Produto removido"},
+		{"backtest summary", models.MessageTypeBacktestSummary,
+			models.BacktestResponse{ChatID: 99, Backtest: &models.BacktestSummary{Days: 30, OffersScanned: 10}},
+			99, "nenhuma oferta atingiu"},
+		{"price history", models.MessageTypePriceHistory,
+			models.HistoryResponse{ChatID: 99, History: &models.PriceHistorySummary{WishlistID: 5}},
+			99, "Produto não encontrado"},
+		{"preferences", models.MessageTypePreferences,
+			models.PreferencesResponse{ChatID: 99, HideSuspicious: true},
+			99, "não serão mais enviadas"},
+		{"command failed", models.MessageTypeCommandFailed,
+			models.CommandFailedResponse{ChatID: 99, Command: "add_wishlist", Reason: models.FailureUserNotRegistered},
+			99, "Use /start"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &recordingClient{}
+			consumer := newTestConsumer(client, bus.NewMemory())
+
+			if err := consumer.processMessage(envelope(t, tt.messageType, models.EnvelopeVersion, "", tt.payload)); err != nil {
+				t.Fatalf("processMessage() error = %v", err)
+			}
+
+			messages := client.messages()
+			if len(messages) != 1 {
+				t.Fatalf("sent %+v, want one message", messages)
+			}
+			if messages[0].chatID != tt.wantChat || !strings.Contains(messages[0].text, tt.wantText) {
+				t.Errorf("sent %q to chat %d, want %q to chat %d", messages[0].text, messages[0].chatID, tt.wantText, tt.wantChat)
+			}
+		})
+	}
+}
+
+func TestProcessMessageRejectsEnvelopesItCannotHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		message []byte
+	}{
+		{"newer version", envelope(t, models.MessageTypeWishlistDeleted, models.EnvelopeVersion+1, "", models.DeleteResponse{ChatID: 99, Success: true})},
+		{"unknown type", envelope(t, "wishlist_shared", models.EnvelopeVersion, "", models.DeleteResponse{ChatID: 99})},
+		{"invalid payload", envelope(t, models.MessageTypeWishlistAdded, models.EnvelopeVersion, "", []int{1, 2})},
+		{"backtest without summary", envelope(t, models.MessageTypeBacktestSummary, models.EnvelopeVersion, "", models.BacktestResponse{ChatID: 99})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &recordingClient{}
+			consumer := newTestConsumer(client, bus.NewMemory())
+
+			err := consumer.processMessage(tt.message)
+			if !bus.IsPermanent(err) {
+				t.Errorf("processMessage() error = %v, want a permanent error", err)
+			}
+			if messages := client.messages(); len(messages) != 0 {
+				t.Errorf("sent %+v, want nothing", messages)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEditsThePendingRequest(t *testing.T) {
+	client := &recordingClient{}
+	messageBus := bus.NewMemory()
+	consumer := newTestConsumer(client, messageBus)
+
+	// The command sent for /list carries the correlation id of its placeholder
+	commands := make(chan models.Command, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := messageBus.Subscribe(ctx, bus.Subscription{Topic: "bot-commands", Group: "backend"}, func(ctx context.Context, msg *bus.Message) error {
+		var cmd models.Command
+		if err := json.Unmarshal(msg.Value, &cmd); err != nil {
+			t.Errorf("invalid command %s: %v", msg.Value, err)
+		}
+		commands <- cmd
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumer.botHandler.HandleUpdate(tgbotapi.Update{Message: &tgbotapi.Message{
+		Text:     "/list",
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: 5}},
+		From:     &tgbotapi.User{ID: 10},
+		Chat:     &tgbotapi.Chat{ID: 99},
+	}})
+
+	var cmd models.Command
+	select {
+	case cmd = <-commands:
+	case <-time.After(time.Second):
+		t.Fatal("command not sent in time")
+	}
+	if cmd.Type != "list_wishlist" || cmd.CorrelationID == "" {
+		t.Fatalf("command = %+v, want a list command with a correlation id", cmd)
+	}
+
+	response := models.WishlistResponse{ChatID: 99, Items: []models.WishlistItem{}}
+
+	// A response to another request goes in a new message
+	if err := consumer.processMessage(envelope(t, models.MessageTypeWishlistList, models.EnvelopeVersion, "other", response)); err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+	if err := consumer.processMessage(envelope(t, models.MessageTypeWishlistList, models.EnvelopeVersion, cmd.CorrelationID, response)); err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+	// The request is resolved, a redelivered response goes in a new message
+	if err := consumer.processMessage(envelope(t, models.MessageTypeWishlistList, models.EnvelopeVersion, cmd.CorrelationID, response)); err != nil {
+		t.Fatalf("processMessage() error = %v", err)
+	}
+
+	messages := client.messages()
+	want := []sentMessage{
+		{"sendMessage", 99, 0, "🔍 Buscando sua lista..."},
+		{"sendMessage", 99, 0, ""},
+		{"editMessageText", 99, 1, ""},
+		{"sendMessage", 99, 0, ""},
+	}
+	if len(messages) != len(want) {
+		t.Fatalf("sent %+v, want %d messages", messages, len(want))
+	}
+	for i, m := range messages {
+		if m.method != want[i].method || m.chatID != want[i].chatID || m.messageID != want[i].messageID {
+			t.Errorf("message %d = %+v, want %s to chat %d of message %d", i, m, want[i].method, want[i].chatID, want[i].messageID)
+		}
+		if want[i].text != "" && m.text != want[i].text {
+			t.Errorf("message %d text = %q, want %q", i, m.text, want[i].text)
+		}
+	}
+}
+
+func TestProcessMessageAcceptsRawMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		wantText string
+	}{
+		{"offer notification", `{"telegram_id":10,"product_name":"Geladeira Consul","price":2500,"match_type":"price"}`, "Oferta Encontrada"},
+		{"backtest response", `{"chat_id":99,"backtest":{"days":30,"offers_scanned":10}}`, "nenhuma oferta atingiu"},
+		{"history response", `{"chat_id":99,"history":{"wishlist_id":5}}`, "Produto não encontrado"},
+		{"wishlist response", `{"chat_id":99,"items":[{"id":5,"product_name":"geladeira"}]}`, "geladeira"},
+		{"delete response", `{"chat_id":99,"success":true}`, "Produto removido"},
+		{"unknown message", `{"hello":"world"}`, ""},
+		{"not json", `not json`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &recordingClient{}
+			consumer := newTestConsumer(client, bus.NewMemory())
+
+			// Unknown raw messages are skipped, not dead-lettered
+			if err := consumer.processMessage([]byte(tt.message)); err != nil {
+				t.Fatalf("processMessage() error = %v", err)
+			}
+
+			messages := client.messages()
+			if tt.wantText == "" {
+				if len(messages) != 0 {
+					t.Errorf("sent %+v, want nothing", messages)
+				}
+				return
+			}
+			if len(messages) != 1 || !strings.Contains(messages[0].text, tt.wantText) {
+				t.Errorf("sent %+v, want one message with %q", messages, tt.wantText)
+			}
+		})
+	}
+}
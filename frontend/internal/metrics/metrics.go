@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "offerbot_frontend"
+
+var (
+	// Notifications counts the offer notifications delivered to the users, by
+	// status (sent or failed)
+	Notifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Offer notifications delivered through Telegram, by status.",
+	}, []string{"status"})
+
+	// Commands counts the commands sent to the backend by type and status
+	// (sent or failed)
+	Commands = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Bot commands sent to the backend, by type and status.",
+	}, []string{"type", "status"})
+
+	// CommandTimeouts counts the commands the backend did not answer in time
+	CommandTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_timeouts_total",
+		Help:      "Bot commands not answered by the backend in time, by type.",
+	}, []string{"type"})
+
+	// TelegramSendErrors counts the failed Telegram Bot API calls by method
+	TelegramSendErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_send_errors_total",
+		Help:      "Failed Telegram Bot API calls, by method.",
+	}, []string{"method"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// EnvelopeVersion is the newest envelope version the frontend understands
+const EnvelopeVersion = 1
+
+// Message types of the bot-responses topic
+const (
+	MessageTypeOfferNotification = "offer_notification"
+	MessageTypeWishlistList      = "wishlist_list"
+	MessageTypeWishlistAdded     = "wishlist_added"
+	MessageTypeWishlistUpdated   = "wishlist_updated"
+	MessageTypeWishlistDeleted   = "wishlist_deleted"
+	MessageTypeBacktestSummary   = "backtest_summary"
+	MessageTypePriceHistory      = "price_history"
+	MessageTypePreferences       = "preferences_updated"
+	MessageTypeCommandFailed     = "command_failed"
+)
+
+// Envelope wraps every message of the bot-responses topic. CorrelationID is the
+// id of the command a response answers.
+type Envelope struct {
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// ParseEnvelope parses an enveloped message. It returns false for the raw
+// messages written before the envelope existed.
+func ParseEnvelope(data []byte) (*Envelope, bool) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, false
+	}
+
+	// Raw messages have no type, version or payload
+	if envelope.Type == "" || envelope.Version == 0 || len(envelope.Payload) == 0 {
+		return nil, false
+	}
+
+	return &envelope, true
+}
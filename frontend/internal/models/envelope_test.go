@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestParseEnvelope(t *testing.T) {
+	tests := []struct {
+		name              string
+		data              string
+		wantOK            bool
+		wantType          string
+		wantVersion       int
+		wantCorrelationID string
+	}{
+		{"response", `{"type":"wishlist_list","version":1,"correlation_id":"abc","payload":{"chat_id":99,"items":[]}}`, true, "wishlist_list", 1, "abc"},
+		{"notification without correlation", `{"type":"offer_notification","version":1,"payload":{"telegram_id":10}}`, true, "offer_notification", 1, ""},
+		{"newer version", `{"type":"wishlist_list","version":2,"payload":{}}`, true, "wishlist_list", 2, ""},
+		{"unknown type", `{"type":"wishlist_shared","version":1,"payload":{}}`, true, "wishlist_shared", 1, ""},
+		{"raw notification", `{"telegram_id":10,"product_name":"Geladeira"}`, false, "", 0, ""},
+		{"raw response with a type field", `{"type":"list","chat_id":99}`, false, "", 0, ""},
+		{"without version", `{"type":"wishlist_list","payload":{}}`, false, "", 0, ""},
+		{"without payload", `{"type":"wishlist_list","version":1}`, false, "", 0, ""},
+		{"not json", `not json`, false, "", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, ok := ParseEnvelope([]byte(tt.data))
+			if ok != tt.wantOK {
+				t.Fatalf("ParseEnvelope() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if envelope != nil {
+					t.Errorf("ParseEnvelope() = %+v, want nil for a raw message", envelope)
+				}
+				return
+			}
+			if envelope.Type != tt.wantType || envelope.Version != tt.wantVersion || envelope.CorrelationID != tt.wantCorrelationID {
+				t.Errorf("ParseEnvelope() = %+v, want type %s version %d correlation %q", envelope, tt.wantType, tt.wantVersion, tt.wantCorrelationID)
+			}
+		})
+	}
+}
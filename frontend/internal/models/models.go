@@ -1,50 +1,143 @@
-package models
-
-import "time"
-
-// OfferNotification represents a notification from backend about a matched offer
-type OfferNotification struct {
-	TelegramID         int64   `json:"telegram_id"`
-	ProductName        string  `json:"product_name"`
-	Price              float64 `json:"price"`
-	OriginalPrice      float64 `json:"original_price"`
-	DiscountPercentage int     `json:"discount_percentage"`
-	CashbackPercentage int     `json:"cashback_percentage"`
-	WishlistID         int     `json:"wishlist_id"`
-	MatchType          string  `json:"match_type"` // "price" or "discount"
-}
-
-// Command represents a command sent from frontend to backend
-type Command struct {
-	Type               string    `json:"type"` // register_user, add_wishlist, list_wishlist, delete_wishlist
-	TelegramID         int64     `json:"telegram_id"`
-	ChatID             int64     `json:"chat_id,omitempty"`
-	Username           string    `json:"username,omitempty"`
-	FirstName          string    `json:"first_name,omitempty"`
-	LastName           string    `json:"last_name,omitempty"`
-	ProductName        string    `json:"product_name,omitempty"`
-	TargetPrice        *float64  `json:"target_price,omitempty"`
-	DiscountPercentage *int      `json:"discount_percentage,omitempty"`
-	WishlistID         int       `json:"wishlist_id,omitempty"`
-	Timestamp          time.Time `json:"timestamp"`
-}
-
-// WishlistItem represents a single wishlist item
-type WishlistItem struct {
-	ID                 int      `json:"id"`
-	ProductName        string   `json:"product_name"`
-	TargetPrice        *float64 `json:"target_price,omitempty"`
-	DiscountPercentage *int     `json:"discount_percentage,omitempty"`
-}
-
-// WishlistResponse represents the response to a list command
-type WishlistResponse struct {
-	ChatID int64          `json:"chat_id"`
-	Items  []WishlistItem `json:"items"`
-}
-
-// DeleteResponse represents the response to a delete command
-type DeleteResponse struct {
-	ChatID  int64 `json:"chat_id"`
-	Success bool  `json:"success"`
-}
+package models
+
+import "time"
+
+// OfferNotification represents a notification from backend about a matched offer
+type OfferNotification struct {
+	TelegramID         int64   `json:"telegram_id"`
+	ProductName        string  `json:"product_name"`
+	Price              float64 `json:"price"`
+	OriginalPrice      float64 `json:"original_price"`
+	DiscountPercentage int     `json:"discount_percentage"`
+	CashbackPercentage int     `json:"cashback_percentage"`
+	EffectivePrice     float64 `json:"effective_price,omitempty"` // Price after cashback
+	URL                string  `json:"url,omitempty"`
+	ImageURL           string  `json:"image_url,omitempty"`
+	WishlistID         int     `json:"wishlist_id"`
+	MatchType          string  `json:"match_type"` // "price", "discount", "cashback" or "effective_price"
+
+	// Set when the original price is above every price recorded for the product
+	SuspiciousReference bool    `json:"suspicious_reference,omitempty"`
+	HighestPrice        float64 `json:"highest_price,omitempty"` // Highest recorded price before the offer
+}
+
+// Command represents a command sent from frontend to backend
+type Command struct {
+	Type               string    `json:"type"`                     // register_user, add_wishlist, list_wishlist, delete_wishlist, price_history, set_hide_suspicious
+	CommandID          string    `json:"command_id,omitempty"`     // Unique per command, used by the backend to skip redeliveries
+	CorrelationID      string    `json:"correlation_id,omitempty"` // Echoed in the response envelope
+	TelegramID         int64     `json:"telegram_id"`
+	ChatID             int64     `json:"chat_id,omitempty"`
+	Username           string    `json:"username,omitempty"`
+	FirstName          string    `json:"first_name,omitempty"`
+	LastName           string    `json:"last_name,omitempty"`
+	ProductName        string    `json:"product_name,omitempty"`
+	TargetPrice        *float64  `json:"target_price,omitempty"`
+	DiscountPercentage *int      `json:"discount_percentage,omitempty"`
+	MinPrice           *float64  `json:"min_price,omitempty"`
+	ExcludedTerms      []string  `json:"excluded_terms,omitempty"`
+	MinCashback        *int      `json:"min_cashback,omitempty"`
+	MaxEffectivePrice  *float64  `json:"max_effective_price,omitempty"`
+	WishlistID         int       `json:"wishlist_id,omitempty"`
+	HideSuspicious     *bool     `json:"hide_suspicious,omitempty"`
+	Timestamp          time.Time `json:"timestamp"`
+}
+
+// WishlistItem represents a single wishlist item
+type WishlistItem struct {
+	ID                 int      `json:"id"`
+	ProductName        string   `json:"product_name"`
+	TargetPrice        *float64 `json:"target_price,omitempty"`
+	DiscountPercentage *int     `json:"discount_percentage,omitempty"`
+	MinPrice           *float64 `json:"min_price,omitempty"`
+	ExcludedTerms      []string `json:"excluded_terms,omitempty"`
+	MinCashback        *int     `json:"min_cashback,omitempty"`
+	MaxEffectivePrice  *float64 `json:"max_effective_price,omitempty"`
+}
+
+// WishlistResponse represents the response to a list command
+type WishlistResponse struct {
+	ChatID int64          `json:"chat_id"`
+	Items  []WishlistItem `json:"items"`
+}
+
+// WishlistAddedResponse confirms an add command with the saved item
+type WishlistAddedResponse struct {
+	ChatID int64        `json:"chat_id"`
+	Item   WishlistItem `json:"item"`
+}
+
+// WishlistUpdatedResponse confirms an update command with the saved item,
+// nil when the item was not found
+type WishlistUpdatedResponse struct {
+	ChatID  int64         `json:"chat_id"`
+	Success bool          `json:"success"`
+	Item    *WishlistItem `json:"item,omitempty"`
+}
+
+// DeleteResponse represents the response to a delete command
+type DeleteResponse struct {
+	ChatID  int64 `json:"chat_id"`
+	Success bool  `json:"success"`
+}
+
+// PreferencesResponse confirms a preferences command
+type PreferencesResponse struct {
+	ChatID         int64 `json:"chat_id"`
+	HideSuspicious bool  `json:"hide_suspicious"`
+}
+
+// Reasons of a command failed response
+const (
+	FailureUserNotRegistered = "user_not_registered"
+	FailureInvalidValues     = "invalid_values"
+)
+
+// CommandFailedResponse answers a command the backend rejected
+type CommandFailedResponse struct {
+	ChatID  int64  `json:"chat_id"`
+	Command string `json:"command"`
+	Reason  string `json:"reason"`
+}
+
+// BacktestSummary is how often a new wishlist item would have been notified in the past
+type BacktestSummary struct {
+	WishlistID    int     `json:"wishlist_id"`
+	Days          int     `json:"days"`
+	OffersScanned int     `json:"offers_scanned"`
+	HitCount      int     `json:"hit_count"`
+	DaysWithHits  int     `json:"days_with_hits"`
+	LowestPrice   float64 `json:"lowest_price,omitempty"`
+}
+
+// BacktestResponse represents the backtest summary sent after an add command
+type BacktestResponse struct {
+	ChatID   int64            `json:"chat_id"`
+	Backtest *BacktestSummary `json:"backtest"`
+}
+
+// PricePeriod summarizes the daily lowest prices of a product in a window
+type PricePeriod struct {
+	Days           int     `json:"days"`
+	MinPrice       float64 `json:"min_price"`
+	AvgPrice       float64 `json:"avg_price"`
+	DaysWithPrices int     `json:"days_with_prices"`
+}
+
+// PriceHistorySummary is the price history of a wishlist item. Chart is a PNG
+// line chart, empty when there are too few days to draw it.
+type PriceHistorySummary struct {
+	WishlistID   int           `json:"wishlist_id"`
+	Found        bool          `json:"found"`
+	ProductName  string        `json:"product_name,omitempty"`
+	Periods      []PricePeriod `json:"periods,omitempty"`
+	CurrentPrice float64       `json:"current_price,omitempty"`
+	CurrentDay   *time.Time    `json:"current_day,omitempty"`
+	Chart        []byte        `json:"chart,omitempty"`
+}
+
+// HistoryResponse represents the response to a price history command
+type HistoryResponse struct {
+	ChatID  int64                `json:"chat_id"`
+	History *PriceHistorySummary `json:"history"`
+}
@@ -0,0 +1,392 @@
+// Package telegramtest provides a fake Telegram Bot API server, so the bot can
+// be exercised offline. Tests script the messages users send to the bot and
+// assert on the messages the bot sends back. Point the frontend at it with
+// TELEGRAM_API_ENDPOINT (see Server.Endpoint).
+package telegramtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Token is the bot token accepted by the server
+const Token = "123456:telegramtest"
+
+// maxPollTimeout caps the long polling of getUpdates, so a stopped bot is
+// released quickly
+const maxPollTimeout = time.Second
+
+// SentMessage is a request made by the bot: a message or photo sent, a message
+// edited or deleted or a callback query answered
+type SentMessage struct {
+	Method      string // sendMessage, sendPhoto, editMessageText, deleteMessage or answerCallbackQuery
+	ChatID      int64
+	MessageID   int    // Id of the message sent, edited or deleted
+	Text        string // Text of a message, caption of a photo or text of a callback answer
+	Photo       string // URL or file name of a photo
+	ParseMode   string
+	ReplyMarkup string // Inline keyboard, as JSON
+	CallbackID  string // Id of the answered callback query
+}
+
+// Server is a fake Telegram Bot API server
+type Server struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	updates       []tgbotapi.Update // Updates not confirmed by the bot yet
+	lastUpdateID  int
+	lastMessageID int
+	sent          []SentMessage
+	callbackChats map[string]int64 // Chat of each callback query
+	changed       chan struct{}    // Closed when an update is added or a message is sent
+}
+
+// NewServer starts a server. Close it when done.
+func NewServer() *Server {
+	s := &Server{callbackChats: make(map[string]int64), changed: make(chan struct{})}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// URL returns the base URL of the server
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Endpoint returns the API endpoint of the server, in the format expected by
+// tgbotapi.NewBotAPIWithAPIEndpoint
+func (s *Server) Endpoint() string {
+	return s.server.URL + "/bot%s/%s"
+}
+
+// SendMessage queues a message sent by a user to the bot, in its private chat.
+// A text starting with "/" is a command, as in the Telegram clients. It
+// returns the id of the message.
+func (s *Server) SendMessage(userID int64, text string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastMessageID++
+	message := &tgbotapi.Message{
+		MessageID: s.lastMessageID,
+		From:      user(userID),
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command := strings.Fields(text)[0]
+		// Entity offsets and lengths are in UTF-16 code units
+		message.Entities = []tgbotapi.MessageEntity{{
+			Type:   "bot_command",
+			Offset: 0,
+			Length: len(utf16.Encode([]rune(command))),
+		}}
+	}
+
+	s.addUpdate(tgbotapi.Update{Message: message})
+	return message.MessageID
+}
+
+// SendCallback queues a user pressing an inline keyboard button with data on
+// a message of the bot. It returns the id of the callback query.
+func (s *Server) SendCallback(userID int64, messageID int, data string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := strconv.Itoa(s.lastUpdateID + 1)
+	s.callbackChats[id] = userID
+	s.addUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:   id,
+		From: user(userID),
+		Message: &tgbotapi.Message{
+			MessageID: messageID,
+			Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		},
+		Data: data,
+	}})
+	return id
+}
+
+// Sent returns the requests made by the bot so far, in order
+func (s *Server) Sent() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]SentMessage(nil), s.sent...)
+}
+
+// SentTo returns the requests made by the bot so far to a chat, in order
+func (s *Server) SentTo(chatID int64) []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sentTo(chatID)
+}
+
+// WaitForSent waits until the bot made at least n requests to a chat and
+// returns them. It fails when ctx is done first.
+func (s *Server) WaitForSent(ctx context.Context, chatID int64, n int) ([]SentMessage, error) {
+	for {
+		s.mu.Lock()
+		sent := s.sentTo(chatID)
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(sent) >= n {
+			return sent, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return sent, fmt.Errorf("got %d of %d messages to chat %d: %w", len(sent), n, chatID, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+// sentTo returns the requests made to a chat. Callers hold s.mu.
+func (s *Server) sentTo(chatID int64) []SentMessage {
+	var sent []SentMessage
+	for _, m := range s.sent {
+		if m.ChatID == chatID {
+			sent = append(sent, m)
+		}
+	}
+	return sent
+}
+
+// addUpdate queues an update. Callers hold s.mu.
+func (s *Server) addUpdate(update tgbotapi.Update) {
+	s.lastUpdateID++
+	update.UpdateID = s.lastUpdateID
+	s.updates = append(s.updates, update)
+	s.notify()
+}
+
+// record records a request of the bot. Callers hold s.mu.
+func (s *Server) record(m SentMessage) {
+	s.sent = append(s.sent, m)
+	s.notify()
+}
+
+// notify wakes up the pollers and waiters. Callers hold s.mu.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// handle serves the Bot API methods, at /bot<token>/<method>
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != Token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+			return
+		}
+	} else if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+
+	switch method {
+	case "getMe":
+		writeResult(w, tgbotapi.User{ID: 123456, IsBot: true, FirstName: "Offer Bot", UserName: "offer_test_bot"})
+	case "getUpdates":
+		s.getUpdates(w, r)
+	case "sendMessage":
+		s.sendMessage(w, r, method, r.FormValue("text"), "")
+	case "sendPhoto":
+		s.sendMessage(w, r, method, r.FormValue("caption"), photo(r))
+	case "editMessageText":
+		s.editMessageText(w, r)
+	case "deleteMessage":
+		s.mu.Lock()
+		s.record(SentMessage{
+			Method:    method,
+			ChatID:    formInt(r, "chat_id"),
+			MessageID: int(formInt(r, "message_id")),
+		})
+		s.mu.Unlock()
+		writeResult(w, true)
+	case "answerCallbackQuery":
+		s.answerCallbackQuery(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found: method "+method+" is not supported")
+	}
+}
+
+// getUpdates returns the updates from the offset, waiting up to the timeout
+// of the request for one to be queued
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request) {
+	offset := int(formInt(r, "offset"))
+	limit := int(formInt(r, "limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	timeout := time.Duration(formInt(r, "timeout")) * time.Second
+	if timeout > maxPollTimeout {
+		timeout = maxPollTimeout
+	}
+	deadline := time.After(timeout)
+
+	for {
+		s.mu.Lock()
+		// Updates before the offset are confirmed and forgotten
+		for len(s.updates) > 0 && s.updates[0].UpdateID < offset {
+			s.updates = s.updates[1:]
+		}
+		updates := s.updates
+		if len(updates) > limit {
+			updates = updates[:limit]
+		}
+		updates = append([]tgbotapi.Update{}, updates...)
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(updates) > 0 {
+			writeResult(w, updates)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline:
+			writeResult(w, updates)
+			return
+		case <-changed:
+		}
+	}
+}
+
+// sendMessage records a message or photo sent to a chat
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request, method, text, photo string) {
+	s.mu.Lock()
+	s.lastMessageID++
+	m := SentMessage{
+		Method:      method,
+		ChatID:      formInt(r, "chat_id"),
+		MessageID:   s.lastMessageID,
+		Text:        text,
+		Photo:       photo,
+		ParseMode:   r.FormValue("parse_mode"),
+		ReplyMarkup: r.FormValue("reply_markup"),
+	}
+	s.record(m)
+	s.mu.Unlock()
+
+	writeResult(w, message(m))
+}
+
+// editMessageText records the new text of a message
+func (s *Server) editMessageText(w http.ResponseWriter, r *http.Request) {
+	m := SentMessage{
+		Method:      "editMessageText",
+		ChatID:      formInt(r, "chat_id"),
+		MessageID:   int(formInt(r, "message_id")),
+		Text:        r.FormValue("text"),
+		ParseMode:   r.FormValue("parse_mode"),
+		ReplyMarkup: r.FormValue("reply_markup"),
+	}
+
+	s.mu.Lock()
+	s.record(m)
+	s.mu.Unlock()
+
+	writeResult(w, message(m))
+}
+
+// answerCallbackQuery records the answer of a callback query, in the chat of
+// the user who pressed the button
+func (s *Server) answerCallbackQuery(w http.ResponseWriter, r *http.Request) {
+	callbackID := r.FormValue("callback_query_id")
+
+	s.mu.Lock()
+	s.record(SentMessage{
+		Method:     "answerCallbackQuery",
+		ChatID:     s.callbackChats[callbackID],
+		Text:       r.FormValue("text"),
+		CallbackID: callbackID,
+	})
+	s.mu.Unlock()
+
+	writeResult(w, true)
+}
+
+// user returns the Telegram user of an id
+func user(id int64) *tgbotapi.User {
+	return &tgbotapi.User{ID: id, FirstName: "User " + strconv.FormatInt(id, 10), UserName: "user" + strconv.FormatInt(id, 10)}
+}
+
+// message returns the message of the result of a sent or edited message
+func message(m SentMessage) tgbotapi.Message {
+	msg := tgbotapi.Message{
+		MessageID: m.MessageID,
+		Chat:      &tgbotapi.Chat{ID: m.ChatID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+	}
+	if m.Photo != "" {
+		msg.Caption = m.Text
+		msg.Photo = []tgbotapi.PhotoSize{{FileID: m.Photo}}
+	} else {
+		msg.Text = m.Text
+	}
+	return msg
+}
+
+// photo returns the URL of a photo sent by URL or the name of an uploaded one
+func photo(r *http.Request) string {
+	if r.MultipartForm != nil {
+		if files := r.MultipartForm.File["photo"]; len(files) > 0 {
+			return files[0].Filename
+		}
+	}
+	return r.FormValue("photo")
+}
+
+// formInt returns an integer parameter of the request, or 0
+func formInt(r *http.Request, key string) int64 {
+	n, _ := strconv.ParseInt(r.FormValue(key), 10, 64)
+	return n
+}
+
+// writeResult writes a successful response with result
+func writeResult(w http.ResponseWriter, result interface{}) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Internal Server Error: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: data})
+}
+
+// writeError writes an error response, as the Bot API does
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: code, Description: description})
+}
@@ -0,0 +1,252 @@
+package telegramtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newBot starts a server and a bot client pointed at it
+func newBot(t *testing.T) (*Server, *tgbotapi.BotAPI) {
+	t.Helper()
+	server := NewServer()
+	t.Cleanup(server.Close)
+
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(Token, server.Endpoint())
+	if err != nil {
+		t.Fatalf("NewBotAPIWithAPIEndpoint() error = %v", err)
+	}
+	return server, bot
+}
+
+func TestRejectsOtherTokens(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	if _, err := tgbotapi.NewBotAPIWithAPIEndpoint("654321:other", server.Endpoint()); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("NewBotAPIWithAPIEndpoint() error = %v, want Unauthorized", err)
+	}
+}
+
+func TestGetUpdatesReturnsCommands(t *testing.T) {
+	server, bot := newBot(t)
+	server.SendMessage(10, "/add câmera 1500")
+	server.SendMessage(20, "oi")
+
+	updates, err := bot.GetUpdates(tgbotapi.UpdateConfig{Timeout: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("got %d updates, want 2", len(updates))
+	}
+
+	command := updates[0].Message
+	if !command.IsCommand() || command.Command() != "add" || command.CommandArguments() != "câmera 1500" || command.Chat.ID != 10 || command.From.ID != 10 {
+		t.Errorf("first update = %q from %d, want the /add command of user 10", command.Text, command.From.ID)
+	}
+	if updates[1].Message.IsCommand() || updates[1].Message.Text != "oi" || updates[1].UpdateID != updates[0].UpdateID+1 {
+		t.Errorf("second update = %+v, want the text of user 20", updates[1].Message)
+	}
+
+	// Updates before the offset are confirmed
+	updates, err = bot.GetUpdates(tgbotapi.UpdateConfig{Offset: updates[0].UpdateID + 1, Timeout: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].Message.Text != "oi" {
+		t.Errorf("got updates %+v after the offset, want the second one", updates)
+	}
+}
+
+func TestGetUpdatesLongPolls(t *testing.T) {
+	server, bot := newBot(t)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.SendMessage(10, "/list")
+	}()
+
+	start := time.Now()
+	updates, err := bot.GetUpdates(tgbotapi.UpdateConfig{Timeout: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].Message.Text != "/list" {
+		t.Fatalf("got updates %+v, want the /list command", updates)
+	}
+	if elapsed := time.Since(start); elapsed >= maxPollTimeout {
+		t.Errorf("poll returned after %s, want as soon as the message was queued", elapsed)
+	}
+
+	// Without updates the poll ends at the capped timeout
+	start = time.Now()
+	updates, err = bot.GetUpdates(tgbotapi.UpdateConfig{Offset: updates[0].UpdateID + 1, Timeout: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); len(updates) != 0 || elapsed < maxPollTimeout || elapsed > 3*maxPollTimeout {
+		t.Errorf("got %d updates after %s, want none after %s", len(updates), elapsed, maxPollTimeout)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	server, bot := newBot(t)
+
+	msg := tgbotapi.NewMessage(10, "<b>Geladeira</b> por R$ 2.500,00")
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Remover", "delete:5"),
+	))
+	sent, err := bot.Send(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.MessageID == 0 || sent.Chat.ID != 10 || sent.Text != msg.Text {
+		t.Errorf("Send() = %+v, want the message sent to chat 10", sent)
+	}
+
+	got := server.SentTo(10)
+	if len(got) != 1 {
+		t.Fatalf("got %d messages to chat 10, want 1", len(got))
+	}
+	m := got[0]
+	if m.Method != "sendMessage" || m.MessageID != sent.MessageID || m.Text != msg.Text || m.ParseMode != tgbotapi.ModeHTML ||
+		!strings.Contains(m.ReplyMarkup, `"callback_data":"delete:5"`) {
+		t.Errorf("recorded %+v, want the HTML message with its keyboard", m)
+	}
+	if len(server.SentTo(20)) != 0 || len(server.Sent()) != 1 {
+		t.Errorf("Sent() = %+v, want only the message to chat 10", server.Sent())
+	}
+}
+
+func TestSendPhoto(t *testing.T) {
+	server, bot := newBot(t)
+
+	byURL := tgbotapi.NewPhoto(10, tgbotapi.FileURL("https://img.example.com/geladeira.jpg"))
+	byURL.Caption = "Geladeira Consul"
+	sent, err := bot.Send(byURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.Caption != "Geladeira Consul" || len(sent.Photo) != 1 {
+		t.Errorf("Send() = %+v, want the photo with its caption", sent)
+	}
+
+	// Charts are uploaded
+	uploaded := tgbotapi.NewPhoto(10, tgbotapi.FileBytes{Name: "historico.png", Bytes: []byte("\x89PNG")})
+	uploaded.Caption = "Histórico de preços"
+	if _, err := bot.Send(uploaded); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.SentTo(10)
+	if len(got) != 2 {
+		t.Fatalf("got %d messages, want 2", len(got))
+	}
+	if got[0].Method != "sendPhoto" || got[0].Photo != "https://img.example.com/geladeira.jpg" || got[0].Text != "Geladeira Consul" {
+		t.Errorf("photo by URL recorded as %+v", got[0])
+	}
+	if got[1].Method != "sendPhoto" || got[1].Photo != "historico.png" || got[1].Text != "Histórico de preços" {
+		t.Errorf("uploaded photo recorded as %+v", got[1])
+	}
+}
+
+func TestEditAndDeleteMessage(t *testing.T) {
+	server, bot := newBot(t)
+
+	sent, err := bot.Send(tgbotapi.NewMessage(10, "Carregando..."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited, err := bot.Send(tgbotapi.NewEditMessageText(10, sent.MessageID, "Sua lista"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.MessageID != sent.MessageID || edited.Text != "Sua lista" {
+		t.Errorf("Send(edit) = %+v, want message %d with the new text", edited, sent.MessageID)
+	}
+
+	if _, err := bot.Request(tgbotapi.NewDeleteMessage(10, sent.MessageID)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.SentTo(10)
+	if len(got) != 3 {
+		t.Fatalf("got %d requests, want 3", len(got))
+	}
+	if got[1].Method != "editMessageText" || got[1].MessageID != sent.MessageID || got[1].Text != "Sua lista" {
+		t.Errorf("edit recorded as %+v", got[1])
+	}
+	if got[2].Method != "deleteMessage" || got[2].MessageID != sent.MessageID {
+		t.Errorf("delete recorded as %+v", got[2])
+	}
+}
+
+func TestCallbackQuery(t *testing.T) {
+	server, bot := newBot(t)
+
+	id := server.SendCallback(10, 7, "delete:5")
+	updates, err := bot.GetUpdates(tgbotapi.UpdateConfig{Timeout: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].CallbackQuery == nil {
+		t.Fatalf("got updates %+v, want the callback query", updates)
+	}
+	query := updates[0].CallbackQuery
+	if query.ID != id || query.Data != "delete:5" || query.From.ID != 10 || query.Message.MessageID != 7 || query.Message.Chat.ID != 10 {
+		t.Errorf("callback query = %+v, want the button of message 7 pressed by user 10", query)
+	}
+
+	if _, err := bot.Request(tgbotapi.NewCallback(id, "Item removido")); err != nil {
+		t.Fatal(err)
+	}
+	got := server.SentTo(10)
+	if len(got) != 1 || got[0].Method != "answerCallbackQuery" || got[0].CallbackID != id || got[0].Text != "Item removido" {
+		t.Errorf("got %+v, want the callback answer in chat 10", got)
+	}
+}
+
+func TestWaitForSent(t *testing.T) {
+	server, bot := newBot(t)
+
+	go func() {
+		for _, text := range []string{"primeira", "segunda"} {
+			time.Sleep(20 * time.Millisecond)
+			bot.Send(tgbotapi.NewMessage(10, text))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	sent, err := server.WaitForSent(ctx, 10, 2)
+	if err != nil {
+		t.Fatalf("WaitForSent() error = %v", err)
+	}
+	if len(sent) != 2 || sent[0].Text != "primeira" || sent[1].Text != "segunda" {
+		t.Errorf("WaitForSent() = %+v, want both messages in order", sent)
+	}
+
+	// Messages to other chats don't count
+	bot.Send(tgbotapi.NewMessage(20, "outra"))
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	sent, err = server.WaitForSent(ctx, 10, 3)
+	if err == nil || !strings.Contains(err.Error(), "got 2 of 3 messages to chat 10") || len(sent) != 2 {
+		t.Errorf("WaitForSent() = %d messages, %v, want the 2 sent and a timeout", len(sent), err)
+	}
+}
+
+func TestUnsupportedMethod(t *testing.T) {
+	_, bot := newBot(t)
+
+	_, err := bot.Request(tgbotapi.NewChatAction(10, tgbotapi.ChatTyping))
+	if err == nil || !strings.Contains(err.Error(), "sendChatAction is not supported") {
+		t.Errorf("Request(sendChatAction) error = %v, want not supported", err)
+	}
+}
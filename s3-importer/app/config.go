@@ -0,0 +1,39 @@
+package app
+
+import "os"
+
+// Config holds application configuration
+type Config struct {
+	KafkaBrokers     string
+	KafkaOffersTopic string
+	PostgresHost     string
+	PostgresPort     string
+	PostgresUser     string
+	PostgresPass     string
+	PostgresDB       string
+	Port             string
+	PushgatewayURL   string
+}
+
+// LoadConfig loads configuration from environment variables
+func LoadConfig() Config {
+	return Config{
+		KafkaBrokers:     getEnv("KAFKA_BROKERS", "kafka:9092"),
+		KafkaOffersTopic: getEnv("KAFKA_OFFERS_TOPIC", "offers"),
+		PostgresHost:     getEnv("POSTGRES_HOST", "postgres"),
+		PostgresPort:     getEnv("POSTGRES_PORT", "5432"),
+		PostgresUser:     getEnv("POSTGRES_USER", "postgres"),
+		PostgresPass:     getEnv("POSTGRES_PASSWORD", "postgres"),
+		PostgresDB:       getEnv("POSTGRES_DB", "postgres"),
+		Port:             getEnv("S3_IMPORTER_PORT", "8085"),
+		PushgatewayURL:   getEnv("PUSHGATEWAY_URL", ""),
+	}
+}
+
+// getEnv gets an environment variable with a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
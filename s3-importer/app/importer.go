@@ -0,0 +1,45 @@
+// Package app runs the S3 import job: it publishes the offers of the JSON
+// files of the active import templates. main runs it once against Kafka; the
+// all-in-one command runs it periodically in process.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/s3-importer/internal/importer"
+	"github.com/FlavioMalvestitiJunior/bf-offers/s3-importer/internal/metrics"
+	"github.com/FlavioMalvestitiJunior/bf-offers/s3-importer/internal/repository"
+)
+
+// Run executes all active import templates once
+func Run(ctx context.Context, config Config, db *sql.DB, publisher bus.Publisher) error {
+	// Initialize repository
+	importRepo := repository.NewImportTemplateRepository(db)
+
+	// Initialize importer
+	s3Importer := importer.NewS3Importer(
+		importRepo,
+		publisher,
+		config.KafkaOffersTopic,
+	)
+
+	// Run import job
+	if err := s3Importer.Run(ctx); err != nil {
+		return err
+	}
+	metrics.LastRun.SetToCurrentTime()
+	return nil
+}
+
+// PushMetrics pushes the metrics of the job to the Prometheus Pushgateway
+func PushMetrics(pushgatewayURL string) error {
+	return metrics.Push(pushgatewayURL)
+}
+
+// MetricsHandler serves the metrics in the Prometheus text format
+func MetricsHandler() http.Handler {
+	return metrics.Handler()
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const namespace = "offerbot_s3_importer"
+
+var (
+	// ImportRows counts the rows of the imported files by template and status
+	// (produced, invalid or failed)
+	ImportRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "import_rows_total",
+		Help:      "Rows read from the import files, by template and status.",
+	}, []string{"template", "status"})
+
+	// LastRun is the time the import job last finished
+	LastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_run_timestamp_seconds",
+		Help:      "Unix time the import job last finished.",
+	})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Push sends the metrics to a Prometheus Pushgateway. The job exits after a
+// run, so it is usually gone before Prometheus scrapes /metrics.
+func Push(url string) error {
+	return push.New(url, "s3-importer").Gatherer(prometheus.DefaultGatherer).Push()
+}
@@ -0,0 +1,409 @@
+// Package app runs the scraper: it publishes the offers found on Promobit,
+// periodically and when a wishlist item is added. main runs it against Kafka;
+// the all-in-one command runs it in process.
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/health"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/bf-offers/scraper/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tracer starts the spans of this package
+var tracer = tracing.NewTracer("github.com/yourusername/bf-offers/scraper/app")
+
+// Offer represents the Kafka message schema
+type Offer struct {
+	ExternalID         string    `json:"externalId,omitempty"` // Id of the offer at its source
+	Seller             string    `json:"seller,omitempty"`
+	ProductName        string    `json:"titulo"`
+	Price              float64   `json:"price"`
+	OriginalPrice      float64   `json:"oldPrice"`
+	Details            string    `json:"details"`
+	CashbackPercentage int       `json:"percentCashback"`
+	URL                string    `json:"url,omitempty"`
+	ImageURL           string    `json:"imageUrl,omitempty"`
+	Source             string    `json:"source"`
+	ReceivedAt         time.Time `json:"received_at"`
+}
+
+// WishlistEvent represents an event when a wishlist item is added, updated or deleted
+type WishlistEvent struct {
+	Type               string    `json:"type"`
+	TelegramID         int64     `json:"telegram_id"`
+	ProductName        string    `json:"product_name"`
+	TargetPrice        *float64  `json:"target_price,omitempty"`
+	DiscountPercentage *int      `json:"discount_percentage,omitempty"`
+	Timestamp          time.Time `json:"timestamp"`
+}
+
+// Promobit API Response Structures
+
+type PromobitSearchResponse struct {
+	Data struct {
+		Offers []PromobitOffer `json:"offers"`
+		Meta   struct {
+			CurrentPage int `json:"current_page"`
+			LastPage    int `json:"last_page"`
+		} `json:"meta"`
+	} `json:"data"`
+}
+
+type PromobitOffer struct {
+	ID          int     `json:"id"`
+	Title       string  `json:"title"`
+	Price       float64 `json:"price"`
+	OldPrice    float64 `json:"old_price"`
+	Description string  `json:"description"`
+	URL         string  `json:"url"`
+	Image       string  `json:"image"`
+	IsActive    bool    `json:"is_active"`
+	Cashback    struct {
+		Percentage int `json:"percentage"`
+	} `json:"cashback"`
+	Store struct {
+		Name string `json:"name"`
+	} `json:"store"`
+}
+
+type PromobitHomeResponse struct {
+	PageProps struct {
+		Offers []PromobitOffer `json:"offers"`
+	} `json:"pageProps"`
+}
+
+// Scraper is a started scraper
+type Scraper struct {
+	// Checker holds the readiness checks of the cache and of the wishlist
+	// events consumer. Callers add the checks of their message bus.
+	Checker *health.Checker
+}
+
+// Start starts the periodic scraping of the Promobit home and of the wishlist
+// items and the consumer of the wishlist events. They stop when ctx is
+// cancelled.
+func Start(ctx context.Context, config Config, redisClient *redis.Client, messageBus bus.Bus) (*Scraper, error) {
+	// Start periodic scraping (5 min) - General Promobit
+	go startPeriodicScraping(ctx, messageBus, config, 5*time.Minute, scrapePromobitHome)
+
+	// Start periodic wishlist scraping (10 min)
+	go startPeriodicWishlistScraping(ctx, redisClient, messageBus, config, 10*time.Minute)
+
+	// Start consumer for on-demand scraping
+	wishlistEvents, err := messageBus.Subscribe(ctx, bus.Subscription{
+		Topic: config.KafkaWishlistEventsTopic,
+		Group: consumerGroupID,
+	}, func(ctx context.Context, message *bus.Message) error {
+		return handleWishlistEvent(ctx, message, messageBus, config)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start wishlist events consumer: %w", err)
+	}
+
+	// Readiness checks of the dependencies, served on /health/ready
+	checker := health.NewChecker(3 * time.Second)
+	checker.Add("redis", func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	})
+	checker.Add("kafka_consumer_wishlist_events", wishlistEvents.Check)
+
+	return &Scraper{Checker: checker}, nil
+}
+
+// Handler serves /health/live for liveness and /health/ready for readiness
+// probes and the Prometheus metrics on /metrics
+func (s *Scraper) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", s.Checker.LiveHandler)
+	mux.HandleFunc("/health/ready", s.Checker.ReadyHandler)
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
+
+func startPeriodicScraping(ctx context.Context, publisher bus.Publisher, config Config, interval time.Duration, scrapeFunc func(context.Context, bus.Publisher, Config)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Run immediately once
+	scrapeFunc(ctx, publisher, config)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scrapeFunc(ctx, publisher, config)
+		}
+	}
+}
+
+func startPeriodicWishlistScraping(ctx context.Context, redisClient *redis.Client, publisher bus.Publisher, config Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scrapeWishlistItems(ctx, redisClient, publisher, config)
+		}
+	}
+}
+
+// consumerGroupID is the consumer group of the wishlist events consumer
+const consumerGroupID = "scraper-consumer-group"
+
+// handleWishlistEvent searches the new or changed wishlist items right away
+func handleWishlistEvent(ctx context.Context, message *bus.Message, publisher bus.Publisher, config Config) error {
+	var event WishlistEvent
+	if err := json.Unmarshal(message.Value, &event); err != nil {
+		log.Printf("Failed to unmarshal wishlist event: %v", err)
+		return nil // Don't retry malformed messages
+	}
+
+	// New or changed items are searched right away
+	if event.Type == "wishlist_item_added" || event.Type == "wishlist_item_updated" {
+		log.Printf("Received wishlist event for: %s", event.ProductName)
+		ctx, span := tracer.StartConsumerSpan(ctx, message, consumerGroupID)
+		scrapePromobitSearch(ctx, publisher, config, event.ProductName)
+		tracing.End(span, nil)
+	}
+	return nil
+}
+
+// Scrape Logic using Promobit API
+
+func scrapePromobitHome(ctx context.Context, publisher bus.Publisher, config Config) {
+	log.Println("Fetching Promobit Home via API...")
+	defer observeScrape("home", "", time.Now())
+
+	ctx, span := tracer.Start(ctx, "scrape home")
+	defer span.End()
+
+	// Use the Next.js data endpoint
+	resp, err := http.Get("https://www.promobit.com.br/_next/data/bcc3e837c1/index.json")
+	if err != nil {
+		log.Printf("Failed to fetch Promobit home: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Promobit home returned status: %d", resp.StatusCode)
+		return
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("Failed to read response body: %v", err)
+		return
+	}
+
+	var homeResp PromobitHomeResponse
+	if err := json.Unmarshal(body, &homeResp); err != nil {
+		log.Printf("Failed to unmarshal home response: %v", err)
+		return
+	}
+
+	count := 0
+	for _, promobitOffer := range homeResp.PageProps.Offers {
+		// Only process active offers
+		if !promobitOffer.IsActive {
+			continue
+		}
+
+		offer := convertPromobitOffer(promobitOffer)
+		publishOffer(ctx, publisher, offer, config.KafkaOffersTopic, "home")
+		count++
+	}
+
+	log.Printf("Published %d offers from Promobit home", count)
+}
+
+func scrapePromobitSearch(ctx context.Context, publisher bus.Publisher, config Config, query string) {
+	log.Printf("Searching Promobit API for: %s", query)
+	defer observeScrape("search", metrics.TermLabel(query), time.Now())
+
+	ctx, span := tracer.Start(ctx, "scrape search", attribute.String("scraper.term", query))
+	defer span.End()
+
+	encodedQuery := url.QueryEscape(query)
+	page := 1
+	totalCount := 0
+
+	for {
+		apiURL := fmt.Sprintf("https://api.promobit.com.br/search/result/offers?q=%s&page=%d", encodedQuery, page)
+
+		resp, err := http.Get(apiURL)
+		if err != nil {
+			log.Printf("Failed to fetch Promobit search page %d: %v", page, err)
+			break
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			log.Printf("Promobit search returned status: %d", resp.StatusCode)
+			resp.Body.Close()
+			break
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if err != nil {
+			log.Printf("Failed to read response body: %v", err)
+			break
+		}
+
+		var searchResp PromobitSearchResponse
+		if err := json.Unmarshal(body, &searchResp); err != nil {
+			log.Printf("Failed to unmarshal search response: %v", err)
+			break
+		}
+
+		// Process offers from this page
+		pageCount := 0
+		for _, promobitOffer := range searchResp.Data.Offers {
+			// Only process active offers
+			if !promobitOffer.IsActive {
+				continue
+			}
+
+			offer := convertPromobitOffer(promobitOffer)
+			publishOffer(ctx, publisher, offer, config.KafkaOffersTopic, "search")
+			pageCount++
+			totalCount++
+		}
+
+		log.Printf("Published %d active offers from page %d", pageCount, page)
+
+		// Check if there are more pages
+		if page >= searchResp.Data.Meta.LastPage {
+			break
+		}
+
+		page++
+		time.Sleep(1 * time.Second) // Polite delay between pages
+	}
+
+	log.Printf("Total published %d active offers for query: %s", totalCount, query)
+}
+
+// observeScrape records the duration of a scrape started at start
+func observeScrape(page, term string, start time.Time) {
+	metrics.ScrapeDuration.WithLabelValues(page, term).Observe(time.Since(start).Seconds())
+}
+
+func scrapeWishlistItems(ctx context.Context, redisClient *redis.Client, publisher bus.Publisher, config Config) {
+	log.Println("Scraping all wishlist items...")
+
+	terms, err := redisClient.SMembers(context.Background(), "all_wishlist_terms").Result()
+	if err != nil {
+		log.Printf("Failed to get wishlist terms from Redis: %v", err)
+		return
+	}
+	metrics.SetActiveTerms(terms)
+
+	for _, term := range terms {
+		scrapePromobitSearch(ctx, publisher, config, term)
+		time.Sleep(2 * time.Second) // Polite delay
+	}
+}
+
+func convertPromobitOffer(promobitOffer PromobitOffer) *Offer {
+	return &Offer{
+		ExternalID:         strconv.Itoa(promobitOffer.ID),
+		Seller:             promobitOffer.Store.Name,
+		ProductName:        promobitOffer.Title,
+		Price:              promobitOffer.Price,
+		OriginalPrice:      promobitOffer.OldPrice,
+		Details:            promobitOffer.Description,
+		CashbackPercentage: promobitOffer.Cashback.Percentage,
+		URL:                promobitURL(promobitOffer.URL),
+		ImageURL:           promobitURL(promobitOffer.Image),
+		Source:             "promobit-api",
+		ReceivedAt:         time.Now(),
+	}
+}
+
+// promobitURL makes links returned relative to the Promobit site absolute
+func promobitURL(link string) string {
+	if strings.HasPrefix(link, "/") {
+		return "https://www.promobit.com.br" + link
+	}
+	return link
+}
+
+func publishOffer(ctx context.Context, publisher bus.Publisher, offer *Offer, topic, page string) {
+	bytes, err := json.Marshal(offer)
+	if err != nil {
+		log.Printf("Failed to marshal offer: %v", err)
+		metrics.OffersPublished.WithLabelValues(page, "failed").Inc()
+		return
+	}
+
+	msg := &bus.Message{
+		Topic: topic,
+		Value: bytes,
+	}
+
+	ctx, span := tracer.StartProducerSpan(ctx, msg)
+	err = publisher.Publish(ctx, msg)
+	tracing.End(span, err)
+	if err != nil {
+		log.Printf("Failed to publish offer: %v", err)
+		metrics.OffersPublished.WithLabelValues(page, "failed").Inc()
+	} else {
+		log.Printf("Published offer: %s (R$ %.2f)", offer.ProductName, offer.Price)
+		metrics.OffersPublished.WithLabelValues(page, "published").Inc()
+	}
+}
+
+// Config and Init
+
+type Config struct {
+	KafkaBrokers             string
+	KafkaOffersTopic         string
+	KafkaWishlistEventsTopic string
+	RedisHost                string
+	RedisPort                string
+	RedisPassword            string
+	RedisDB                  int
+	Port                     string
+}
+
+// LoadConfig loads configuration from environment variables
+func LoadConfig() Config {
+	return Config{
+		KafkaBrokers:             getEnv("KAFKA_BROKERS", "kafka:9092"),
+		KafkaOffersTopic:         getEnv("KAFKA_OFFERS_TOPIC", "offers"),
+		KafkaWishlistEventsTopic: getEnv("KAFKA_WISHLIST_EVENTS_TOPIC", "wishlist-events"),
+		RedisHost:                getEnv("REDIS_HOST", "redis"),
+		RedisPort:                getEnv("REDIS_PORT", "6379"),
+		RedisPassword:            getEnv("REDIS_PASSWORD", ""),
+		RedisDB:                  0,
+		Port:                     getEnv("SCRAPER_PORT", "8083"),
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
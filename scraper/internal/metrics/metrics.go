@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "offerbot_scraper"
+
+// Search terms come from users, so only up to MaxTerms of them get their own
+// term label; the scrapes of the others are labeled OtherTerm
+const (
+	MaxTerms  = 100
+	OtherTerm = "other"
+)
+
+var (
+	// ScrapeDuration measures a scrape of the home page or of every result
+	// page of a search term. Term is empty for the home page and is the label
+	// returned by TermLabel for searches.
+	ScrapeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scrape_duration_seconds",
+		Help:      "Time spent scraping Promobit, by page and search term.",
+		Buckets:   []float64{.25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"page", "term"})
+
+	// OffersPublished counts the offers published to Kafka by page (home or
+	// search) and status (published or failed)
+	OffersPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "offers_published_total",
+		Help:      "Scraped offers published to the offers topic, by page and status.",
+	}, []string{"page", "status"})
+)
+
+// terms are the search terms with their own term label
+var terms = struct {
+	sync.Mutex
+	labeled map[string]bool
+}{labeled: make(map[string]bool)}
+
+// normalizeTerm folds the case and spacing of a search term, so variants of
+// the same term share a label
+func normalizeTerm(term string) string {
+	return strings.Join(strings.Fields(strings.ToLower(term)), " ")
+}
+
+// SetActiveTerms labels the active wishlist terms, up to MaxTerms of them in
+// alphabetical order, and removes the series of the terms no longer labeled
+func SetActiveTerms(active []string) {
+	normalized := make([]string, 0, len(active))
+	for _, term := range active {
+		if term = normalizeTerm(term); term != "" {
+			normalized = append(normalized, term)
+		}
+	}
+	sort.Strings(normalized)
+
+	labeled := make(map[string]bool)
+	for _, term := range normalized {
+		if len(labeled) == MaxTerms {
+			break
+		}
+		labeled[term] = true
+	}
+
+	terms.Lock()
+	defer terms.Unlock()
+	for term := range terms.labeled {
+		if !labeled[term] {
+			ScrapeDuration.DeletePartialMatch(prometheus.Labels{"term": term})
+		}
+	}
+	terms.labeled = labeled
+}
+
+// TermLabel returns the term label of a search: the normalized term when it is
+// labeled or there is room to label it, OtherTerm otherwise
+func TermLabel(term string) string {
+	term = normalizeTerm(term)
+
+	terms.Lock()
+	defer terms.Unlock()
+	if terms.labeled[term] {
+		return term
+	}
+	if term == "" || len(terms.labeled) >= MaxTerms {
+		return OtherTerm
+	}
+	terms.labeled[term] = true
+	return term
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
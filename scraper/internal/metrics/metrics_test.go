@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTermLabel(t *testing.T) {
+	SetActiveTerms([]string{"  iPhone   15 ", "Geladeira"})
+
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"iphone 15", "iphone 15"},
+		{"IPHONE 15", "iphone 15"},
+		{"geladeira", "geladeira"},
+		{"air fryer", "air fryer"}, // Not active, but there is room
+		{"  ", OtherTerm},
+	}
+
+	for _, tt := range tests {
+		if got := TermLabel(tt.term); got != tt.want {
+			t.Errorf("TermLabel(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestTermLabelsAreCapped(t *testing.T) {
+	active := make([]string, MaxTerms+10)
+	for i := range active {
+		active[i] = fmt.Sprintf("term %03d", i)
+	}
+	SetActiveTerms(active)
+
+	if got := TermLabel("term 000"); got != "term 000" {
+		t.Errorf("TermLabel(first term) = %q, want it labeled", got)
+	}
+	if got := TermLabel(active[MaxTerms]); got != OtherTerm {
+		t.Errorf("TermLabel(term above the cap) = %q, want %q", got, OtherTerm)
+	}
+	if got := TermLabel("new term"); got != OtherTerm {
+		t.Errorf("TermLabel(new term when full) = %q, want %q", got, OtherTerm)
+	}
+}
+
+func TestSetActiveTermsRemovesInactiveSeries(t *testing.T) {
+	SetActiveTerms(nil)
+	ScrapeDuration.WithLabelValues("search", TermLabel("notebook")).Observe(1)
+	ScrapeDuration.WithLabelValues("search", TermLabel("tv")).Observe(1)
+	before := testutil.CollectAndCount(ScrapeDuration)
+
+	SetActiveTerms([]string{"tv"})
+
+	if after := testutil.CollectAndCount(ScrapeDuration); after != before-1 {
+		t.Errorf("%d series after removing notebook, want %d", after, before-1)
+	}
+	if got := TermLabel("tv"); got != "tv" {
+		t.Errorf("TermLabel(active term) = %q, want it labeled", got)
+	}
+}
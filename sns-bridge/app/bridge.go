@@ -0,0 +1,390 @@
+// Package app runs the SNS bridge: it maps the messages of an SQS queue
+// subscribed to SNS to offers, using the message templates, and publishes
+// them. main runs it against Kafka; the all-in-one command runs it in process.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/health"
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/tracing"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/flaviomalvestitijunior/bf-offers/sns-bridge/internal/metrics"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+)
+
+// tracer starts the spans of this package
+var tracer = tracing.NewTracer("github.com/flaviomalvestitijunior/bf-offers/sns-bridge/app")
+
+// MessageTemplate represents a template for SNS messages
+type MessageTemplate struct {
+	ID               int
+	Name             string
+	ProductModel     string
+	TitleField       string
+	DescriptionField *string
+	PriceField       string
+	DiscountField    *string
+	DetailsFields    *string // JSON array string
+	URLField         *string
+	ImageField       *string
+	IsActive         bool
+}
+
+// Offer represents the Kafka message schema
+type Offer struct {
+	ExternalID         string    `json:"externalId,omitempty"` // Id of the offer at its source
+	Seller             string    `json:"seller,omitempty"`
+	ProductName        string    `json:"titulo"`
+	Price              float64   `json:"price"`
+	OriginalPrice      float64   `json:"oldPrice"`
+	Details            string    `json:"details"`
+	CashbackPercentage int       `json:"percentCashback"`
+	URL                string    `json:"url,omitempty"`
+	ImageURL           string    `json:"imageUrl,omitempty"`
+	Source             string    `json:"source"`
+	ReceivedAt         time.Time `json:"received_at"`
+}
+
+// Bridge is the bridge of an SQS queue
+type Bridge struct {
+	// Checker holds the readiness checks of the database and of the queue.
+	// Callers add the checks of their message bus.
+	Checker *health.Checker
+
+	config    Config
+	db        *sql.DB
+	publisher bus.Publisher
+	sqsClient *sqs.SQS
+}
+
+// NewBridge creates the bridge of the queue at config.SNSQueueURL
+func NewBridge(config Config, db *sql.DB, publisher bus.Publisher) (*Bridge, error) {
+	// Initialize SNS/SQS Consumer
+	sqsClient, err := initSQSClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SQS client: %w", err)
+	}
+
+	// Readiness checks of the dependencies, served on /health/ready
+	checker := health.NewChecker(3 * time.Second)
+	checker.Add("postgres", db.PingContext)
+	checker.Add("sqs", func(ctx context.Context) error {
+		_, err := sqsClient.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl:       aws.String(config.SNSQueueURL),
+			AttributeNames: []*string{aws.String(sqs.QueueAttributeNameQueueArn)},
+		})
+		return err
+	})
+
+	return &Bridge{
+		Checker:   checker,
+		config:    config,
+		db:        db,
+		publisher: publisher,
+		sqsClient: sqsClient,
+	}, nil
+}
+
+// Run polls the queue and publishes the offers mapped from its messages until
+// ctx is cancelled
+func (b *Bridge) Run(ctx context.Context) {
+	pollLoop(ctx, b.sqsClient, b.db, b.publisher, b.config)
+}
+
+// Handler serves /health/live for liveness and /health/ready for readiness
+// probes and the Prometheus metrics on /metrics
+func (b *Bridge) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", b.Checker.LiveHandler)
+	mux.HandleFunc("/health/ready", b.Checker.ReadyHandler)
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
+
+func pollLoop(ctx context.Context, sqsClient *sqs.SQS, db *sql.DB, publisher bus.Publisher, config Config) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			// Receive messages
+			result, err := sqsClient.ReceiveMessage(&sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String(config.SNSQueueURL),
+				MaxNumberOfMessages: aws.Int64(10),
+				WaitTimeSeconds:     aws.Int64(20), // Long polling
+				VisibilityTimeout:   aws.Int64(30),
+			})
+
+			if err != nil {
+				log.Printf("Error receiving messages: %v", err)
+				metrics.SQSPolls.WithLabelValues("error").Inc()
+				time.Sleep(5 * time.Second)
+				continue
+			}
+
+			if len(result.Messages) == 0 {
+				metrics.SQSPolls.WithLabelValues("empty").Inc()
+			} else {
+				metrics.SQSPolls.WithLabelValues("messages").Inc()
+				metrics.SQSMessages.Add(float64(len(result.Messages)))
+			}
+
+			// Process messages
+			for _, message := range result.Messages {
+				processMessage(ctx, message, db, publisher, config)
+
+				// Delete message
+				_, err := sqsClient.DeleteMessage(&sqs.DeleteMessageInput{
+					QueueUrl:      aws.String(config.SNSQueueURL),
+					ReceiptHandle: message.ReceiptHandle,
+				})
+				if err != nil {
+					log.Printf("Error deleting message: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func processMessage(ctx context.Context, message *sqs.Message, db *sql.DB, publisher bus.Publisher, config Config) {
+	if message.Body == nil {
+		return
+	}
+
+	// SQS messages carry no trace context, so each one starts a trace
+	ctx, span := tracer.Start(ctx, "sqs process",
+		semconv.MessagingSystemAWSSqs,
+		semconv.MessagingMessageID(aws.StringValue(message.MessageId)),
+	)
+	defer span.End()
+
+	// Parse SNS wrapper if present
+	var bodyStr string
+	var snsMessage struct {
+		Message string `json:"Message"`
+	}
+	if err := json.Unmarshal([]byte(*message.Body), &snsMessage); err == nil && snsMessage.Message != "" {
+		bodyStr = snsMessage.Message
+	} else {
+		bodyStr = *message.Body
+	}
+
+	// Parse JSON body to map
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(bodyStr), &data); err != nil {
+		log.Printf("Failed to parse message body: %v", err)
+		return
+	}
+
+	// Load active templates
+	templates, err := loadActiveTemplates(db)
+	if err != nil {
+		log.Printf("Failed to load templates: %v", err)
+		return
+	}
+
+	// Try to match/map using templates
+	for _, tmpl := range templates {
+		offer, err := mapToOffer(data, tmpl)
+		if err != nil {
+			continue // Template didn't match or error mapping
+		}
+
+		// Publish to Kafka
+		if err := publishOffer(ctx, publisher, offer, config.KafkaOffersTopic); err != nil {
+			log.Printf("Failed to publish offer: %v", err)
+			metrics.OffersPublished.WithLabelValues(tmpl.Name, "failed").Inc()
+		} else {
+			log.Printf("Published offer: %s (Template: %s)", offer.ProductName, tmpl.Name)
+			metrics.OffersPublished.WithLabelValues(tmpl.Name, "published").Inc()
+		}
+	}
+}
+
+func loadActiveTemplates(db *sql.DB) ([]MessageTemplate, error) {
+	rows, err := db.Query(`
+		SELECT id, name, product_model, title_field, description_field, price_field, 
+		       discount_field, details_fields, url_field, image_field
+		FROM message_templates 
+		WHERE is_active = true
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []MessageTemplate
+	for rows.Next() {
+		var t MessageTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.ProductModel, &t.TitleField,
+			&t.DescriptionField, &t.PriceField, &t.DiscountField, &t.DetailsFields,
+			&t.URLField, &t.ImageField); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func mapToOffer(data map[string]interface{}, tmpl MessageTemplate) (*Offer, error) {
+	// Extract fields based on template
+	title, ok := getString(data, tmpl.TitleField)
+	if !ok {
+		return nil, fmt.Errorf("title field not found")
+	}
+
+	price, ok := getFloat(data, tmpl.PriceField)
+	if !ok {
+		return nil, fmt.Errorf("price field not found")
+	}
+
+	var oldPrice float64
+	// Logic for oldPrice? Maybe just same as price if not found, or 0.
+	// The requirement says "oldPrice". If not in template, maybe 0.
+	// I'll assume 0 for now as it's not in the template explicit mapping (except maybe description/details).
+
+	var details string
+	if tmpl.DetailsFields != nil && *tmpl.DetailsFields != "" {
+		var fields []string
+		if err := json.Unmarshal([]byte(*tmpl.DetailsFields), &fields); err == nil {
+			var parts []string
+			for _, f := range fields {
+				if val, ok := getString(data, f); ok {
+					parts = append(parts, val)
+				}
+			}
+			details = strings.Join(parts, " | ")
+		}
+	}
+	if details == "" && tmpl.DescriptionField != nil {
+		details, _ = getString(data, *tmpl.DescriptionField)
+	}
+
+	// Cashback? Not in template currently. Default to 0.
+
+	var offerURL, imageURL string
+	if tmpl.URLField != nil && *tmpl.URLField != "" {
+		offerURL, _ = getString(data, *tmpl.URLField)
+	}
+	if tmpl.ImageField != nil && *tmpl.ImageField != "" {
+		imageURL, _ = getString(data, *tmpl.ImageField)
+	}
+
+	return &Offer{
+		ProductName:        title,
+		Price:              price,
+		OriginalPrice:      oldPrice,
+		Details:            details,
+		CashbackPercentage: 0,
+		URL:                offerURL,
+		ImageURL:           imageURL,
+		Source:             "sns-bridge",
+		ReceivedAt:         time.Now(),
+	}, nil
+}
+
+func getString(data map[string]interface{}, key string) (string, bool) {
+	val, ok := data[key]
+	if !ok {
+		return "", false
+	}
+	if str, ok := val.(string); ok {
+		return str, true
+	}
+	return fmt.Sprintf("%v", val), true
+}
+
+func getFloat(data map[string]interface{}, key string) (float64, bool) {
+	val, ok := data[key]
+	if !ok {
+		return 0, false
+	}
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		// Try parsing? For now assume number type in JSON
+		return 0, false
+	default:
+		return 0, false
+	}
+}
+
+func publishOffer(ctx context.Context, publisher bus.Publisher, offer *Offer, topic string) error {
+	bytes, err := json.Marshal(offer)
+	if err != nil {
+		return err
+	}
+
+	msg := &bus.Message{
+		Topic: topic,
+		Value: bytes,
+	}
+
+	ctx, span := tracer.StartProducerSpan(ctx, msg)
+	err = publisher.Publish(ctx, msg)
+	tracing.End(span, err)
+	return err
+}
+
+// Config and Init functions...
+
+type Config struct {
+	AWSRegion        string
+	SNSQueueURL      string
+	KafkaBrokers     string
+	KafkaOffersTopic string
+	PostgresHost     string
+	PostgresPort     string
+	PostgresUser     string
+	PostgresPass     string
+	PostgresDB       string
+	Port             string
+}
+
+// LoadConfig loads configuration from environment variables
+func LoadConfig() Config {
+	return Config{
+		AWSRegion:        getEnv("AWS_REGION", "us-east-1"),
+		SNSQueueURL:      getEnv("SNS_QUEUE_URL", ""),
+		KafkaBrokers:     getEnv("KAFKA_BROKERS", "kafka:9092"),
+		KafkaOffersTopic: getEnv("KAFKA_OFFERS_TOPIC", "offers"),
+		PostgresHost:     getEnv("POSTGRES_HOST", "postgres"),
+		PostgresPort:     getEnv("POSTGRES_PORT", "5432"),
+		PostgresUser:     getEnv("POSTGRES_USER", "offerbot"),
+		PostgresPass:     getEnv("POSTGRES_PASSWORD", "offerbot123"),
+		PostgresDB:       getEnv("POSTGRES_DB", "offerbot"),
+		Port:             getEnv("SNS_BRIDGE_PORT", "8084"),
+	}
+}
+
+func initSQSClient(config Config) (*sqs.SQS, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(config.AWSRegion),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sqs.New(sess), nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
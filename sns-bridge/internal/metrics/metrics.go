@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "offerbot_sns_bridge"
+
+var (
+	// SQSPolls counts the SQS receive calls by result (messages, empty or error)
+	SQSPolls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sqs_polls_total",
+		Help:      "SQS receive calls, by result.",
+	}, []string{"result"})
+
+	// SQSMessages counts the messages received from SQS
+	SQSMessages = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sqs_messages_received_total",
+		Help:      "Messages received from SQS.",
+	})
+
+	// OffersPublished counts the offers mapped from SQS messages by template
+	// and status (published or failed)
+	OffersPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "offers_published_total",
+		Help:      "Offers published to the offers topic, by template and status.",
+	}, []string{"template", "status"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
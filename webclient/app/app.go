@@ -0,0 +1,93 @@
+// Package app serves the web client: the admin API and its static pages.
+// main serves it on its own; the all-in-one command serves it in process.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/FlavioMalvestitiJunior/bf-offers/bus/health"
+	"github.com/FlavioMalvestitiJunior/bf-offers/webclient/internal/handlers"
+	"github.com/FlavioMalvestitiJunior/bf-offers/webclient/internal/metrics"
+	"github.com/FlavioMalvestitiJunior/bf-offers/webclient/internal/repository"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+)
+
+// NewHandler returns the handler of the web client. Match explanations are
+// asked to the backend at backendURL and the pages are served from staticDir.
+func NewHandler(db *sql.DB, rdb *redis.Client, backendURL, staticDir string) http.Handler {
+	// Initialize repositories
+	statsRepo := repository.NewStatsRepository(db, rdb)
+	templateRepo := repository.NewTemplateRepository(db)
+	importTemplateRepo := repository.NewImportTemplateRepository(db)
+
+	// Initialize handlers
+	dashboardHandler := handlers.NewDashboardHandler(statsRepo)
+	templateHandler := handlers.NewTemplateHandler(templateRepo)
+	importTemplateHandler := handlers.NewImportTemplateHandler(importTemplateRepo)
+	explainHandler := handlers.NewExplainHandler(backendURL)
+
+	// Setup router
+	r := mux.NewRouter()
+
+	// API routes
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(metrics.Middleware)
+
+	// Dashboard endpoints
+	api.HandleFunc("/stats", dashboardHandler.GetStats).Methods("GET")
+	api.HandleFunc("/users/active", dashboardHandler.GetActiveUsers).Methods("GET")
+	api.HandleFunc("/users/search", dashboardHandler.SearchUsers).Methods("GET")
+	api.HandleFunc("/users/{id}/wishlist", dashboardHandler.GetUserWishlist).Methods("GET")
+	api.HandleFunc("/users/{id}/blacklist", dashboardHandler.BlacklistUser).Methods("POST")
+	api.HandleFunc("/users/{id}/blacklist", dashboardHandler.UnblacklistUser).Methods("DELETE")
+	api.HandleFunc("/users/{id}", dashboardHandler.DeleteUser).Methods("DELETE")
+
+	// Template endpoints
+	api.HandleFunc("/templates", templateHandler.GetAllTemplates).Methods("GET")
+	api.HandleFunc("/templates", templateHandler.CreateTemplate).Methods("POST")
+	api.HandleFunc("/templates/{id}", templateHandler.GetTemplate).Methods("GET")
+	api.HandleFunc("/templates/{id}", templateHandler.UpdateTemplate).Methods("PUT")
+	api.HandleFunc("/templates/{id}", templateHandler.DeleteTemplate).Methods("DELETE")
+
+	// Import template endpoints
+	api.HandleFunc("/import-templates", importTemplateHandler.GetAllTemplates).Methods("GET")
+	api.HandleFunc("/import-templates", importTemplateHandler.CreateTemplate).Methods("POST")
+	api.HandleFunc("/import-templates/{id}", importTemplateHandler.GetTemplate).Methods("GET")
+	api.HandleFunc("/import-templates/{id}", importTemplateHandler.UpdateTemplate).Methods("PUT")
+	api.HandleFunc("/import-templates/{id}", importTemplateHandler.DeleteTemplate).Methods("DELETE")
+	api.HandleFunc("/import-templates/test", importTemplateHandler.TestS3URL).Methods("POST")
+
+	// Match explanation endpoint
+	api.HandleFunc("/explain", explainHandler.Explain).Methods("POST")
+
+	// Health checks: /health/live for liveness and /health/ready for readiness probes
+	checker := health.NewChecker(3 * time.Second)
+	checker.Add("postgres", db.PingContext)
+	checker.Add("redis", func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	})
+	r.HandleFunc("/health/live", checker.LiveHandler).Methods("GET")
+	r.HandleFunc("/health/ready", checker.ReadyHandler).Methods("GET")
+	r.HandleFunc("/health", checker.LiveHandler).Methods("GET") // Kept for older probes
+
+	// Prometheus metrics
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Static files
+	r.PathPrefix("/").Handler(http.FileServer(http.Dir(staticDir)))
+
+	// CORS middleware
+	c := cors.New(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"*"},
+		AllowCredentials: true,
+	})
+
+	return c.Handler(r)
+}
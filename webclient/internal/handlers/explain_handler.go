@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ExplainHandler forwards match explanation requests to the backend, which runs
+// the same matcher used for notifications
+type ExplainHandler struct {
+	backendURL string
+	client     *http.Client
+}
+
+func NewExplainHandler(backendURL string) *ExplainHandler {
+	return &ExplainHandler{
+		backendURL: strings.TrimSuffix(backendURL, "/"),
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Explain explains why an offer did or did not match a wishlist
+func (h *ExplainHandler) Explain(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.client.Post(h.backendURL+"/explain", "application/json", r.Body)
+	if err != nil {
+		http.Error(w, "Failed to reach backend: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
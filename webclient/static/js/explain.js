@@ -0,0 +1,193 @@
+// API Base URL
+const API_BASE = '/api';
+
+const MATCH_KINDS = {
+    exact: 'Palavra igual',
+    contains: 'Contém',
+    joined: 'Palavras juntas',
+    typo: 'Erro de digitação'
+};
+
+const RULES = {
+    excluded_term: 'Termo excluído',
+    min_price: 'Preço mínimo',
+    price: 'Preço desejado',
+    discount: 'Desconto mínimo',
+    cashback: 'Cashback mínimo',
+    effective_price: 'Preço com cashback'
+};
+
+// Explain the matcher decision for an offer and a wishlist item
+async function explainMatch(event) {
+    event.preventDefault();
+
+    const container = document.getElementById('explainResult');
+    const request = {
+        wishlist_id: parseInt(document.getElementById('wishlistId').value)
+    };
+
+    const offerJson = document.getElementById('offerJson').value.trim();
+    const offerId = document.getElementById('offerId').value;
+
+    if (offerJson) {
+        try {
+            request.offer = JSON.parse(offerJson);
+        } catch (error) {
+            alert('JSON da oferta inválido: ' + error.message);
+            return;
+        }
+    } else if (offerId) {
+        request.offer_id = parseInt(offerId);
+    } else {
+        alert('Informe o ID da oferta ou cole o JSON da oferta');
+        return;
+    }
+
+    container.innerHTML = '<div class="spinner"></div>';
+
+    try {
+        const response = await fetch(`${API_BASE}/explain`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(request)
+        });
+
+        if (!response.ok) {
+            const message = await response.text();
+            container.innerHTML = `
+                <div class="empty-state">
+                    <div class="empty-state-icon">⚠️</div>
+                    <p>${escapeHtml(message)}</p>
+                </div>
+            `;
+            return;
+        }
+
+        container.innerHTML = renderExplanation(await response.json());
+    } catch (error) {
+        console.error('Error explaining match:', error);
+        container.innerHTML = `
+            <div class="empty-state">
+                <div class="empty-state-icon">⚠️</div>
+                <p>Erro ao consultar o matcher</p>
+            </div>
+        `;
+    }
+}
+
+function renderExplanation(result) {
+    const explanation = result.explanation;
+    const name = explanation.name;
+
+    return `
+        <p style="margin-bottom: 1rem;">
+            ${badge(explanation.matched, explanation.matched
+                ? `✓ Notificaria (${RULES[explanation.match_type] || explanation.match_type})`
+                : '✗ Não notificaria')}
+        </p>
+
+        <h3 style="margin-bottom: 0.5rem; color: var(--secondary-pink);">1. Produto</h3>
+        <p><strong>Oferta:</strong> ${escapeHtml(result.offer.titulo)}</p>
+        <p><strong>Lista:</strong> ${escapeHtml(result.wishlist.product_name)}</p>
+        ${result.normalization && result.normalization.length > 0 ? `
+            <p><strong>Ajustes na oferta:</strong></p>
+            <ul>${result.normalization.map(change => `<li>${escapeHtml(change)}</li>`).join('')}</ul>
+        ` : ''}
+
+        <h3 style="margin: 1.5rem 0 0.5rem; color: var(--secondary-pink);">2. Palavras normalizadas</h3>
+        <p><strong>Oferta:</strong> ${tokens(name.offer_tokens)}</p>
+        <p><strong>Lista:</strong> ${tokens(name.wishlist_tokens)}</p>
+
+        <div class="table-container">
+            <table class="table">
+                <thead>
+                    <tr>
+                        <th>Palavra da Lista</th>
+                        <th>Palavra da Oferta</th>
+                        <th>Tipo</th>
+                        <th>Resultado</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    ${(name.matches || []).map(match => `
+                        <tr>
+                            <td><code>${escapeHtml(match.wishlist_token)}</code></td>
+                            <td>${match.offer_token ? `<code>${escapeHtml(match.offer_token)}</code>` : '-'}</td>
+                            <td>${match.kind ? MATCH_KINDS[match.kind] + (match.distance ? ` (${match.distance})` : '') : '-'}</td>
+                            <td>${badge(!!match.kind, match.kind ? '✓' : '✗')}</td>
+                        </tr>
+                    `).join('')}
+                </tbody>
+            </table>
+        </div>
+
+        <p style="margin-top: 1rem;">
+            <strong>Similaridade:</strong> ${name.matched_count}/${(name.wishlist_tokens || []).length} palavras
+            = ${percent(name.score)} (mínimo ${percent(name.threshold)})
+            ${name.contained ? ' — um nome contém o outro' : ''}
+            ${badge(name.matched, name.matched ? '✓ Nome compatível' : '✗ Nome incompatível')}
+        </p>
+
+        <h3 style="margin: 1.5rem 0 0.5rem; color: var(--secondary-pink);">3. Regras de preço</h3>
+        ${explanation.rules && explanation.rules.length > 0 ? `
+            <div class="table-container">
+                <table class="table">
+                    <thead>
+                        <tr>
+                            <th>Regra</th>
+                            <th>Esperado</th>
+                            <th>Oferta</th>
+                            <th>Resultado</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+                        ${explanation.rules.map(rule => `
+                            <tr>
+                                <td>${RULES[rule.rule] || rule.rule}</td>
+                                <td>${ruleValue(rule, rule.expected)}</td>
+                                <td>${rule.rule === 'excluded_term' ? (rule.passed ? 'Ausente' : 'Presente') : ruleValue(rule, rule.actual)}</td>
+                                <td>${badge(rule.passed, rule.passed ? '✓ Passou' : '✗ Falhou')}</td>
+                            </tr>
+                        `).join('')}
+                    </tbody>
+                </table>
+            </div>
+        ` : '<p>Nenhuma regra de preço, desconto ou cashback definida.</p>'}
+    `;
+}
+
+function ruleValue(rule, value) {
+    switch (rule.rule) {
+        case 'excluded_term':
+            return `<code>${escapeHtml(rule.term)}</code>`;
+        case 'discount':
+        case 'cashback':
+            return `${value || 0}%`;
+        default:
+            return `R$ ${(value || 0).toFixed(2)}`;
+    }
+}
+
+function tokens(list) {
+    if (!list || list.length === 0) return '-';
+    return list.map(token => `<code>${escapeHtml(token)}</code>`).join(' ');
+}
+
+function badge(passed, text) {
+    return `<span class="badge ${passed ? 'badge-active' : 'badge-inactive'}">${text}</span>`;
+}
+
+function percent(value) {
+    return `${Math.round((value || 0) * 100)}%`;
+}
+
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
+
+// Prefill from the query string (e.g. /explain.html?wishlist_id=42&offer_id=1234)
+const params = new URLSearchParams(window.location.search);
+if (params.get('wishlist_id')) document.getElementById('wishlistId').value = params.get('wishlist_id');
+if (params.get('offer_id')) document.getElementById('offerId').value = params.get('offer_id');